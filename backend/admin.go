@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/account"
+	"github.com/anish-chanda/ferna/internal/auth"
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/schedule"
+	"github.com/anish-chanda/ferna/internal/storage"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/go-pkgz/auth/v2/avatar"
+	"github.com/google/uuid"
+)
+
+// adminUsage documents the admin subcommands
+const adminUsage = `Usage: ferna-api admin [--json] <command> [flags] [arguments]
+
+Commands:
+  list-users                         list all accounts
+  create-user [flags] EMAIL NAME     create a local account with a verified email address
+      --admin                        make the account an instance admin
+      --timezone TZ                  IANA timezone of the user (default UTC)
+      --password-stdin               read the password from stdin instead of generating one
+  reset-password [--password-stdin] USER
+                                     set a new password and sign the user out everywhere
+  disable USER                       block sign-in and revoke all sessions and API keys
+  enable USER                        allow a disabled account to sign in again
+  promote USER                       make the user an instance admin
+  demote USER                        make an instance admin a regular user
+  delete --yes USER                  erase the account and all its data right away
+
+USER is an email address or a user ID. Generated passwords are printed once.
+`
+
+// errAdminUsage marks errors caused by wrong arguments
+var errAdminUsage = errors.New("invalid arguments")
+
+// adminCommand holds what the admin subcommands work with
+type adminCommand struct {
+	config *Config
+	logger *logger.ServiceLogger
+	db     db.Store
+	json   bool
+	out    io.Writer
+}
+
+// adminUser is how the admin commands show a user. Password hashes are left out.
+type adminUser struct {
+	ID                  uuid.UUID          `json:"id"`
+	Email               string             `json:"email"`
+	FullName            string             `json:"full_name"`
+	AuthProvider        model.AuthProvider `json:"auth_provider"`
+	Role                model.UserRole     `json:"role"`
+	EmailVerified       bool               `json:"email_verified"`
+	DisabledAt          *time.Time         `json:"disabled_at"`
+	DeletionScheduledAt *time.Time         `json:"deletion_scheduled_at"`
+	CreatedAt           time.Time          `json:"created_at"`
+}
+
+// adminResult is the output of the commands that change an account
+type adminResult struct {
+	Message  string     `json:"message"`
+	User     *adminUser `json:"user,omitempty"`
+	Password string     `json:"password,omitempty"` // Only set when the password was generated
+}
+
+// runAdminCommand runs an admin subcommand against the configured database
+// and returns the process exit code
+func runAdminCommand(args []string) int {
+	flags := flag.NewFlagSet("admin", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, adminUsage) }
+	jsonOutput := flags.Bool("json", false, "print results as JSON")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprint(os.Stderr, adminUsage)
+		return 2
+	}
+
+	name := flags.Arg(0)
+	switch name {
+	case "list-users", "create-user", "reset-password", "disable", "enable", "promote", "demote", "delete":
+	case "help":
+		fmt.Print(adminUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown admin command %q\n\n%s", name, adminUsage)
+		return 2
+	}
+
+	// Load configuration
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	// Logs share stdout with the command output, so only problems are logged
+	if !strings.EqualFold(config.Logger.Level, "debug") {
+		config.Logger.Level = "warn"
+	}
+	appLogger := logger.New(config.Logger)
+	logger.SetGlobalLogger(config.Logger)
+
+	ctx := context.Background()
+	database, err := openDatabase(ctx, config.Database, appLogger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize database: %v\n", err)
+		return 1
+	}
+	defer database.Close()
+
+	command := &adminCommand{config: config, logger: appLogger, db: database, json: *jsonOutput, out: os.Stdout}
+	if err := command.run(ctx, name, flags.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Admin %s failed: %v\n", name, err)
+		if errors.Is(err, errAdminUsage) {
+			fmt.Fprintf(os.Stderr, "\n%s", adminUsage)
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// run dispatches a subcommand
+func (c *adminCommand) run(ctx context.Context, name string, args []string) error {
+	switch name {
+	case "list-users":
+		return c.listUsers(ctx, args)
+	case "create-user":
+		return c.createUser(ctx, args)
+	case "reset-password":
+		return c.resetPassword(ctx, args)
+	case "disable":
+		return c.setDisabled(ctx, args, true)
+	case "enable":
+		return c.setDisabled(ctx, args, false)
+	case "promote":
+		return c.setRole(ctx, args, model.UserRoleAdmin)
+	case "demote":
+		return c.setRole(ctx, args, model.UserRoleUser)
+	default:
+		return c.deleteUser(ctx, args)
+	}
+}
+
+// listUsers prints all accounts
+func (c *adminCommand) listUsers(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("list-users takes no arguments: %w", errAdminUsage)
+	}
+
+	users, err := c.db.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	views := make([]*adminUser, len(users))
+	for i, user := range users {
+		views[i] = newAdminUser(user)
+	}
+	if c.json {
+		return c.printJSON(views)
+	}
+
+	table := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tEMAIL\tNAME\tPROVIDER\tROLE\tSTATUS\tCREATED")
+	for _, user := range views {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			user.ID, user.Email, user.FullName, user.AuthProvider, user.Role, user.status(), user.CreatedAt.Format(time.DateOnly))
+	}
+	return table.Flush()
+}
+
+// createUser creates a local account. The address is marked as verified since
+// the administrator vouches for it.
+func (c *adminCommand) createUser(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("create-user", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	admin := flags.Bool("admin", false, "")
+	timezone := flags.String("timezone", "UTC", "")
+	passwordStdin := flags.Bool("password-stdin", false, "")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%v: %w", err, errAdminUsage)
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("create-user needs an email and a name: %w", errAdminUsage)
+	}
+
+	email := strings.TrimSpace(strings.ToLower(flags.Arg(0)))
+	fullName := strings.TrimSpace(flags.Arg(1))
+	if !strings.Contains(email, "@") || !strings.Contains(email, ".") {
+		return errors.New("invalid email format")
+	}
+	if fullName == "" {
+		return errors.New("full name is required")
+	}
+	if _, err := schedule.LoadLocation(*timezone); err != nil {
+		return errors.New("timezone must be a valid IANA timezone name")
+	}
+
+	exists, err := c.db.CheckIfEmailExists(ctx, email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("email already registered")
+	}
+
+	password, generated, err := c.password(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := auth.HashPassword(ctx, password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	userID, err := auth.GenerateUserID()
+	if err != nil {
+		return err
+	}
+
+	role := model.UserRoleUser
+	if *admin {
+		role = model.UserRoleAdmin
+	}
+	verifiedAt := time.Now().UTC()
+	user := &model.User{
+		ID:              userID,
+		Email:           email,
+		FullName:        fullName,
+		PasswordHash:    &hashedPassword,
+		AuthProvider:    model.AuthProviderLocal,
+		Timezone:        strings.TrimSpace(*timezone),
+		Role:            role,
+		EmailVerifiedAt: &verifiedAt,
+	}
+	if _, err := c.db.CreateUser(ctx, user); err != nil {
+		return err
+	}
+
+	result := &adminResult{Message: fmt.Sprintf("Created %s %s", role, email)}
+	if generated {
+		result.Password = password
+	}
+	return c.printResult(ctx, result, userID)
+}
+
+// resetPassword sets a new password, which also signs the user out everywhere
+func (c *adminCommand) resetPassword(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	passwordStdin := flags.Bool("password-stdin", false, "")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%v: %w", err, errAdminUsage)
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("reset-password needs a user: %w", errAdminUsage)
+	}
+
+	user, err := c.findUser(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if user.AuthProvider != model.AuthProviderLocal {
+		return fmt.Errorf("%s signs in with %s and has no password", user.Email, user.AuthProvider)
+	}
+
+	password, generated, err := c.password(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := auth.HashPassword(ctx, password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := c.db.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return err
+	}
+
+	result := &adminResult{Message: fmt.Sprintf("Password of %s reset", user.Email)}
+	if generated {
+		result.Password = password
+	}
+	return c.printResult(ctx, result, user.ID)
+}
+
+// setDisabled disables or re-enables an account
+func (c *adminCommand) setDisabled(ctx context.Context, args []string, disabled bool) error {
+	if len(args) != 1 {
+		return fmt.Errorf("a single user is required: %w", errAdminUsage)
+	}
+	user, err := c.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	updated, err := c.db.SetUserDisabled(ctx, user.ID, disabled)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("user %s not found", args[0])
+	}
+
+	message := fmt.Sprintf("Enabled %s", user.Email)
+	if disabled {
+		message = fmt.Sprintf("Disabled %s", user.Email)
+	}
+	return c.printResult(ctx, &adminResult{Message: message}, user.ID)
+}
+
+// setRole changes the instance role of a user
+func (c *adminCommand) setRole(ctx context.Context, args []string, role model.UserRole) error {
+	if len(args) != 1 {
+		return fmt.Errorf("a single user is required: %w", errAdminUsage)
+	}
+	user, err := c.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	updated, err := c.db.SetUserRole(ctx, user.ID, role)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("user %s not found", args[0])
+	}
+
+	return c.printResult(ctx, &adminResult{Message: fmt.Sprintf("%s is now %s", user.Email, role)}, user.ID)
+}
+
+// deleteUser erases an account and all its data, skipping any grace period,
+// and removes the account's files right away
+func (c *adminCommand) deleteUser(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	confirmed := flags.Bool("yes", false, "")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%v: %w", err, errAdminUsage)
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("delete needs a user: %w", errAdminUsage)
+	}
+
+	user, err := c.findUser(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if !*confirmed {
+		return fmt.Errorf("deleting %s erases all their data and cannot be undone, run again with --yes to confirm", user.Email)
+	}
+
+	blobs, err := storage.New(c.config.Storage)
+	if err != nil {
+		return fmt.Errorf("failed to initialize blob storage: %w", err)
+	}
+	eraser, err := account.NewEraser(c.db, blobs, avatar.NewLocalFS(c.config.Auth.AvatarPath), c.config.Deletion, c.logger)
+	if err != nil {
+		return err
+	}
+	if err := eraser.Erase(ctx, user.ID); err != nil {
+		return err
+	}
+	// Files that cannot be removed now stay queued for the server's worker
+	if err := eraser.DeleteFiles(ctx); err != nil {
+		c.logger.Warnf("Failed to remove files of %s, the server will retry: %v", user.Email, err)
+	}
+
+	result := &adminResult{Message: fmt.Sprintf("Deleted %s", user.Email)}
+	if c.json {
+		return c.printJSON(result)
+	}
+	fmt.Fprintln(c.out, result.Message)
+	return nil
+}
+
+// findUser looks a user up by ID or email
+func (c *adminCommand) findUser(ctx context.Context, ref string) (*model.User, error) {
+	var user *model.User
+	var err error
+	if id, parseErr := uuid.Parse(ref); parseErr == nil {
+		user, err = c.db.GetUserByID(ctx, id)
+	} else {
+		user, err = c.db.GetUserByEmail(ctx, strings.TrimSpace(strings.ToLower(ref)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %s not found", ref)
+	}
+	return user, nil
+}
+
+// password reads a password from the first line of stdin, or generates one.
+// Reports whether the password was generated.
+func (c *adminCommand) password(fromStdin bool) (string, bool, error) {
+	if !fromStdin {
+		password, _, err := auth.GenerateToken()
+		if err != nil {
+			return "", false, fmt.Errorf("failed to generate password: %w", err)
+		}
+		return password, true, nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", false, fmt.Errorf("failed to read password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if len(strings.TrimSpace(password)) < 6 {
+		return "", false, errors.New("password must be at least 6 characters long")
+	}
+	return password, false, nil
+}
+
+// printResult reloads the user a command changed and prints it with the result
+func (c *adminCommand) printResult(ctx context.Context, result *adminResult, userID uuid.UUID) error {
+	user, err := c.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		result.User = newAdminUser(user)
+	}
+
+	if c.json {
+		return c.printJSON(result)
+	}
+
+	fmt.Fprintln(c.out, result.Message)
+	if result.User != nil {
+		table := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(table, "  ID:\t%s\n", result.User.ID)
+		fmt.Fprintf(table, "  Role:\t%s\n", result.User.Role)
+		fmt.Fprintf(table, "  Status:\t%s\n", result.User.status())
+		if err := table.Flush(); err != nil {
+			return err
+		}
+	}
+	if result.Password != "" {
+		fmt.Fprintf(c.out, "  Password: %s\n", result.Password)
+	}
+	return nil
+}
+
+// printJSON writes v as indented JSON
+func (c *adminCommand) printJSON(v any) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// newAdminUser converts a user for output
+func newAdminUser(user *model.User) *adminUser {
+	return &adminUser{
+		ID:                  user.ID,
+		Email:               user.Email,
+		FullName:            user.FullName,
+		AuthProvider:        user.AuthProvider,
+		Role:                user.Role,
+		EmailVerified:       user.EmailVerifiedAt != nil,
+		DisabledAt:          user.DisabledAt,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		CreatedAt:           user.CreatedAt,
+	}
+}
+
+// status summarizes whether the account can be used
+func (u *adminUser) status() string {
+	switch {
+	case u.DisabledAt != nil:
+		return "disabled"
+	case u.DeletionScheduledAt != nil:
+		return "deleting"
+	case !u.EmailVerified:
+		return "unverified"
+	default:
+		return "active"
+	}
+}
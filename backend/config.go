@@ -8,8 +8,17 @@ import (
 	"strings"
 	"time"
 
+	"github.com/anish-chanda/ferna/internal/account"
+	"github.com/anish-chanda/ferna/internal/auth"
 	"github.com/anish-chanda/ferna/internal/db"
 	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/mail"
+	"github.com/anish-chanda/ferna/internal/push"
+	"github.com/anish-chanda/ferna/internal/registration"
+	"github.com/anish-chanda/ferna/internal/reminders"
+	"github.com/anish-chanda/ferna/internal/storage"
+	"github.com/anish-chanda/ferna/internal/throttle"
+	"github.com/anish-chanda/ferna/internal/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -21,6 +30,33 @@ type AuthConfig struct {
 	BaseURL        string // Base URL for the application
 	AvatarPath     string // Path for avatar storage, default to /var/lib/ferna/avatars
 	DisableXSRF    bool   // Whether to disable XSRF protection, this default to true
+
+	PasswordResetURL string        // Page or app link that receives the reset token as ?token=
+	PasswordResetTTL time.Duration // How long a password reset token stays valid
+
+	EmailVerificationURL string        // Page or app link that receives the verification token as ?token=
+	EmailVerificationTTL time.Duration // How long an email verification token stays valid
+	RequireVerifiedEmail bool          // Whether local users must verify their email before logging in
+
+	Google   auth.OAuthConfig // Google sign-in, enabled when client credentials are set
+	Facebook auth.OAuthConfig // Facebook login, enabled when client credentials are set
+}
+
+// PhotoConfig holds photo upload configuration
+type PhotoConfig struct {
+	MaxUploadBytes int64 // Maximum size of an uploaded photo in bytes
+}
+
+// PushConfig holds push notification configuration
+type PushConfig struct {
+	Enabled bool        // Whether reminders are sent as push notifications
+	FCM     push.Config // FCM HTTP v1 settings
+}
+
+// MetricsConfig holds Prometheus metrics configuration
+type MetricsConfig struct {
+	Enabled bool // Whether /metrics is served
+	Port    int  // Separate port for /metrics, 0 serves it on the API port
 }
 
 // Config holds all application configuration
@@ -37,6 +73,38 @@ type Config struct {
 
 	// Authentication configuration
 	Auth AuthConfig
+
+	// Who can sign up for a new account
+	Registration registration.Config
+
+	// Blob storage configuration for photos
+	Storage storage.Config
+
+	// Photo upload configuration
+	Photos PhotoConfig
+
+	// Care task reminder configuration
+	Reminders reminders.Config
+
+	// Push notification configuration
+	Push PushConfig
+
+	// Outbound email configuration
+	Mail mail.Config
+
+	// Whether reminders are also sent by email
+	EmailReminders bool
+
+	Deletion account.Config
+
+	// Login throttling configuration
+	Throttle throttle.Config
+
+	// Prometheus metrics configuration
+	Metrics MetricsConfig
+
+	// OpenTelemetry tracing configuration
+	Tracing tracing.Config
 }
 
 // LoadConfig loads configuration from environment variables with sensible defaults
@@ -70,6 +138,115 @@ func LoadConfig() (*Config, error) {
 			BaseURL:        getEnv("API_BASE_URL", "http://localhost:8080"),
 			AvatarPath:     "./data/avatars",
 			DisableXSRF:    true,
+
+			PasswordResetURL: getEnv("PASSWORD_RESET_URL", getEnv("API_BASE_URL", "http://localhost:8080")+"/reset-password"),
+			PasswordResetTTL: getEnvAsDuration("PASSWORD_RESET_TTL", time.Hour),
+
+			EmailVerificationURL: getEnv("EMAIL_VERIFICATION_URL", getEnv("API_BASE_URL", "http://localhost:8080")+"/verify-email"),
+			EmailVerificationTTL: getEnvAsDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+			RequireVerifiedEmail: getEnvAsBool("REQUIRE_VERIFIED_EMAIL", false),
+
+			Google: auth.OAuthConfig{
+				ClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
+				ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
+				AuthURL:      getEnv("GOOGLE_AUTH_URL", ""),
+				TokenURL:     getEnv("GOOGLE_TOKEN_URL", ""),
+				UserInfoURL:  getEnv("GOOGLE_USERINFO_URL", ""),
+			},
+			Facebook: auth.OAuthConfig{
+				ClientID:     getEnv("FACEBOOK_CLIENT_ID", ""),
+				ClientSecret: getEnv("FACEBOOK_CLIENT_SECRET", ""),
+				AuthURL:      getEnv("FACEBOOK_AUTH_URL", ""),
+				TokenURL:     getEnv("FACEBOOK_TOKEN_URL", ""),
+				UserInfoURL:  getEnv("FACEBOOK_USERINFO_URL", ""),
+			},
+		},
+
+		Registration: registration.Config{
+			Mode:           registration.Mode(strings.ToLower(getEnv("REGISTRATION_MODE", string(registration.ModeOpen)))),
+			AllowedDomains: getEnvAsList("REGISTRATION_ALLOWED_DOMAINS"),
+		},
+
+		// Blob storage configuration
+		Storage: storage.Config{
+			Driver:    storage.Driver(getEnv("BLOB_STORE_DRIVER", "local")),
+			LocalPath: getEnv("BLOB_STORE_PATH", "./data/blobs"),
+			S3: storage.S3Config{
+				Endpoint:  getEnv("S3_ENDPOINT", ""),
+				AccessKey: getEnv("S3_ACCESS_KEY", ""),
+				SecretKey: getEnv("S3_SECRET_KEY", ""),
+				Bucket:    getEnv("S3_BUCKET", ""),
+				Region:    getEnv("S3_REGION", ""),
+				UseSSL:    getEnvAsBool("S3_USE_SSL", true),
+			},
+		},
+
+		// Photo upload configuration
+		Photos: PhotoConfig{
+			MaxUploadBytes: getEnvAsInt64("PHOTO_MAX_UPLOAD_BYTES", 10<<20), // 10 MiB
+		},
+
+		// Reminder dispatcher configuration
+		Reminders: reminders.Config{
+			Enabled:     getEnvAsBool("REMINDERS_ENABLED", true),
+			Interval:    getEnvAsDuration("REMINDER_INTERVAL", time.Minute),
+			BatchSize:   getEnvAsInt("REMINDER_BATCH_SIZE", 100),
+			MaxAttempts: getEnvAsInt("REMINDER_MAX_ATTEMPTS", 8),
+			Lookback:    getEnvAsDuration("REMINDER_LOOKBACK", 24*time.Hour),
+		},
+
+		// Push notification configuration
+		Push: PushConfig{
+			Enabled: getEnvAsBool("PUSH_ENABLED", false),
+			FCM: push.Config{
+				Endpoint:        getEnv("FCM_ENDPOINT", push.DefaultFCMEndpoint),
+				ProjectID:       getEnv("FCM_PROJECT_ID", ""),
+				CredentialsFile: getEnv("FCM_CREDENTIALS_FILE", ""),
+			},
+		},
+
+		// Outbound email configuration
+		Mail: mail.Config{
+			Driver: mail.Driver(getEnv("MAIL_DRIVER", "log")),
+			From:   getEnv("MAIL_FROM", "Ferna <no-reply@localhost>"),
+			SMTP: mail.SMTPConfig{
+				Host:     getEnv("SMTP_HOST", ""),
+				Port:     getEnvAsInt("SMTP_PORT", 587),
+				Username: getEnv("SMTP_USERNAME", ""),
+				Password: getEnv("SMTP_PASSWORD", ""),
+				TLS:      mail.TLSMode(getEnv("SMTP_TLS", "starttls")),
+			},
+			Interval:    getEnvAsDuration("MAIL_INTERVAL", 30*time.Second),
+			BatchSize:   getEnvAsInt("MAIL_BATCH_SIZE", 50),
+			MaxAttempts: getEnvAsInt("MAIL_MAX_ATTEMPTS", 10),
+		},
+		EmailReminders: getEnvAsBool("REMINDER_EMAIL_ENABLED", false),
+
+		Deletion: account.Config{
+			GracePeriod: getEnvAsDuration("ACCOUNT_DELETION_GRACE_PERIOD", 7*24*time.Hour),
+			Interval:    getEnvAsDuration("ACCOUNT_DELETION_INTERVAL", 10*time.Minute),
+		},
+
+		Throttle: throttle.Config{
+			Enabled:            getEnvAsBool("LOGIN_THROTTLE_ENABLED", true),
+			MaxAccountFailures: getEnvAsInt("LOGIN_MAX_ACCOUNT_FAILURES", 10),
+			MaxIPFailures:      getEnvAsInt("LOGIN_MAX_IP_FAILURES", 50),
+			LockoutDuration:    getEnvAsDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			BaseDelay:          getEnvAsDuration("LOGIN_BACKOFF_BASE", time.Second),
+			MaxDelay:           getEnvAsDuration("LOGIN_BACKOFF_MAX", time.Minute),
+			FailureWindow:      getEnvAsDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+			TrustForwardedFor:  getEnvAsBool("TRUST_FORWARDED_FOR", false),
+		},
+
+		Metrics: MetricsConfig{
+			Enabled: getEnvAsBool("METRICS_ENABLED", true),
+			Port:    getEnvAsInt("METRICS_PORT", 0),
+		},
+
+		Tracing: tracing.Config{
+			Exporter:    getEnv("TRACING_EXPORTER", tracing.ExporterNone),
+			Endpoint:    getEnv("TRACING_OTLP_ENDPOINT", "http://localhost:4318"),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1),
 		},
 	}
 
@@ -86,9 +263,18 @@ func (c *Config) validate() error {
 	if c.Database.DSN == "" {
 		return errors.New("DATABASE_URL cannot be empty")
 	}
-	// Validate DSN syntax using pgx
-	if _, err := pgxpool.ParseConfig(c.Database.DSN); err != nil {
-		return fmt.Errorf("invalid DATABASE_URL: %w", err)
+	switch c.Database.Driver() {
+	case db.DriverPostgres:
+		// Validate DSN syntax using pgx
+		if _, err := pgxpool.ParseConfig(c.Database.DSN); err != nil {
+			return fmt.Errorf("invalid DATABASE_URL: %w", err)
+		}
+	case db.DriverSQLite:
+		if db.SQLitePath(c.Database.DSN) == "" {
+			return errors.New("DATABASE_URL must include a file path for sqlite")
+		}
+	default:
+		return errors.New("DATABASE_URL must start with postgres://, postgresql:// or sqlite://")
 	}
 
 	validLogLevels := []string{"debug", "info", "warn", "error"}
@@ -103,6 +289,111 @@ func (c *Config) validate() error {
 		return errors.New("LOG_LEVEL must be one of: debug, info, warn, error")
 	}
 
+	if c.Auth.PasswordResetTTL <= 0 {
+		return errors.New("PASSWORD_RESET_TTL must be positive")
+	}
+	if c.Auth.EmailVerificationTTL <= 0 {
+		return errors.New("EMAIL_VERIFICATION_TTL must be positive")
+	}
+
+	if err := c.Registration.Validate(); err != nil {
+		return fmt.Errorf("invalid REGISTRATION_MODE or REGISTRATION_ALLOWED_DOMAINS: %w", err)
+	}
+	// Without verification anyone could sign up with an address of an allowed domain
+	if c.Registration.Mode == registration.ModeDomains && !c.Auth.RequireVerifiedEmail {
+		return errors.New("REQUIRE_VERIFIED_EMAIL must be enabled when REGISTRATION_MODE is domains")
+	}
+
+	switch c.Storage.Driver {
+	case storage.DriverLocal:
+		if c.Storage.LocalPath == "" {
+			return errors.New("BLOB_STORE_PATH cannot be empty")
+		}
+	case storage.DriverS3:
+		if c.Storage.S3.Endpoint == "" || c.Storage.S3.Bucket == "" {
+			return errors.New("S3_ENDPOINT and S3_BUCKET are required when BLOB_STORE_DRIVER is s3")
+		}
+	default:
+		return errors.New("BLOB_STORE_DRIVER must be one of: local, s3")
+	}
+
+	if c.Photos.MaxUploadBytes <= 0 {
+		return errors.New("PHOTO_MAX_UPLOAD_BYTES must be positive")
+	}
+
+	if c.Reminders.Enabled {
+		if c.Reminders.Interval <= 0 {
+			return errors.New("REMINDER_INTERVAL must be positive")
+		}
+		if c.Reminders.BatchSize <= 0 || c.Reminders.MaxAttempts <= 0 {
+			return errors.New("REMINDER_BATCH_SIZE and REMINDER_MAX_ATTEMPTS must be positive")
+		}
+		if c.Reminders.Lookback <= 0 {
+			return errors.New("REMINDER_LOOKBACK must be positive")
+		}
+	}
+
+	if c.Deletion.GracePeriod < 0 {
+		return errors.New("ACCOUNT_DELETION_GRACE_PERIOD cannot be negative")
+	}
+	if c.Deletion.Interval <= 0 {
+		return errors.New("ACCOUNT_DELETION_INTERVAL must be positive")
+	}
+
+	if c.Throttle.Enabled {
+		if c.Throttle.MaxAccountFailures <= throttle.FreeFailures || c.Throttle.MaxIPFailures <= throttle.FreeFailures {
+			return fmt.Errorf("LOGIN_MAX_ACCOUNT_FAILURES and LOGIN_MAX_IP_FAILURES must be larger than %d", throttle.FreeFailures)
+		}
+		if c.Throttle.LockoutDuration <= 0 || c.Throttle.BaseDelay <= 0 || c.Throttle.FailureWindow <= 0 {
+			return errors.New("LOGIN_LOCKOUT_DURATION, LOGIN_BACKOFF_BASE and LOGIN_FAILURE_WINDOW must be positive")
+		}
+		if c.Throttle.MaxDelay < c.Throttle.BaseDelay {
+			return errors.New("LOGIN_BACKOFF_MAX cannot be less than LOGIN_BACKOFF_BASE")
+		}
+	}
+
+	if c.Metrics.Port < 0 || c.Metrics.Port > 65535 {
+		return errors.New("METRICS_PORT must be a valid port, or 0 to serve metrics on API_PORT")
+	}
+	if c.Metrics.Enabled && c.Metrics.Port == c.APIPort {
+		return errors.New("METRICS_PORT cannot be the same as API_PORT, use 0 to share it")
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout:
+	case tracing.ExporterOTLP:
+		if !strings.HasPrefix(c.Tracing.Endpoint, "http://") && !strings.HasPrefix(c.Tracing.Endpoint, "https://") {
+			return errors.New("TRACING_OTLP_ENDPOINT must be an http:// or https:// URL")
+		}
+	default:
+		return errors.New("TRACING_EXPORTER must be either 'none', 'otlp' or 'stdout'")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return errors.New("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	if c.Push.Enabled && c.Push.FCM.ProjectID == "" && c.Push.FCM.CredentialsFile == "" {
+		return errors.New("FCM_PROJECT_ID or FCM_CREDENTIALS_FILE is required when PUSH_ENABLED is true")
+	}
+
+	switch c.Mail.Driver {
+	case mail.DriverLog:
+	case mail.DriverSMTP:
+		if c.Mail.SMTP.Host == "" {
+			return errors.New("SMTP_HOST is required when MAIL_DRIVER is smtp")
+		}
+		switch c.Mail.SMTP.TLS {
+		case mail.TLSModeSTARTTLS, mail.TLSModeImplicit, mail.TLSModeNone:
+		default:
+			return errors.New("SMTP_TLS must be one of: starttls, tls, none")
+		}
+	default:
+		return errors.New("MAIL_DRIVER must be one of: log, smtp")
+	}
+	if c.Mail.Interval <= 0 || c.Mail.BatchSize <= 0 || c.Mail.MaxAttempts <= 0 {
+		return errors.New("MAIL_INTERVAL, MAIL_BATCH_SIZE and MAIL_MAX_ATTEMPTS must be positive")
+	}
+
 	return nil
 }
 
@@ -136,6 +427,26 @@ func getEnvAsInt32(key string, fallback int32) int32 {
 	return fallback
 }
 
+// getEnvAsInt64 gets an environment variable as int64 with a fallback value
+func getEnvAsInt64(key string, fallback int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if intVal, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return intVal
+		}
+	}
+	return fallback
+}
+
+// getEnvAsFloat gets an environment variable as a float64 with a fallback value
+func getEnvAsFloat(key string, fallback float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return fallback
+}
+
 // getEnvAsDuration gets an environment variable as duration with a fallback value
 // Expects duration in format like "1h", "30m", "5s"
 func getEnvAsDuration(key string, fallback time.Duration) time.Duration {
@@ -159,3 +470,14 @@ func getEnvAsBool(key string, fallback bool) bool {
 	}
 	return fallback
 }
+
+// getEnvAsList gets a comma separated environment variable as a list, leaving out empty items
+func getEnvAsList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
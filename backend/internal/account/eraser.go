@@ -0,0 +1,311 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/auth"
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/storage"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/go-pkgz/auth/v2/avatar"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// tracer records purge runs as traces, so their log lines can be found from one
+var tracer = otel.Tracer("github.com/anish-chanda/ferna/internal/account")
+
+const (
+	// batchSize is how many accounts or files are handled per query
+	batchSize = 50
+	// fileLease hides a claimed file deletion from other erasers
+	fileLease = 5 * time.Minute
+	// baseRetryDelay is the delay after the first failed file deletion, doubled on every further failure
+	baseRetryDelay = time.Minute
+	// maxRetryDelay caps the delay between attempts; files are retried until they are gone
+	maxRetryDelay = 6 * time.Hour
+)
+
+// ErrUserNotFound is returned when the account to delete does not exist
+var ErrUserNotFound = errors.New("user not found")
+
+// Config holds account deletion configuration
+type Config struct {
+	GracePeriod time.Duration // How long a requested deletion can be cancelled, zero erases right away
+	Interval    time.Duration // How often accounts and files due for deletion are checked
+}
+
+// Eraser deletes accounts together with all their data. Deletions requested by
+// users are scheduled after a grace period and carried out by a background
+// worker, which also removes the files of erased accounts from the blob store
+// and the avatar store.
+type Eraser struct {
+	db      db.Store
+	blobs   storage.BlobStore
+	avatars avatar.Store
+	config  Config
+	logger  *logger.ServiceLogger
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+// NewEraser creates an eraser removing files from blobs and avatars
+func NewEraser(database db.Store, blobs storage.BlobStore, avatars avatar.Store, config Config, logger *logger.ServiceLogger) (*Eraser, error) {
+	if config.GracePeriod < 0 {
+		return nil, errors.New("account deletion grace period cannot be negative")
+	}
+	if config.Interval <= 0 {
+		return nil, errors.New("account deletion interval must be positive")
+	}
+
+	return &Eraser{
+		db:      database,
+		blobs:   blobs,
+		avatars: avatars,
+		config:  config,
+		logger:  logger.WithField("component", "account-eraser"),
+		wake:    make(chan struct{}, 1),
+	}, nil
+}
+
+// Schedule requests the deletion of an account once the grace period has
+// passed and returns when that will be. Without a grace period the account is
+// erased right away and nil is returned.
+func (e *Eraser) Schedule(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	if e.config.GracePeriod == 0 {
+		return nil, e.Erase(ctx, userID)
+	}
+
+	at, err := e.db.ScheduleUserDeletion(ctx, userID, time.Now().UTC().Add(e.config.GracePeriod))
+	if err != nil {
+		return nil, err
+	}
+	if at == nil {
+		return nil, ErrUserNotFound
+	}
+	return at, nil
+}
+
+// Cancel withdraws a scheduled deletion. Returns false if none was scheduled.
+func (e *Eraser) Cancel(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return e.db.CancelUserDeletion(ctx, userID)
+}
+
+// Erase deletes an account and all its data right away, regardless of any
+// grace period, which also makes it the override for administrators. The
+// account's files are queued in the same transaction and removed by the worker.
+func (e *Eraser) Erase(ctx context.Context, userID uuid.UUID) error {
+	return e.erase(ctx, userID, nil)
+}
+
+// erase deletes an account, only if its deletion is due by dueBy when that is set
+func (e *Eraser) erase(ctx context.Context, userID uuid.UUID, dueBy *time.Time) error {
+	user, err := e.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	identities, err := e.db.ListIdentitiesByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	erased, err := e.db.EraseUser(ctx, userID, auth.AvatarIDs(user, identities), dueBy)
+	if err != nil {
+		return err
+	}
+	if !erased {
+		return ErrUserNotFound
+	}
+
+	e.logger.InfofContext(ctx, "Account %s erased", userID)
+
+	// Let a running worker remove the files without waiting for the next tick
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start runs the worker in a background goroutine until Stop is called
+func (e *Eraser) Start() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.done != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done = make(chan struct{})
+
+	go func() {
+		defer close(e.done)
+		e.run(ctx)
+	}()
+}
+
+// Stop signals the worker to stop and waits for the current account or file
+// to finish, or for ctx to expire
+func (e *Eraser) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+		e.logger.Info("Account eraser stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("account eraser did not stop in time: %w", ctx.Err())
+	}
+}
+
+// run purges once immediately and then on every tick or wake-up until ctx is cancelled
+func (e *Eraser) run(ctx context.Context) {
+	e.logger.InfofContext(ctx, "Account eraser started - Interval: %s, Grace period: %s", e.config.Interval, e.config.GracePeriod)
+
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Purge(ctx); err != nil && ctx.Err() == nil {
+			e.logger.ErrorfContext(ctx, "Account purge failed: %v", err)
+		}
+		if err := e.DeleteFiles(ctx); err != nil && ctx.Err() == nil {
+			e.logger.ErrorfContext(ctx, "File deletion failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-e.wake:
+		}
+	}
+}
+
+// Purge erases the accounts whose grace period has ended, stopping early when ctx is cancelled
+func (e *Eraser) Purge(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "account.purge")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	for ctx.Err() == nil {
+		now := time.Now().UTC()
+		ids, err := e.db.ListUsersDueForDeletion(ctx, now, batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			// Each erasure is a single transaction, let it finish during shutdown.
+			// Accounts whose deletion was cancelled since they were listed are skipped.
+			if err := e.erase(context.WithoutCancel(ctx), id, &now); err != nil && !errors.Is(err, ErrUserNotFound) {
+				return fmt.Errorf("failed to erase account %s: %w", id, err)
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		if len(ids) < batchSize {
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteFiles removes the queued files of erased accounts, stopping early when ctx is cancelled
+func (e *Eraser) DeleteFiles(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "account.delete_files")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	for ctx.Err() == nil {
+		deletions, err := e.db.ClaimFileDeletions(ctx, time.Now().UTC(), fileLease, batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, deletion := range deletions {
+			e.deleteFile(context.WithoutCancel(ctx), deletion)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		if len(deletions) < batchSize {
+			break
+		}
+	}
+	return nil
+}
+
+// deleteFile removes a single file and records the outcome
+func (e *Eraser) deleteFile(ctx context.Context, deletion *model.FileDeletion) {
+	var err error
+	switch deletion.Store {
+	case model.FileStoreBlobs:
+		err = e.blobs.Delete(ctx, deletion.Key)
+	case model.FileStoreAvatars:
+		// Most sign-in methods never stored an avatar, so a missing file is expected
+		if err = e.avatars.Remove(deletion.Key); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	default:
+		err = fmt.Errorf("unknown file store %q", deletion.Store)
+	}
+
+	if err == nil {
+		if err := e.db.CompleteFileDeletion(ctx, deletion.ID); err != nil {
+			e.logger.ErrorfContext(ctx, "Failed to complete file deletion %s: %v", deletion.ID, err)
+		}
+		return
+	}
+
+	retryAt := time.Now().UTC().Add(retryDelay(deletion.Attempts))
+	e.logger.WarnfContext(ctx, "Failed to delete %s file %s, retrying at %s: %v", deletion.Store, deletion.Key, retryAt.Format(time.RFC3339), err)
+	if err := e.db.MarkFileDeletionFailed(ctx, deletion.ID, err.Error(), retryAt); err != nil {
+		e.logger.ErrorfContext(ctx, "Failed to record file deletion failure: %v", err)
+	}
+}
+
+// retryDelay returns the exponential backoff after the given number of attempts
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,154 @@
+package account
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/auth"
+	"github.com/anish-chanda/ferna/internal/db/dbtest"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/storage"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/go-pkgz/auth/v2/avatar"
+	pkgzlogger "github.com/go-pkgz/auth/v2/logger"
+	"github.com/go-pkgz/auth/v2/provider"
+	"github.com/go-pkgz/auth/v2/token"
+	"github.com/google/uuid"
+)
+
+// localLogin signs in through the go-pkgz local provider the way the server
+// does, which stores an avatar for the token user. With userIDFunc nil the
+// user is keyed by the email, as local sign-ins used to be.
+func localLogin(t *testing.T, proxy *avatar.Proxy, email string, userIDFunc provider.UserIDFunc) {
+	t.Helper()
+	handler := provider.DirectHandler{
+		L:            pkgzlogger.NoOp,
+		ProviderName: string(model.AuthProviderLocal),
+		CredChecker:  provider.CredCheckerFunc(func(string, string) (bool, error) { return true, nil }),
+		TokenService: token.NewService(token.Opts{
+			SecretReader: token.SecretFunc(func(string) (string, error) { return "secret", nil }),
+		}),
+		Issuer:      "ferna",
+		AvatarSaver: proxy,
+		UserIDFunc:  userIDFunc,
+	}
+
+	body := `{"user":"` + email + `","passwd":"password","aud":"ferna-mobile"}`
+	req := httptest.NewRequest(http.MethodPost, "/auth/local/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.LoginHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("local login of %s returned %d: %s", email, rec.Code, rec.Body)
+	}
+}
+
+// oauthLogin stores the avatar of a provider login, mapping the provider's
+// user data with the same function as the server
+func oauthLogin(t *testing.T, proxy *avatar.Proxy, opt provider.CustomHandlerOpt, data provider.UserData) {
+	t.Helper()
+	user := opt.MapUserFn(data, []byte("{}"))
+	if _, err := proxy.Put(user, nil); err != nil {
+		t.Fatalf("failed to store avatar of %s: %v", user.ID, err)
+	}
+}
+
+// listAvatars returns the sorted IDs of the stored avatars
+func listAvatars(t *testing.T, avatars avatar.Store) []string {
+	t.Helper()
+	ids, err := avatars.List()
+	if err != nil {
+		t.Fatalf("failed to list avatars: %v", err)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// contains reports whether ids holds id
+func contains(ids []string, id string) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+// TestEraseRemovesAvatars checks that the avatar keys queued for an erased
+// account match the files go-pkgz stored for each of its sign-in methods
+func TestEraseRemovesAvatars(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New(logger.Config{Level: "error"})
+	database := dbtest.NewSQLite(t)
+
+	avatars := avatar.NewLocalFS(t.TempDir())
+	proxy := &avatar.Proxy{L: pkgzlogger.NoOp, Store: avatars, RoutePath: "/avatar"}
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+
+	hash := "unused"
+	user := &model.User{
+		ID:           uuid.New(),
+		AuthProvider: model.AuthProviderLocal,
+		Email:        "user@example.com",
+		FullName:     "User",
+		PasswordHash: &hash,
+		Timezone:     "UTC",
+	}
+	if _, err := database.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	for _, identity := range []*model.UserIdentity{
+		{ID: uuid.New(), UserID: user.ID, Provider: model.AuthProviderGoogle, Subject: "108234567890"},
+		{ID: uuid.New(), UserID: user.ID, Provider: model.AuthProviderFacebook, Subject: "10223344556677"},
+	} {
+		if err := database.LinkIdentity(ctx, identity); err != nil {
+			t.Fatalf("LinkIdentity failed: %v", err)
+		}
+	}
+
+	// Every way the user signed in left an avatar behind
+	localLogin(t, proxy, user.Email, func(string, *http.Request) string { return user.ID.String() })
+	localLogin(t, proxy, user.Email, nil)
+	oauthLogin(t, proxy, auth.GoogleProvider(auth.OAuthConfig{}), provider.UserData{"sub": "108234567890"})
+	oauthLogin(t, proxy, auth.FacebookProvider(auth.OAuthConfig{}), provider.UserData{"id": "10223344556677"})
+	own := listAvatars(t, avatars)
+	if len(own) != 4 {
+		t.Fatalf("stored %d avatars, want 4: %v", len(own), own)
+	}
+
+	// The avatar of another user has to survive
+	oauthLogin(t, proxy, auth.GoogleProvider(auth.OAuthConfig{}), provider.UserData{"sub": "other"})
+
+	eraser, err := NewEraser(database, blobs, avatars, Config{Interval: time.Hour}, log)
+	if err != nil {
+		t.Fatalf("NewEraser failed: %v", err)
+	}
+	if err := eraser.Erase(ctx, user.ID); err != nil {
+		t.Fatalf("Erase failed: %v", err)
+	}
+	if err := eraser.DeleteFiles(ctx); err != nil {
+		t.Fatalf("DeleteFiles failed: %v", err)
+	}
+
+	got := listAvatars(t, avatars)
+	if len(got) != 1 || contains(own, got[0]) {
+		t.Fatalf("avatars left after erasure: %v, want only the other user's", got)
+	}
+
+	// Every queued avatar deletion completed
+	deletions, err := database.ClaimFileDeletions(ctx, time.Now().UTC().Add(24*time.Hour), time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimFileDeletions failed: %v", err)
+	}
+	if len(deletions) != 0 {
+		t.Errorf("%d file deletions still queued", len(deletions))
+	}
+}
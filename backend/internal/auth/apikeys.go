@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/middleware"
+	"github.com/anish-chanda/ferna/model"
+	pkgzmiddleware "github.com/go-pkgz/auth/v2/middleware"
+	"github.com/go-pkgz/auth/v2/token"
+)
+
+const (
+	// APIKeyPrefix starts every API key so it can be told apart from a JWT and found by secret scanners
+	APIKeyPrefix = "ferna_"
+	// APIKeyIDAttr is the token user attribute holding the ID of the API key a request used
+	APIKeyIDAttr = "api_key_id"
+
+	// apiKeyVisibleChars is how much of the random part is kept as the visible prefix
+	apiKeyVisibleChars = 8
+)
+
+// GenerateAPIKey returns a new API key, its visible prefix and the hash to store for it
+func GenerateAPIKey() (key, prefix string, hash []byte, err error) {
+	secret, _, err := GenerateToken()
+	if err != nil {
+		return "", "", nil, err
+	}
+	key = APIKeyPrefix + secret
+	return key, key[:len(APIKeyPrefix)+apiKeyVisibleChars], HashToken(key), nil
+}
+
+// Authenticator accepts either a go-pkgz JWT or a personal API key sent as
+// "Authorization: Bearer <key>". Requests authenticated with a key get a token
+// user carrying the owner's ID, so handlers do not need to know the difference.
+type Authenticator struct {
+	jwt    pkgzmiddleware.Authenticator
+	db     db.Store
+	logger *logger.ServiceLogger
+}
+
+// NewAuthenticator creates an Authenticator falling back to jwt for requests without an API key
+func NewAuthenticator(jwt pkgzmiddleware.Authenticator, database db.Store, logger *logger.ServiceLogger) *Authenticator {
+	return &Authenticator{
+		jwt:    jwt,
+		db:     database,
+		logger: logger.WithField("component", "auth"),
+	}
+}
+
+// Auth requires a JWT session. API keys are refused, which keeps account
+// management such as creating keys out of reach of a leaked key.
+func (a *Authenticator) Auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := bearerAPIKey(r); ok {
+			writeAuthError(w, "API keys cannot be used for this endpoint", http.StatusForbidden)
+			return
+		}
+		a.jwt.Auth(withUser(next)).ServeHTTP(w, r)
+	})
+}
+
+// Scoped accepts a JWT session or an API key that allows scope for the request method
+func (a *Authenticator) Scoped(scope model.APIKeyScope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := bearerAPIKey(r)
+		if !ok {
+			a.jwt.Auth(withUser(next)).ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		key, err := a.db.GetAPIKeyByHash(ctx, HashToken(raw))
+		if err != nil {
+			a.logger.Errorf("Failed to look up API key: %v", err)
+			writeAuthError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if key == nil {
+			writeAuthError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !key.Allows(scope, r.Method) {
+			a.logger.Debugf("API key %s lacks scope %s for %s %s", key.ID, scope, r.Method, r.URL.Path)
+			writeAuthError(w, "API key does not grant access to this endpoint", http.StatusForbidden)
+			return
+		}
+
+		if err := a.db.TouchAPIKey(ctx, key.ID, time.Now().UTC()); err != nil {
+			a.logger.Warnf("Failed to record use of API key %s: %v", key.ID, err)
+		}
+
+		user := token.User{ID: "apikey_" + key.ID.String(), Name: key.Name}
+		SetUserID(&user, key.UserID)
+		user.SetStrAttr(APIKeyIDAttr, key.ID.String())
+		next.ServeHTTP(w, middleware.SetUser(token.SetUserInfo(r, user), key.UserID.String()))
+	})
+}
+
+// withUser records the user of a request authenticated by JWT for the access log
+func withUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID, err := UserIDFromRequest(r); err == nil {
+			r = middleware.SetUser(r, userID.String())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerAPIKey returns the API key from the Authorization header, if one was sent
+func bearerAPIKey(r *http.Request) (string, bool) {
+	scheme, value, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	value = strings.TrimSpace(value)
+	return value, strings.HasPrefix(value, APIKeyPrefix)
+}
+
+// writeAuthError writes an error in the JSON format of the API handlers
+func writeAuthError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"message": message,
+	})
+}
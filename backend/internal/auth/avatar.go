@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/sha1"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/go-pkgz/auth/v2/token"
+)
+
+// avatarSuffix is appended to avatar IDs by the go-pkgz avatar stores
+const avatarSuffix = ".image"
+
+// AvatarIDs returns the IDs of the avatars the auth service may have stored for
+// a user, one for every way the user signed in
+func AvatarIDs(user *model.User, identities []*model.UserIdentity) []string {
+	ids := []string{
+		avatarID(string(model.AuthProviderLocal), user.ID.String()),
+		// Local sign-ins used to be keyed by email
+		avatarID(string(model.AuthProviderLocal), user.Email),
+	}
+	for _, identity := range identities {
+		ids = append(ids, avatarID(string(identity.Provider), identity.Subject))
+	}
+	return ids
+}
+
+// avatarID mirrors how go-pkgz derives the avatar ID from the token user ID
+func avatarID(provider, subject string) string {
+	return token.HashID(sha1.New(), tokenUserID(provider, subject)) + avatarSuffix
+}
+
+// tokenUserID mirrors how go-pkgz derives the token user ID from a provider subject
+func tokenUserID(provider, subject string) string {
+	return provider + "_" + token.HashID(sha1.New(), subject)
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-pkgz/auth/v2/token"
+	"github.com/google/uuid"
+)
+
+// UserIDAttr is the JWT user attribute holding the ferna user ID
+const UserIDAttr = "user_id"
+
+// SetUserID stores the ferna user ID on the token user
+func SetUserID(user *token.User, id uuid.UUID) {
+	user.SetStrAttr(UserIDAttr, id.String())
+}
+
+// UserIDFromRequest returns the ferna user ID of the authenticated request.
+// It relies on the auth middleware having populated the user info.
+func UserIDFromRequest(r *http.Request) (uuid.UUID, error) {
+	user, err := token.GetUserInfo(r)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	raw := user.StrAttr(UserIDAttr)
+	if raw == "" {
+		return uuid.Nil, fmt.Errorf("no user id in token for %s", user.ID)
+	}
+
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid user id in token: %w", err)
+	}
+	return id, nil
+}
@@ -1,15 +1,22 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/anish-chanda/ferna/internal/metrics"
+	"go.opentelemetry.io/otel"
 	"golang.org/x/crypto/argon2"
 )
 
+// tracer records the expensive steps of authentication as spans of the request
+var tracer = otel.Tracer("github.com/anish-chanda/ferna/internal/auth")
+
 const (
 	argonTime    = 1
 	argonMemory  = 64 * 1024
@@ -20,11 +27,13 @@ const (
 
 // hashPassword applies Argon2id with OWASP‐recommended params and returns
 // a single string in the standard “$argon2id$v=19$m=…,t=…,p=…$salt$hash” format.
-func HashPassword(password string) (string, error) {
+func HashPassword(ctx context.Context, password string) (string, error) {
 	salt := make([]byte, argonSaltLen)
 	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
+	_, span := tracer.Start(ctx, "argon2id.hash")
+	start := time.Now()
 	hash := argon2.IDKey(
 		[]byte(password),
 		salt,
@@ -33,6 +42,8 @@ func HashPassword(password string) (string, error) {
 		argonThreads,
 		argonKeyLen,
 	)
+	metrics.Argon2Duration.WithLabelValues("hash").Observe(time.Since(start).Seconds())
+	span.End()
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
 	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
 	parts := []string{
@@ -46,7 +57,7 @@ func HashPassword(password string) (string, error) {
 }
 
 // verifyPassword parses and verifies an encoded Argon2id hash.
-func VerifyPassword(password, encoded string) (bool, error) {
+func VerifyPassword(ctx context.Context, password, encoded string) (bool, error) {
 	// encoded: $argon2id$v=19$m=...,t=...,p=...$<salt>$<hash>
 	fields := strings.Split(encoded, "$")
 	if len(fields) != 6 || fields[1] != "argon2id" {
@@ -65,7 +76,11 @@ func VerifyPassword(password, encoded string) (bool, error) {
 		return false, err
 	}
 
+	_, span := tracer.Start(ctx, "argon2id.verify")
+	start := time.Now()
 	computed := argon2.IDKey([]byte(password), salt, timeParam, memory, uint8(threads), uint32(len(hash)))
+	metrics.Argon2Duration.WithLabelValues("verify").Observe(time.Since(start).Seconds())
+	span.End()
 	// constant-time compare
 	if subtle.ConstantTimeCompare(computed, hash) == 1 {
 		return true, nil
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-pkgz/auth/v2/provider"
+	"github.com/go-pkgz/auth/v2/token"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/facebook"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// OAuthSubjectAttr holds the provider's stable account ID
+	OAuthSubjectAttr = "oauth_subject"
+	// OAuthEmailVerifiedAttr is set when the provider vouches for the email address
+	OAuthEmailVerifiedAttr = "oauth_email_verified"
+	// LinkRequiredAttr marks a token whose email belongs to an account the
+	// identity is not linked to yet. Such tokens carry no user ID.
+	LinkRequiredAttr = "link_required"
+	// TwoFactorRequiredAttr marks a token of an OAuth login to an account with
+	// two-factor authentication. Such tokens carry no user ID either, the
+	// session is only issued once the second factor is entered.
+	TwoFactorRequiredAttr = "two_factor_required"
+
+	defaultGoogleUserInfoURL   = "https://openidconnect.googleapis.com/v1/userinfo"
+	defaultFacebookUserInfoURL = "https://graph.facebook.com/me?fields=id,name,email,picture"
+)
+
+// OAuthConfig holds the client credentials of an OAuth provider. The endpoint
+// URLs default to the real provider and can be pointed at a fake server in tests.
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+}
+
+// Enabled reports whether the provider is configured
+func (c OAuthConfig) Enabled() bool {
+	return c.ClientID != "" && c.ClientSecret != ""
+}
+
+// endpoint returns the configured endpoint, falling back to def for empty URLs
+func (c OAuthConfig) endpoint(def oauth2.Endpoint) oauth2.Endpoint {
+	if c.AuthURL != "" {
+		def.AuthURL = c.AuthURL
+	}
+	if c.TokenURL != "" {
+		def.TokenURL = c.TokenURL
+	}
+	return def
+}
+
+// userInfoURL returns the configured user info URL or def
+func (c OAuthConfig) userInfoURL(def string) string {
+	if c.UserInfoURL != "" {
+		return c.UserInfoURL
+	}
+	return def
+}
+
+// GoogleProvider returns go-pkgz handler options for Google sign-in through OpenID Connect
+func GoogleProvider(cfg OAuthConfig) provider.CustomHandlerOpt {
+	return provider.CustomHandlerOpt{
+		Endpoint: cfg.endpoint(google.Endpoint),
+		InfoURL:  cfg.userInfoURL(defaultGoogleUserInfoURL),
+		Scopes:   []string{"openid", "email", "profile"},
+		MapUserFn: func(data provider.UserData, _ []byte) token.User {
+			user := token.User{
+				ID:      "google_" + token.HashID(sha1.New(), data.Value("sub")),
+				Name:    data.Value("name"),
+				Picture: data.Value("picture"),
+				Email:   strings.ToLower(data.Value("email")),
+			}
+			user.SetStrAttr(OAuthSubjectAttr, data.Value("sub"))
+			user.SetBoolAttr(OAuthEmailVerifiedAttr, data.Value("email_verified") == "true")
+			return user
+		},
+	}
+}
+
+// FacebookProvider returns go-pkgz handler options for Facebook login
+func FacebookProvider(cfg OAuthConfig) provider.CustomHandlerOpt {
+	return provider.CustomHandlerOpt{
+		Endpoint: cfg.endpoint(facebook.Endpoint),
+		InfoURL:  cfg.userInfoURL(defaultFacebookUserInfoURL),
+		Scopes:   []string{"public_profile", "email"},
+		MapUserFn: func(data provider.UserData, raw []byte) token.User {
+			user := token.User{
+				ID:    "facebook_" + token.HashID(sha1.New(), data.Value("id")),
+				Name:  data.Value("name"),
+				Email: strings.ToLower(data.Value("email")),
+			}
+
+			var info struct {
+				Picture struct {
+					Data struct {
+						URL string `json:"url"`
+					} `json:"data"`
+				} `json:"picture"`
+			}
+			if err := json.Unmarshal(raw, &info); err == nil {
+				user.Picture = info.Picture.Data.URL
+			}
+
+			user.SetStrAttr(OAuthSubjectAttr, data.Value("id"))
+			// Facebook only returns confirmed email addresses
+			user.SetBoolAttr(OAuthEmailVerifiedAttr, user.Email != "")
+			return user
+		},
+	}
+}
+
+// ProviderFromUserID returns the provider prefix of a go-pkgz user ID, e.g. "google" for "google_3f2a..."
+func ProviderFromUserID(id string) string {
+	name, _, _ := strings.Cut(id, "_")
+	return name
+}
+
+// PendingLink is an OAuth identity waiting to be linked to an existing account
+type PendingLink struct {
+	Provider string
+	Subject  string
+	Email    string
+	// EmailVerified is set when the provider vouches for Email
+	EmailVerified bool
+}
+
+// PendingLinkFromRequest returns the identity of an authenticated request whose
+// token was marked with LinkRequiredAttr
+func PendingLinkFromRequest(r *http.Request) (*PendingLink, error) {
+	user, err := token.GetUserInfo(r)
+	if err != nil {
+		return nil, err
+	}
+	if !user.BoolAttr(LinkRequiredAttr) {
+		return nil, fmt.Errorf("token for %s has no identity to link", user.ID)
+	}
+
+	link := &PendingLink{
+		Provider:      ProviderFromUserID(user.ID),
+		Subject:       user.StrAttr(OAuthSubjectAttr),
+		Email:         user.Email,
+		EmailVerified: user.BoolAttr(OAuthEmailVerifiedAttr),
+	}
+	if link.Subject == "" || link.Email == "" {
+		return nil, fmt.Errorf("incomplete identity in token for %s", user.ID)
+	}
+	return link, nil
+}
+
+// PendingLogin is an OAuth login waiting for the second factor of its account
+type PendingLogin struct {
+	Provider string
+	Subject  string
+}
+
+// PendingLoginFromRequest returns the identity of an authenticated request whose
+// token was marked with TwoFactorRequiredAttr
+func PendingLoginFromRequest(r *http.Request) (*PendingLogin, error) {
+	user, err := token.GetUserInfo(r)
+	if err != nil {
+		return nil, err
+	}
+	if !user.BoolAttr(TwoFactorRequiredAttr) {
+		return nil, fmt.Errorf("token for %s has no login awaiting a second factor", user.ID)
+	}
+
+	login := &PendingLogin{
+		Provider: ProviderFromUserID(user.ID),
+		Subject:  user.StrAttr(OAuthSubjectAttr),
+	}
+	if login.Subject == "" {
+		return nil, fmt.Errorf("incomplete identity in token for %s", user.ID)
+	}
+	return login, nil
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+)
+
+// RequireRole requires a JWT session of a user holding role. The role is read
+// from the database on every request, so demoting a user takes effect without
+// waiting for their token to expire. API keys are refused like with Auth.
+func (a *Authenticator) RequireRole(role model.UserRole, next http.Handler) http.Handler {
+	return a.Auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := UserIDFromRequest(r)
+		if err != nil {
+			a.logger.Debugf("No user ID in authenticated request: %v", err)
+			writeAuthError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		user, err := a.db.GetUserByID(ctx, userID)
+		if err != nil {
+			a.logger.Errorf("Failed to load user %s for role check: %v", userID, err)
+			writeAuthError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil || user.DisabledAt != nil {
+			writeAuthError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !user.HasRole(role) {
+			a.logger.Infof("User %s lacks role %s for %s %s", userID, role, r.Method, r.URL.Path)
+			writeAuthError(w, "Insufficient permissions", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// Admin requires a JWT session of an instance admin
+func (a *Authenticator) Admin(next http.Handler) http.Handler {
+	return a.RequireRole(model.UserRoleAdmin, next)
+}
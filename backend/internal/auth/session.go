@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/go-pkgz/auth/v2/token"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Sessions issues the same JWT sessions as the go-pkgz local provider for
+// logins that are completed outside of it, such as the second factor step
+type Sessions struct {
+	tokens   *token.Service
+	audience string
+}
+
+// NewSessions creates a Sessions issuing tokens for audience
+func NewSessions(tokens *token.Service, audience string) *Sessions {
+	return &Sessions{tokens: tokens, audience: audience}
+}
+
+// Issue sets the JWT of a new session for a local user on the response.
+// A session-only login gets a cookie that ends with the browser session.
+func (s *Sessions) Issue(w http.ResponseWriter, user *model.User, sessionOnly bool) (*token.User, error) {
+	cid, _, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Matches the user the local provider creates, so the session is indistinguishable
+	tokenUser := &token.User{
+		Name: user.Email,
+		ID:   tokenUserID(string(model.AuthProviderLocal), user.ID.String()),
+	}
+	SetUserID(tokenUser, user.ID)
+
+	claims, err := s.tokens.Set(w, token.Claims{
+		User: tokenUser,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       cid,
+			Audience: []string{s.audience},
+		},
+		SessionOnly: sessionOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims.User, nil
+}
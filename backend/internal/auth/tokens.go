@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// tokenBytes is the entropy of generated tokens
+const tokenBytes = 32
+
+// GenerateToken returns a random URL-safe token and the hash to store for it.
+// The token itself is only ever given to the user.
+func GenerateToken() (string, []byte, error) {
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the SHA-256 hash of a token. Tokens carry enough entropy
+// that a fast hash is sufficient to keep stored values useless if leaked.
+func HashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
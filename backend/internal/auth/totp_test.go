@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db/dbtest"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890"
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// The RFC lists 8 digit codes, the 6 digit codes are their last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	key := []byte("12345678901234567890")
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+
+		step, ok := ValidateTOTP(rfc6238Secret, tt.want, time.Unix(tt.unix, 0))
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%s) at %d = %d, %v, want step %d", tt.want, tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// 1111111109 is in step 37037036, whose code is 081804
+	const code = "081804"
+	const step = 37037036
+	start := time.Unix(step*totpPeriod, 0)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		ok   bool
+	}{
+		{"start of the step", start, true},
+		{"end of the step", start.Add(totpPeriod*time.Second - time.Second), true},
+		{"one step late", start.Add(totpPeriod * time.Second), true},
+		{"one step early", start.Add(-totpPeriod * time.Second), true},
+		{"two steps late", start.Add(2 * totpPeriod * time.Second), false},
+		{"two steps early", start.Add(-time.Second - totpPeriod*time.Second), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValidateTOTP(rfc6238Secret, code, tt.now)
+			if ok != tt.ok {
+				t.Fatalf("ValidateTOTP at %s = %v, want %v", tt.now.UTC().Format(time.RFC3339), ok, tt.ok)
+			}
+			// The step is the one the code belongs to, not the current one
+			if ok && got != step {
+				t.Errorf("ValidateTOTP returned step %d, want %d", got, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejectsMalformed(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870822", "28708a", "94287082"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now); ok {
+			t.Errorf("ValidateTOTP accepted %q", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "287082", now); ok {
+		t.Errorf("ValidateTOTP accepted an invalid secret")
+	}
+	// Surrounding whitespace and a lower case secret are fine
+	if _, ok := ValidateTOTP(strings.ToLower(rfc6238Secret), " 287082 ", now); !ok {
+		t.Errorf("ValidateTOTP rejected a code with whitespace")
+	}
+}
+
+// TestTOTPReplay checks that a code accepted once, or any code of an earlier
+// step within the skew window, is refused by UseTOTPStep
+func TestTOTPReplay(t *testing.T) {
+	database := dbtest.NewSQLite(t)
+	ctx := context.Background()
+
+	// A fixed time keeps the codes of neighbouring steps distinct
+	secret := rfc6238Secret
+	key := []byte("12345678901234567890")
+	now := time.Unix(1111111111, 0)
+
+	hash := "unused"
+	userID, err := database.CreateUser(ctx, &model.User{
+		ID:           uuid.New(),
+		AuthProvider: model.AuthProviderLocal,
+		Email:        "user@example.com",
+		FullName:     "User",
+		PasswordHash: &hash,
+		Timezone:     "UTC",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, err := database.SaveTOTPSecret(ctx, userID, secret); err != nil {
+		t.Fatalf("SaveTOTPSecret failed: %v", err)
+	}
+
+	current := now.Unix() / totpPeriod
+	// Enrolment uses the code of the previous step
+	if ok, err := database.EnableTOTP(ctx, userID, current-1, nil); err != nil || !ok {
+		t.Fatalf("EnableTOTP = %v, %v", ok, err)
+	}
+
+	use := func(step int64) bool {
+		t.Helper()
+		got, valid := ValidateTOTP(secret, totpCode(key, step), now)
+		if !valid || got != step {
+			t.Fatalf("code of step %d was not valid", step)
+		}
+		ok, err := database.UseTOTPStep(ctx, userID, got)
+		if err != nil {
+			t.Fatalf("UseTOTPStep failed: %v", err)
+		}
+		return ok
+	}
+
+	if use(current - 1) {
+		t.Errorf("the enrolment code was accepted again")
+	}
+	if !use(current) {
+		t.Fatalf("the current code was refused")
+	}
+	if use(current) {
+		t.Errorf("the current code was accepted twice")
+	}
+	if use(current - 1) {
+		t.Errorf("an earlier code was accepted after a later one")
+	}
+	if !use(current + 1) {
+		t.Errorf("the next code was refused")
+	}
+}
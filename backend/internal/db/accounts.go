@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ScheduleUserDeletion marks a user for erasure at the given time. A deletion
+// that is already scheduled keeps its time. Returns nil if the user does not exist.
+func (db *PostgresDB) ScheduleUserDeletion(ctx context.Context, userID uuid.UUID, at time.Time) (*time.Time, error) {
+	query := `UPDATE users SET deletion_scheduled_at = COALESCE(deletion_scheduled_at, $2), updated_at = NOW()
+			  WHERE id = $1
+			  RETURNING deletion_scheduled_at`
+
+	var scheduledAt time.Time
+	err := db.Pool.QueryRow(ctx, query, userID, at).Scan(&scheduledAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to schedule deletion of user %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to schedule account deletion: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "Deletion of user %s scheduled for %s", userID, scheduledAt.Format(time.RFC3339))
+	return &scheduledAt, nil
+}
+
+// CancelUserDeletion clears a scheduled deletion. Returns false if none was scheduled.
+func (db *PostgresDB) CancelUserDeletion(ctx context.Context, userID uuid.UUID) (bool, error) {
+	query := `UPDATE users SET deletion_scheduled_at = NULL, updated_at = NOW()
+			  WHERE id = $1 AND deletion_scheduled_at IS NOT NULL`
+
+	tag, err := db.Pool.Exec(ctx, query, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to cancel deletion of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	db.logger.InfofContext(ctx, "Deletion of user %s cancelled", userID)
+	return true, nil
+}
+
+// ListUsersDueForDeletion returns up to limit users whose grace period ended before now
+func (db *PostgresDB) ListUsersDueForDeletion(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	query := `SELECT id FROM users
+			  WHERE deletion_scheduled_at <= $1
+			  ORDER BY deletion_scheduled_at
+			  LIMIT $2`
+
+	rows, err := db.Pool.Query(ctx, query, now, limit)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list users due for deletion: %v", err)
+		return nil, fmt.Errorf("failed to list users due for deletion: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list users due for deletion: %w", err)
+	}
+
+	return ids, nil
+}
+
+// EraseUser deletes a user and, through cascades, their plants, schedules,
+// events, photos, devices, API keys, identities and tokens in one transaction.
+// Mail to the user's address is dropped from the outbox. The blobs of their
+// photos and the given avatar files are queued as file deletions in the same
+// transaction, so no file is forgotten if the process stops halfway.
+// With dueBy set the user is only erased if their deletion is scheduled at or
+// before it, so a cancellation that raced the purge wins.
+// Returns false if the user does not exist or is not due.
+func (db *PostgresDB) EraseUser(ctx context.Context, userID uuid.UUID, avatarKeys []string, dueBy *time.Time) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	err = tx.QueryRow(ctx, `SELECT email FROM users WHERE id = $1
+			  AND ($2::timestamptz IS NULL OR deletion_scheduled_at <= $2)
+			  FOR UPDATE`, userID, dueBy).Scan(&email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO file_deletions (store, key)
+			  SELECT 'blobs', k.key
+			  FROM plant_photos p
+			  CROSS JOIN LATERAL unnest(ARRAY[p.blob_key, p.thumbnail_key]) AS k(key)
+			  WHERE p.user_id = $1`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to queue photo deletions: %w", err)
+	}
+
+	if len(avatarKeys) > 0 {
+		_, err = tx.Exec(ctx, `INSERT INTO file_deletions (store, key)
+				  SELECT 'avatars', key FROM unnest($1::text[]) AS key`, avatarKeys)
+		if err != nil {
+			return false, fmt.Errorf("failed to queue avatar deletions: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mail_outbox WHERE to_address = $1`, email); err != nil {
+		return false, fmt.Errorf("failed to delete mail: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to delete user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit account erasure: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "User %s erased", userID)
+	return true, nil
+}
+
+// ClaimFileDeletions leases up to limit file deletions whose next attempt is due
+func (db *PostgresDB) ClaimFileDeletions(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.FileDeletion, error) {
+	query := `UPDATE file_deletions
+			  SET attempts = attempts + 1, next_attempt_at = $2
+			  WHERE id IN (
+				  SELECT id FROM file_deletions
+				  WHERE next_attempt_at <= $1
+				  ORDER BY next_attempt_at
+				  LIMIT $3
+				  FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING id, store, key, attempts`
+
+	rows, err := db.Pool.Query(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to claim file deletions: %v", err)
+		return nil, fmt.Errorf("failed to claim file deletions: %w", err)
+	}
+	defer rows.Close()
+
+	deletions := []*model.FileDeletion{}
+	for rows.Next() {
+		var deletion model.FileDeletion
+		if err := rows.Scan(&deletion.ID, &deletion.Store, &deletion.Key, &deletion.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan file deletion: %w", err)
+		}
+		deletions = append(deletions, &deletion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim file deletions: %w", err)
+	}
+
+	return deletions, nil
+}
+
+// CompleteFileDeletion removes a file deletion once the file is gone
+func (db *PostgresDB) CompleteFileDeletion(ctx context.Context, id uuid.UUID) error {
+	if _, err := db.Pool.Exec(ctx, `DELETE FROM file_deletions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to complete file deletion: %w", err)
+	}
+	return nil
+}
+
+// MarkFileDeletionFailed records a failed attempt, retrying at retryAt
+func (db *PostgresDB) MarkFileDeletionFailed(ctx context.Context, id uuid.UUID, reason string, retryAt time.Time) error {
+	query := `UPDATE file_deletions SET last_error = $2, next_attempt_at = $3 WHERE id = $1`
+
+	if _, err := db.Pool.Exec(ctx, query, id, reason, retryAt); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to record file deletion %s failure: %v", id, err)
+		return fmt.Errorf("failed to record file deletion failure: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, last_used_at, created_at`
+
+// scanAPIKey scans a single API key row in apiKeyColumns order
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var key model.APIKey
+	var scopes []string
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&scopes,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = make([]model.APIKeyScope, len(scopes))
+	for i, s := range scopes {
+		key.Scopes[i] = model.APIKeyScope(s)
+	}
+	return &key, nil
+}
+
+// CreateAPIKey stores a new API key, filling in created_at
+func (db *PostgresDB) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	scopes := make([]string, len(key.Scopes))
+	for i, s := range key.Scopes {
+		scopes[i] = string(s)
+	}
+
+	query := `INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, NOW())
+			  RETURNING created_at`
+
+	err := db.Pool.QueryRow(ctx, query,
+		key.ID,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		scopes,
+	).Scan(&key.CreatedAt)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create API key for user %s: %v", key.UserID, err)
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "API key %s created for user %s", key.ID, key.UserID)
+	return nil
+}
+
+// ListAPIKeysByUser returns the API keys of a user, newest first
+func (db *PostgresDB) ListAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]*model.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC, id`
+
+	rows, err := db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list API keys for user %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*model.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// CountAPIKeysByUser returns how many API keys a user has
+func (db *PostgresDB) CountAPIKeysByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM api_keys WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+	return count, nil
+}
+
+// GetAPIKeyByHash fetches the API key with the given hash. Keys of disabled
+// accounts are treated as unknown. Returns nil if not found.
+func (db *PostgresDB) GetAPIKeyByHash(ctx context.Context, hash []byte) (*model.APIKey, error) {
+	query := `SELECT ` + prefixColumns("k", apiKeyColumns) + ` FROM api_keys k
+			  JOIN users u ON u.id = k.user_id
+			  WHERE k.key_hash = $1 AND u.disabled_at IS NULL`
+
+	key, err := scanAPIKey(db.Pool.QueryRow(ctx, query, hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to get API key: %v", err)
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return key, nil
+}
+
+// TouchAPIKey records that a key was used at usedAt. To avoid a write on every
+// request the timestamp is only updated once per minute.
+func (db *PostgresDB) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $2
+			  WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2 - INTERVAL '1 minute')`
+
+	if _, err := db.Pool.Exec(ctx, query, id, usedAt); err != nil {
+		return fmt.Errorf("failed to update API key usage: %w", err)
+	}
+	return nil
+}
+
+// DeleteAPIKey revokes an API key of a user. Returns false if nothing was deleted.
+func (db *PostgresDB) DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to delete API key %s: %v", id, err)
+		return false, fmt.Errorf("failed to delete API key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	db.logger.InfofContext(ctx, "API key %s revoked for user %s", id, userID)
+	return true, nil
+}
@@ -0,0 +1,30 @@
+// Package dbtest provides databases for tests
+package dbtest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/migrations"
+)
+
+// NewSQLite opens a migrated SQLite database in a temporary directory of t,
+// which is closed when the test finishes
+func NewSQLite(t testing.TB) *db.SQLiteDB {
+	t.Helper()
+	ctx := context.Background()
+	log := logger.New(logger.Config{Level: "error"})
+
+	database, err := db.NewSQLiteDB(ctx, db.Config{DSN: "sqlite://" + filepath.Join(t.TempDir(), "ferna.db")}, log)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(database.Close)
+	if err := migrations.RunSQLiteMigrations(ctx, database.DB, log); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return database
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// CreateEmailVerificationToken stores the hash of a token confirming email for
+// a user, replacing any token sent to the user before
+func (db *PostgresDB) CreateEmailVerificationToken(ctx context.Context, userID uuid.UUID, email string, tokenHash []byte, expiresAt time.Time) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM email_verification_tokens WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete previous verification tokens: %w", err)
+	}
+
+	query := `INSERT INTO email_verification_tokens (id, user_id, email, token_hash, expires_at, created_at)
+			  VALUES ($1, $2, $3, $4, $5, NOW())`
+	if _, err := tx.Exec(ctx, query, uuid.New(), userID, email, tokenHash, expiresAt); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create verification token for user %s: %v", userID, err)
+		return fmt.Errorf("failed to create verification token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit verification token: %w", err)
+	}
+	return nil
+}
+
+// ErrEmailInUse is returned when an email change is confirmed for an address
+// that another account took in the meantime
+var ErrEmailInUse = errors.New("email address is already in use")
+
+// VerifyEmail consumes a verification token and marks the address it was sent
+// to as verified. If the token was sent for an email change the address becomes
+// the user's email. Returns false if the token is unknown or expired.
+func (db *PostgresDB) VerifyEmail(ctx context.Context, tokenHash []byte) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID uuid.UUID
+	var email string
+	err = tx.QueryRow(ctx, `DELETE FROM email_verification_tokens
+			  WHERE token_hash = $1 AND expires_at > NOW()
+			  RETURNING user_id, email`, tokenHash).Scan(&userID, &email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to consume verification token: %w", err)
+	}
+
+	// Creating a token replaces older ones, so this is the latest address the user asked for
+	query := `UPDATE users SET email = $2, email_verified_at = NOW(), updated_at = NOW()
+			  WHERE id = $1`
+	tag, err := tx.Exec(ctx, query, userID, email)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "users_email_unique" {
+			return false, ErrEmailInUse
+		}
+		db.logger.DebugfContext(ctx, "Failed to verify email of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to verify email: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit email verification: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "Email verified for user %s", userID)
+	return true, nil
+}
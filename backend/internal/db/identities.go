@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetUserByIdentity fetches the user an OAuth identity is linked to. Returns nil if not linked.
+func (db *PostgresDB) GetUserByIdentity(ctx context.Context, provider model.AuthProvider, subject string) (*model.User, error) {
+	query := `SELECT ` + prefixColumns("u", userColumns) + `
+			  FROM user_identities i
+			  JOIN users u ON u.id = i.user_id
+			  WHERE i.provider = $1 AND i.subject = $2`
+
+	user, err := scanUser(db.Pool.QueryRow(ctx, query, provider, subject))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to get user by %s identity: %v", provider, err)
+		return nil, fmt.Errorf("failed to get user by identity: %w", err)
+	}
+	return user, nil
+}
+
+// CreateOAuthUser inserts a user without a password together with the identity it signed up with
+func (db *PostgresDB) CreateOAuthUser(ctx context.Context, user *model.User, identity *model.UserIdentity) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO users (id, avatar_url, auth_provider, email, full_name, password_hash, timezone, email_verified_at, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, NULL, $6, $7, NOW(), NOW())
+			  RETURNING created_at, updated_at`
+	err = tx.QueryRow(ctx, query,
+		user.ID,
+		user.AvatarURL,
+		user.AuthProvider,
+		user.Email,
+		user.FullName,
+		user.Timezone,
+		user.EmailVerifiedAt,
+	).Scan(&user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create %s user %s: %v", user.AuthProvider, user.Email, err)
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if err := insertIdentity(ctx, tx, identity); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit user: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "User created through %s: %s", user.AuthProvider, user.Email)
+	return nil
+}
+
+// LinkIdentity attaches an OAuth identity to an existing user
+func (db *PostgresDB) LinkIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	if err := insertIdentity(ctx, db.Pool, identity); err != nil {
+		return err
+	}
+	db.logger.InfofContext(ctx, "Linked %s identity to user %s", identity.Provider, identity.UserID)
+	return nil
+}
+
+// insertIdentity stores an identity using q, filling in created_at
+func insertIdentity(ctx context.Context, q queryRower, identity *model.UserIdentity) error {
+	query := `INSERT INTO user_identities (id, user_id, provider, subject, email, created_at)
+			  VALUES ($1, $2, $3, $4, $5, NOW())
+			  RETURNING created_at`
+
+	err := q.QueryRow(ctx, query,
+		identity.ID,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+	).Scan(&identity.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+// ListIdentitiesByUser returns the OAuth identities linked to a user
+func (db *PostgresDB) ListIdentitiesByUser(ctx context.Context, userID uuid.UUID) ([]*model.UserIdentity, error) {
+	query := `SELECT id, user_id, provider, subject, email, created_at
+			  FROM user_identities WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list identities of user %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []*model.UserIdentity{}
+	for rows.Next() {
+		var identity model.UserIdentity
+		err := rows.Scan(
+			&identity.ID,
+			&identity.UserID,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+			&identity.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		identities = append(identities, &identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+
+	return identities, nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anish-chanda/ferna/model"
+)
+
+// instanceStatsQuery counts the accounts and data of the instance in one round trip
+const instanceStatsQuery = `SELECT
+	(SELECT count(*) FROM users),
+	(SELECT count(*) FROM users WHERE role = 'admin'),
+	(SELECT count(*) FROM users WHERE disabled_at IS NOT NULL),
+	(SELECT count(*) FROM users WHERE email_verified_at IS NULL),
+	(SELECT count(*) FROM users WHERE deletion_scheduled_at IS NOT NULL),
+	(SELECT count(*) FROM plants),
+	(SELECT count(*) FROM care_schedules),
+	(SELECT count(*) FROM care_events),
+	(SELECT count(*) FROM plant_photos),
+	(SELECT coalesce(sum(size_bytes), 0) FROM plant_photos),
+	(SELECT count(*) FROM api_keys),
+	(SELECT count(*) FROM devices)`
+
+// scanInstanceStats scans the row of instanceStatsQuery
+func scanInstanceStats(row rowScanner) (*model.InstanceStats, error) {
+	var stats model.InstanceStats
+	err := row.Scan(
+		&stats.Users,
+		&stats.Admins,
+		&stats.DisabledUsers,
+		&stats.UnverifiedUsers,
+		&stats.PendingDeletions,
+		&stats.Plants,
+		&stats.CareSchedules,
+		&stats.CareEvents,
+		&stats.Photos,
+		&stats.PhotoBytes,
+		&stats.APIKeys,
+		&stats.Devices,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetInstanceSettings returns the runtime settings of the instance
+func (db *PostgresDB) GetInstanceSettings(ctx context.Context) (*model.InstanceSettings, error) {
+	query := `SELECT registration_enabled, updated_at FROM instance_settings`
+
+	var settings model.InstanceSettings
+	if err := db.Pool.QueryRow(ctx, query).Scan(&settings.RegistrationEnabled, &settings.UpdatedAt); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to get instance settings: %v", err)
+		return nil, fmt.Errorf("failed to get instance settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SetRegistrationEnabled opens or closes sign up and returns the updated settings
+func (db *PostgresDB) SetRegistrationEnabled(ctx context.Context, enabled bool) (*model.InstanceSettings, error) {
+	query := `UPDATE instance_settings SET registration_enabled = $1, updated_at = NOW()
+			  RETURNING registration_enabled, updated_at`
+
+	var settings model.InstanceSettings
+	if err := db.Pool.QueryRow(ctx, query, enabled).Scan(&settings.RegistrationEnabled, &settings.UpdatedAt); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to update registration setting: %v", err)
+		return nil, fmt.Errorf("failed to update registration setting: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "Registration enabled set to %t", enabled)
+	return &settings, nil
+}
+
+// GetInstanceStats counts the accounts and data stored on the instance
+func (db *PostgresDB) GetInstanceStats(ctx context.Context) (*model.InstanceStats, error) {
+	stats, err := scanInstanceStats(db.Pool.QueryRow(ctx, instanceStatsQuery))
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to get instance stats: %v", err)
+		return nil, fmt.Errorf("failed to get instance stats: %w", err)
+	}
+	return stats, nil
+}
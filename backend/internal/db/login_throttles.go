@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// LoginBlockedUntil returns until when logins from ip or for account are
+// blocked, or nil if neither is blocked at now
+func (db *PostgresDB) LoginBlockedUntil(ctx context.Context, ip, account string, now time.Time) (*time.Time, error) {
+	query := `SELECT max(blocked_until) FROM login_throttles
+			  WHERE ((scope = 'ip' AND key = $1) OR (scope = 'account' AND key = $2))
+			  AND blocked_until > $3`
+
+	var until *time.Time
+	if err := db.Pool.QueryRow(ctx, query, ip, account, now).Scan(&until); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to check login throttle: %v", err)
+		return nil, fmt.Errorf("failed to check login throttle: %w", err)
+	}
+	return until, nil
+}
+
+// ReserveLoginAttempt counts a login attempt of a throttle key as a failure
+// before its password is checked and returns the number of failures in a row,
+// or 0 without counting anything if the key is blocked at now. The count starts
+// over when the previous failure is older than windowStart or the count reached
+// limit and the lockout has passed. The attempt that reaches limit blocks the
+// key until lockedUntil in the same statement, so concurrent attempts cannot
+// get past the limit.
+func (db *PostgresDB) ReserveLoginAttempt(ctx context.Context, scope model.ThrottleScope, key string, now, windowStart time.Time, limit int, lockedUntil time.Time) (int, error) {
+	query := `INSERT INTO login_throttles (scope, key, failures, last_failure_at)
+			  VALUES ($1, $2, 1, $3)
+			  ON CONFLICT (scope, key) DO UPDATE
+			  SET failures = CASE
+					  WHEN login_throttles.last_failure_at < $4 OR login_throttles.failures >= $5 THEN 1
+					  ELSE login_throttles.failures + 1
+				  END,
+				  blocked_until = CASE
+					  WHEN login_throttles.last_failure_at >= $4 AND login_throttles.failures = $5 - 1 THEN $6::timestamptz
+					  ELSE login_throttles.blocked_until
+				  END,
+				  last_failure_at = EXCLUDED.last_failure_at
+			  WHERE login_throttles.blocked_until IS NULL OR login_throttles.blocked_until <= $3
+			  RETURNING failures`
+
+	var failures int
+	if err := db.Pool.QueryRow(ctx, query, scope, key, now, windowStart, limit, lockedUntil).Scan(&failures); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to reserve login attempt for %s %s: %v", scope, key, err)
+		return 0, fmt.Errorf("failed to reserve login attempt: %w", err)
+	}
+	return failures, nil
+}
+
+// ReleaseLoginAttempt takes back a reserved attempt that turned out not to be
+// a failure. The lockout until lockedUntil is lifted if that attempt set it.
+func (db *PostgresDB) ReleaseLoginAttempt(ctx context.Context, scope model.ThrottleScope, key string, lockedUntil time.Time) error {
+	query := `UPDATE login_throttles
+			  SET failures = GREATEST(failures - 1, 0),
+				  blocked_until = CASE WHEN blocked_until = $3 THEN NULL ELSE blocked_until END
+			  WHERE scope = $1 AND key = $2`
+
+	if _, err := db.Pool.Exec(ctx, query, scope, key, lockedUntil); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to release login attempt for %s %s: %v", scope, key, err)
+		return fmt.Errorf("failed to release login attempt: %w", err)
+	}
+	return nil
+}
+
+// BlockLogin blocks logins for a throttle key until the given time. A longer
+// block that is already in place is kept.
+func (db *PostgresDB) BlockLogin(ctx context.Context, scope model.ThrottleScope, key string, until time.Time) error {
+	query := `UPDATE login_throttles SET blocked_until = GREATEST(blocked_until, $3) WHERE scope = $1 AND key = $2`
+
+	if _, err := db.Pool.Exec(ctx, query, scope, key, until); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to block logins for %s %s: %v", scope, key, err)
+		return fmt.Errorf("failed to block logins: %w", err)
+	}
+	return nil
+}
+
+// ClearLoginFailures forgets the failed logins of a throttle key after a successful login
+func (db *PostgresDB) ClearLoginFailures(ctx context.Context, scope model.ThrottleScope, key string) error {
+	if _, err := db.Pool.Exec(ctx, `DELETE FROM login_throttles WHERE scope = $1 AND key = $2`, scope, key); err != nil {
+		return fmt.Errorf("failed to clear login failures: %w", err)
+	}
+	return nil
+}
+
+// DeleteStaleLoginThrottles removes throttles whose last failure was before
+// the given time and that no longer block anything
+func (db *PostgresDB) DeleteStaleLoginThrottles(ctx context.Context, before, now time.Time) (int64, error) {
+	query := `DELETE FROM login_throttles
+			  WHERE last_failure_at < $1 AND (blocked_until IS NULL OR blocked_until <= $2)`
+
+	tag, err := db.Pool.Exec(ctx, query, before, now)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to delete stale login throttles: %v", err)
+		return 0, fmt.Errorf("failed to delete stale login throttles: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
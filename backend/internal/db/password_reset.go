@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreatePasswordResetToken stores the hash of a new reset token for a user,
+// replacing any unused token the user requested before
+func (db *PostgresDB) CreatePasswordResetToken(ctx context.Context, userID uuid.UUID, tokenHash []byte, expiresAt time.Time) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM password_reset_tokens WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return fmt.Errorf("failed to delete previous reset tokens: %w", err)
+	}
+
+	query := `INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
+			  VALUES ($1, $2, $3, $4, NOW())`
+	if _, err := tx.Exec(ctx, query, uuid.New(), userID, tokenHash, expiresAt); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create reset token for user %s: %v", userID, err)
+		return fmt.Errorf("failed to create reset token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit reset token: %w", err)
+	}
+	return nil
+}
+
+// GetPasswordResetUserID returns the user a reset token belongs to, or uuid.Nil
+// if the token is unknown, used or expired
+func (db *PostgresDB) GetPasswordResetUserID(ctx context.Context, tokenHash []byte) (uuid.UUID, error) {
+	query := `SELECT user_id FROM password_reset_tokens
+			  WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()`
+
+	var userID uuid.UUID
+	if err := db.Pool.QueryRow(ctx, query, tokenHash).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to look up reset token: %v", err)
+		return uuid.Nil, fmt.Errorf("failed to look up reset token: %w", err)
+	}
+	return userID, nil
+}
+
+// ResetPassword consumes a reset token and sets the user's password hash in a
+// single transaction, revoking all sessions of the user. Returns false if the
+// token is no longer valid, e.g. because it was used concurrently.
+func (db *PostgresDB) ResetPassword(ctx context.Context, tokenHash []byte, passwordHash string) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID uuid.UUID
+	err = tx.QueryRow(ctx, `UPDATE password_reset_tokens SET used_at = NOW()
+			  WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+			  RETURNING user_id`, tokenHash).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to consume reset token: %w", err)
+	}
+
+	query := `UPDATE users
+			  SET password_hash = $2, sessions_revoked_at = NOW(), updated_at = NOW()
+			  WHERE id = $1 AND auth_provider = 'local'`
+	tag, err := tx.Exec(ctx, query, userID, passwordHash)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to reset password of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to reset password: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM password_reset_tokens WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return false, fmt.Errorf("failed to delete remaining reset tokens: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit password reset: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "Password reset for user %s", userID)
+	return true, nil
+}
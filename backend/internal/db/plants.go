@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const plantColumns = `id, user_id, name, species, location, notes, acquired_at, created_at, updated_at`
+
+// scanPlant scans a single plant row in plantColumns order
+func scanPlant(row rowScanner) (*model.Plant, error) {
+	var plant model.Plant
+	err := row.Scan(
+		&plant.ID,
+		&plant.UserID,
+		&plant.Name,
+		&plant.Species,
+		&plant.Location,
+		&plant.Notes,
+		&plant.AcquiredAt,
+		&plant.CreatedAt,
+		&plant.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &plant, nil
+}
+
+// CreatePlant inserts a new plant and fills in the server generated timestamps
+func (db *PostgresDB) CreatePlant(ctx context.Context, plant *model.Plant) error {
+	query := `INSERT INTO plants (id, user_id, name, species, location, notes, acquired_at, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+			  RETURNING created_at, updated_at`
+
+	err := db.Pool.QueryRow(ctx, query,
+		plant.ID,
+		plant.UserID,
+		plant.Name,
+		plant.Species,
+		plant.Location,
+		plant.Notes,
+		plant.AcquiredAt,
+	).Scan(&plant.CreatedAt, &plant.UpdatedAt)
+
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create plant for user %s: %v", plant.UserID, err)
+		return fmt.Errorf("failed to create plant: %w", err)
+	}
+
+	db.logger.DebugfContext(ctx, "Plant created successfully: %s", plant.ID)
+	return nil
+}
+
+// GetPlant fetches a plant by ID, scoped to its owner. Returns nil if not found.
+func (db *PostgresDB) GetPlant(ctx context.Context, userID, plantID uuid.UUID) (*model.Plant, error) {
+	query := `SELECT ` + plantColumns + ` FROM plants WHERE id = $1 AND user_id = $2`
+
+	plant, err := scanPlant(db.Pool.QueryRow(ctx, query, plantID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to get plant %s: %v", plantID, err)
+		return nil, fmt.Errorf("failed to get plant: %w", err)
+	}
+
+	return plant, nil
+}
+
+// ListPlantsByUser returns all plants owned by a user, oldest first
+func (db *PostgresDB) ListPlantsByUser(ctx context.Context, userID uuid.UUID) ([]*model.Plant, error) {
+	query := `SELECT ` + plantColumns + ` FROM plants WHERE user_id = $1 ORDER BY created_at, id`
+
+	rows, err := db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list plants for user %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to list plants: %w", err)
+	}
+	defer rows.Close()
+
+	plants := []*model.Plant{}
+	for rows.Next() {
+		plant, err := scanPlant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan plant: %w", err)
+		}
+		plants = append(plants, plant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list plants: %w", err)
+	}
+
+	return plants, nil
+}
+
+// UpdatePlant saves the editable fields of a plant. Returns false if the plant
+// does not exist or is not owned by plant.UserID.
+func (db *PostgresDB) UpdatePlant(ctx context.Context, plant *model.Plant) (bool, error) {
+	query := `UPDATE plants
+			  SET name = $3, species = $4, location = $5, notes = $6, acquired_at = $7, updated_at = NOW()
+			  WHERE id = $1 AND user_id = $2
+			  RETURNING updated_at`
+
+	err := db.Pool.QueryRow(ctx, query,
+		plant.ID,
+		plant.UserID,
+		plant.Name,
+		plant.Species,
+		plant.Location,
+		plant.Notes,
+		plant.AcquiredAt,
+	).Scan(&plant.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to update plant %s: %v", plant.ID, err)
+		return false, fmt.Errorf("failed to update plant: %w", err)
+	}
+
+	return true, nil
+}
+
+// DeletePlant removes a plant owned by the user. Returns false if nothing was deleted.
+func (db *PostgresDB) DeletePlant(ctx context.Context, userID, plantID uuid.UUID) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM plants WHERE id = $1 AND user_id = $2`, plantID, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to delete plant %s: %v", plantID, err)
+		return false, fmt.Errorf("failed to delete plant: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
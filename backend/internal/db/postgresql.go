@@ -2,12 +2,15 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/anish-chanda/ferna/internal/logger"
 	"github.com/anish-chanda/ferna/model"
+	"github.com/exaring/otelpgx"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -40,6 +43,9 @@ func NewPostgresDB(ctx context.Context, cfg Config, logger *logger.ServiceLogger
 	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
 	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
 
+	// Trace every query, batch and connection as a span of the calling request or job
+	poolConfig.ConnConfig.Tracer = otelpgx.NewTracer(otelpgx.WithTrimSQLInSpanName())
+
 	// Create the connection pool
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
@@ -53,7 +59,7 @@ func NewPostgresDB(ctx context.Context, cfg Config, logger *logger.ServiceLogger
 	}
 
 	logger.Info("PostgreSQL connection pool initialized successfully")
-	logger.Debugf("Pool configuration - MaxConns: %d, MinConns: %d", cfg.MaxConns, cfg.MinConns)
+	logger.DebugfContext(ctx, "Pool configuration - MaxConns: %d, MinConns: %d", cfg.MaxConns, cfg.MinConns)
 
 	return &PostgresDB{
 		Pool:   pool,
@@ -69,6 +75,11 @@ func (db *PostgresDB) Close() {
 	}
 }
 
+// Ping checks that a connection can be acquired and the server answers
+func (db *PostgresDB) Ping(ctx context.Context) error {
+	return db.Pool.Ping(ctx)
+}
+
 // Stats returns connection pool statistics
 func (db *PostgresDB) Stats() *pgxpool.Stat {
 	return db.Pool.Stat()
@@ -80,19 +91,24 @@ func (db *PostgresDB) CheckIfEmailExists(ctx context.Context, email string) (boo
 	query := "SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)"
 	err := db.Pool.QueryRow(ctx, query, email).Scan(&exists)
 	if err != nil {
-		db.logger.Debugf("Failed to check email existence for %s: %v", email, err)
+		db.logger.DebugfContext(ctx, "Failed to check email existence for %s: %v", email, err)
 		return false, fmt.Errorf("failed to check email existence: %w", err)
 	}
-	db.logger.Debugf("Email existence check for %s: %v", email, exists)
+	db.logger.DebugfContext(ctx, "Email existence check for %s: %v", email, exists)
 	return exists, nil
 }
 
-// CreateUser inserts a new user and returns the user ID
+// CreateUser inserts a new user and returns the user ID. Users without a role
+// are created with UserRoleUser.
 func (db *PostgresDB) CreateUser(ctx context.Context, user *model.User) (uuid.UUID, error) {
-	query := `INSERT INTO users (id, avatar_url, auth_provider, email, full_name, password_hash, timezone, created_at, updated_at)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+	query := `INSERT INTO users (id, avatar_url, auth_provider, email, full_name, password_hash, timezone, role, email_verified_at, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
 			  RETURNING id`
 			  
+	if user.Role == "" {
+		user.Role = model.UserRoleUser
+	}
+
 	var userID uuid.UUID
 	err := db.Pool.QueryRow(ctx, query,
 		user.ID,
@@ -102,44 +118,50 @@ func (db *PostgresDB) CreateUser(ctx context.Context, user *model.User) (uuid.UU
 		user.FullName,
 		user.PasswordHash,
 		user.Timezone,
+		user.Role,
+		user.EmailVerifiedAt,
 	).Scan(&userID)
 	
 	if err != nil {
-		db.logger.Debugf("Failed to create user %s: %v", user.Email, err)
+		db.logger.DebugfContext(ctx, "Failed to create user %s: %v", user.Email, err)
 		return uuid.Nil, fmt.Errorf("failed to create user: %w", err)
 	}
 	
-	db.logger.Infof("User created successfully: %s", user.Email)
+	db.logger.InfofContext(ctx, "User created successfully: %s", user.Email)
 	return userID, nil
 }
 
 // GetUserByEmail fetches a user by email
 func (db *PostgresDB) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
-	query := `SELECT id, avatar_url, auth_provider, created_at, email, full_name, password_hash, timezone, updated_at
-			  FROM users WHERE email = $1`
-			  
-	var user model.User
-	err := db.Pool.QueryRow(ctx, query, email).Scan(
-		&user.ID,
-		&user.AvatarURL,
-		&user.AuthProvider,
-		&user.CreatedAt,
-		&user.Email,
-		&user.FullName,
-		&user.PasswordHash,
-		&user.Timezone,
-		&user.UpdatedAt,
-	)
-	
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+
+	user, err := scanUser(db.Pool.QueryRow(ctx, query, email))
 	if err != nil {
 		if err.Error() == "no rows in result set" {
-			db.logger.Debugf("User not found: %s", email)
+			db.logger.DebugfContext(ctx, "User not found: %s", email)
 			return nil, nil
 		}
-		db.logger.Debugf("Failed to get user by email %s: %v", email, err)
+		db.logger.DebugfContext(ctx, "Failed to get user by email %s: %v", email, err)
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
 	
-	db.logger.Debugf("User retrieved successfully: %s", email)
-	return &user, nil
+	db.logger.DebugfContext(ctx, "User retrieved successfully: %s", email)
+	return user, nil
+}
+
+// GetUserByID fetches a user by ID. Returns nil if not found.
+func (db *PostgresDB) GetUserByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+
+	user, err := scanUser(db.Pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			db.logger.DebugfContext(ctx, "User not found: %s", id)
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to get user by id %s: %v", id, err)
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	return user, nil
 }
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// EnqueueDueReminders queues a pending delivery on every channel for each care
+// schedule that became due within lookback of now. Reminders already queued for
+// the same occurrence, due time and channel are left alone, so calling this repeatedly is safe.
+// Returns the number of deliveries queued.
+func (db *PostgresDB) EnqueueDueReminders(ctx context.Context, channels []string, now time.Time, lookback time.Duration) (int64, error) {
+	if len(channels) == 0 {
+		return 0, nil
+	}
+
+	query := `INSERT INTO reminder_deliveries (schedule_id, channel, occurrence_at, due_at)
+			  SELECT s.id, ch.channel, s.occurrence_at, s.next_due_at
+			  FROM care_schedules s
+			  JOIN users u ON u.id = s.user_id
+			  CROSS JOIN unnest($1::text[]) AS ch(channel)
+			  WHERE s.next_due_at <= $2 AND s.next_due_at > $3
+				AND u.deletion_scheduled_at IS NULL -- accounts about to be erased get no reminders
+			  ON CONFLICT ON CONSTRAINT reminder_deliveries_dedup DO NOTHING`
+
+	tag, err := db.Pool.Exec(ctx, query, channels, now, now.Add(-lookback))
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to enqueue reminders: %v", err)
+		return 0, fmt.Errorf("failed to enqueue reminders: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimReminders leases up to limit pending deliveries whose next attempt is due.
+// Each claimed delivery counts as an attempt and is hidden from other claims until
+// the lease expires, so a delivery interrupted by a crash is retried later.
+func (db *PostgresDB) ClaimReminders(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.Reminder, error) {
+	query := `WITH claimed AS (
+				  UPDATE reminder_deliveries
+				  SET attempts = attempts + 1, next_attempt_at = $2, updated_at = NOW()
+				  WHERE id IN (
+					  SELECT id FROM reminder_deliveries
+					  WHERE status = 'pending' AND next_attempt_at <= $1
+					  ORDER BY next_attempt_at
+					  LIMIT $3
+					  FOR UPDATE SKIP LOCKED
+				  )
+				  RETURNING id, schedule_id, channel, occurrence_at, due_at, attempts
+			  )
+			  SELECT c.id, c.channel, c.attempts, c.schedule_id, c.occurrence_at, c.due_at,
+					 s.user_id, s.plant_id, p.name, s.task_type, s.next_due_at = c.due_at
+			  FROM claimed c
+			  JOIN care_schedules s ON s.id = c.schedule_id
+			  JOIN plants p ON p.id = s.plant_id
+			  ORDER BY c.due_at`
+
+	rows, err := db.Pool.Query(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to claim reminders: %v", err)
+		return nil, fmt.Errorf("failed to claim reminders: %w", err)
+	}
+	defer rows.Close()
+
+	reminders := []*model.Reminder{}
+	for rows.Next() {
+		var reminder model.Reminder
+		err := rows.Scan(
+			&reminder.DeliveryID,
+			&reminder.Channel,
+			&reminder.Attempts,
+			&reminder.ScheduleID,
+			&reminder.OccurrenceAt,
+			&reminder.DueAt,
+			&reminder.UserID,
+			&reminder.PlantID,
+			&reminder.PlantName,
+			&reminder.TaskType,
+			&reminder.Current,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		reminders = append(reminders, &reminder)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim reminders: %w", err)
+	}
+
+	return reminders, nil
+}
+
+// MarkReminderSent records a successful delivery
+func (db *PostgresDB) MarkReminderSent(ctx context.Context, deliveryID uuid.UUID) error {
+	query := `UPDATE reminder_deliveries
+			  SET status = 'sent', sent_at = NOW(), last_error = NULL, updated_at = NOW()
+			  WHERE id = $1`
+
+	if _, err := db.Pool.Exec(ctx, query, deliveryID); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to mark reminder %s as sent: %v", deliveryID, err)
+		return fmt.Errorf("failed to mark reminder as sent: %w", err)
+	}
+	return nil
+}
+
+// MarkReminderCancelled stops a delivery that is no longer relevant, for example
+// because the task was completed before the reminder went out
+func (db *PostgresDB) MarkReminderCancelled(ctx context.Context, deliveryID uuid.UUID) error {
+	query := `UPDATE reminder_deliveries SET status = 'cancelled', updated_at = NOW() WHERE id = $1`
+
+	if _, err := db.Pool.Exec(ctx, query, deliveryID); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to cancel reminder %s: %v", deliveryID, err)
+		return fmt.Errorf("failed to cancel reminder: %w", err)
+	}
+	return nil
+}
+
+// MarkReminderFailed records a failed attempt. The delivery is retried at
+// retryAt, or given up on when retryAt is nil.
+func (db *PostgresDB) MarkReminderFailed(ctx context.Context, deliveryID uuid.UUID, reason string, retryAt *time.Time) error {
+	query := `UPDATE reminder_deliveries
+			  SET status = 'failed', last_error = $2, updated_at = NOW()
+			  WHERE id = $1`
+	args := []any{deliveryID, reason}
+	if retryAt != nil {
+		query = `UPDATE reminder_deliveries
+				 SET last_error = $2, next_attempt_at = $3, updated_at = NOW()
+				 WHERE id = $1`
+		args = append(args, *retryAt)
+	}
+
+	if _, err := db.Pool.Exec(ctx, query, args...); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to record reminder %s failure: %v", deliveryID, err)
+		return fmt.Errorf("failed to record reminder failure: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const careScheduleColumns = `id, plant_id, user_id, task_type, rrule, anchor_local, notes, next_due_at, occurrence_at, created_at, updated_at`
+
+// scanCareSchedule scans a single care schedule row in careScheduleColumns order
+func scanCareSchedule(row rowScanner) (*model.CareSchedule, error) {
+	var schedule model.CareSchedule
+	var anchor time.Time
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.PlantID,
+		&schedule.UserID,
+		&schedule.TaskType,
+		&schedule.RRule,
+		&anchor,
+		&schedule.Notes,
+		&schedule.NextDueAt,
+		&schedule.OccurrenceAt,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	schedule.Anchor = model.LocalTime(anchor)
+	return &schedule, nil
+}
+
+// collectCareSchedules scans all rows into a slice of care schedules
+func collectCareSchedules(rows pgx.Rows) ([]*model.CareSchedule, error) {
+	defer rows.Close()
+
+	schedules := []*model.CareSchedule{}
+	for rows.Next() {
+		schedule, err := scanCareSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan care schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// CreateCareSchedule inserts a new care schedule and fills in the server generated timestamps
+func (db *PostgresDB) CreateCareSchedule(ctx context.Context, schedule *model.CareSchedule) error {
+	query := `INSERT INTO care_schedules (id, plant_id, user_id, task_type, rrule, anchor_local, notes, next_due_at, occurrence_at, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+			  RETURNING created_at, updated_at`
+
+	err := db.Pool.QueryRow(ctx, query,
+		schedule.ID,
+		schedule.PlantID,
+		schedule.UserID,
+		schedule.TaskType,
+		schedule.RRule,
+		schedule.Anchor.Time(),
+		schedule.Notes,
+		schedule.NextDueAt,
+		schedule.OccurrenceAt,
+	).Scan(&schedule.CreatedAt, &schedule.UpdatedAt)
+
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create care schedule for plant %s: %v", schedule.PlantID, err)
+		return fmt.Errorf("failed to create care schedule: %w", err)
+	}
+
+	return nil
+}
+
+// GetCareSchedule fetches a care schedule of a plant, scoped to its owner. Returns nil if not found.
+func (db *PostgresDB) GetCareSchedule(ctx context.Context, userID, plantID, scheduleID uuid.UUID) (*model.CareSchedule, error) {
+	query := `SELECT ` + careScheduleColumns + ` FROM care_schedules WHERE id = $1 AND plant_id = $2 AND user_id = $3`
+
+	schedule, err := scanCareSchedule(db.Pool.QueryRow(ctx, query, scheduleID, plantID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to get care schedule %s: %v", scheduleID, err)
+		return nil, fmt.Errorf("failed to get care schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+// ListCareSchedulesByPlant returns all care schedules of a plant, soonest due first
+func (db *PostgresDB) ListCareSchedulesByPlant(ctx context.Context, userID, plantID uuid.UUID) ([]*model.CareSchedule, error) {
+	query := `SELECT ` + careScheduleColumns + ` FROM care_schedules
+			  WHERE plant_id = $1 AND user_id = $2
+			  ORDER BY next_due_at, id`
+
+	rows, err := db.Pool.Query(ctx, query, plantID, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list care schedules for plant %s: %v", plantID, err)
+		return nil, fmt.Errorf("failed to list care schedules: %w", err)
+	}
+
+	schedules, err := collectCareSchedules(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list care schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// ListCareSchedulesDueBefore returns the user's care schedules whose next occurrence is at or before until
+func (db *PostgresDB) ListCareSchedulesDueBefore(ctx context.Context, userID uuid.UUID, until time.Time) ([]*model.CareSchedule, error) {
+	query := `SELECT ` + careScheduleColumns + ` FROM care_schedules
+			  WHERE user_id = $1 AND next_due_at <= $2
+			  ORDER BY next_due_at, id`
+
+	rows, err := db.Pool.Query(ctx, query, userID, until)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list due care schedules for user %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to list due care schedules: %w", err)
+	}
+
+	schedules, err := collectCareSchedules(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due care schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// UpdateCareSchedule saves the editable fields of a care schedule. The pending
+// occurrence is only written when resetState is set, otherwise the stored one is
+// kept so a task action applied in the meantime is not reverted, and loaded into
+// schedule. Returns false if the schedule does not exist for the given owner.
+func (db *PostgresDB) UpdateCareSchedule(ctx context.Context, schedule *model.CareSchedule, resetState bool) (bool, error) {
+	query := `UPDATE care_schedules
+			  SET task_type = $3, rrule = $4, anchor_local = $5, notes = $6,
+			      next_due_at = CASE WHEN $9::boolean THEN $7 ELSE next_due_at END,
+			      occurrence_at = CASE WHEN $9::boolean THEN $8 ELSE occurrence_at END,
+			      updated_at = NOW()
+			  WHERE id = $1 AND user_id = $2
+			  RETURNING next_due_at, occurrence_at, updated_at`
+
+	err := db.Pool.QueryRow(ctx, query,
+		schedule.ID,
+		schedule.UserID,
+		schedule.TaskType,
+		schedule.RRule,
+		schedule.Anchor.Time(),
+		schedule.Notes,
+		schedule.NextDueAt,
+		schedule.OccurrenceAt,
+		resetState,
+	).Scan(&schedule.NextDueAt, &schedule.OccurrenceAt, &schedule.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to update care schedule %s: %v", schedule.ID, err)
+		return false, fmt.Errorf("failed to update care schedule: %w", err)
+	}
+
+	return true, nil
+}
+
+// DeleteCareSchedule removes a care schedule of a plant. Returns false if nothing was deleted.
+func (db *PostgresDB) DeleteCareSchedule(ctx context.Context, userID, plantID, scheduleID uuid.UUID) (bool, error) {
+	query := `DELETE FROM care_schedules WHERE id = $1 AND plant_id = $2 AND user_id = $3`
+
+	tag, err := db.Pool.Exec(ctx, query, scheduleID, plantID, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to delete care schedule %s: %v", scheduleID, err)
+		return false, fmt.Errorf("failed to delete care schedule: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
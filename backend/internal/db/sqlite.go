@@ -0,0 +1,241 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteTimeLayout is how timestamps are stored in SQLite. Times are always
+// written in UTC with a fixed number of digits, so comparing the text compares
+// the times. The microsecond precision matches PostgreSQL.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+// sqlitePragmas are applied to every connection. Transactions take the write
+// lock when they begin so concurrent writers wait for each other instead of
+// failing halfway.
+const sqlitePragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+// SQLiteDB implements Store on an embedded SQLite database file, for single
+// user instances that do not want to run PostgreSQL
+type SQLiteDB struct {
+	DB     *sql.DB
+	logger *logger.ServiceLogger
+}
+
+// sqlQuerier is implemented by both the database and transactions
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SQLitePath returns the database file named by a sqlite:// DSN
+func SQLitePath(dsn string) string {
+	_, path, _ := strings.Cut(dsn, ":")
+	path = strings.TrimPrefix(path, "//")
+	path, _, _ = strings.Cut(path, "?")
+	return path
+}
+
+// NewSQLiteDB opens the SQLite database named by a sqlite:// DSN, creating
+// the file and its directory if needed, and pings it
+func NewSQLiteDB(ctx context.Context, cfg Config, logger *logger.ServiceLogger) (*SQLiteDB, error) {
+	path := SQLitePath(cfg.DSN)
+	if path == "" {
+		return nil, errors.New("sqlite DSN has no database path")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", path+"?"+sqlitePragmas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite has a single writer. One connection serializes statements in the
+	// process instead of having them wait on the file lock.
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	logger.InfofContext(ctx, "SQLite database opened: %s", path)
+
+	return &SQLiteDB{
+		DB:     db,
+		logger: logger,
+	}, nil
+}
+
+// Close closes the database file
+func (db *SQLiteDB) Close() {
+	if db.DB != nil {
+		db.logger.Info("Closing SQLite database")
+		db.DB.Close()
+	}
+}
+
+// Ping checks that the database file can be read
+func (db *SQLiteDB) Ping(ctx context.Context) error {
+	return db.DB.PingContext(ctx)
+}
+
+// exec runs a statement on q with its time arguments converted to sqliteTimeLayout
+func (db *SQLiteDB) exec(ctx context.Context, q sqlQuerier, query string, args ...any) (sql.Result, error) {
+	return q.ExecContext(ctx, query, sqliteArgs(args)...)
+}
+
+// query runs a query on q with its time arguments converted to sqliteTimeLayout
+func (db *SQLiteDB) query(ctx context.Context, q sqlQuerier, query string, args ...any) (*sql.Rows, error) {
+	return q.QueryContext(ctx, query, sqliteArgs(args)...)
+}
+
+// queryRow runs a single row query on q with its time arguments converted to sqliteTimeLayout
+func (db *SQLiteDB) queryRow(ctx context.Context, q sqlQuerier, query string, args ...any) *sql.Row {
+	return q.QueryRowContext(ctx, query, sqliteArgs(args)...)
+}
+
+// sqliteArgs formats time arguments with sqliteTimeLayout. The driver would
+// otherwise store them in their own zone with a varying number of digits.
+func sqliteArgs(args []any) []any {
+	converted := make([]any, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			converted[i] = sqliteTime(v)
+		case *time.Time:
+			if v == nil {
+				converted[i] = nil
+			} else {
+				converted[i] = sqliteTime(*v)
+			}
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
+
+// sqliteTime formats t as stored in SQLite
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// sqliteNow returns the current time at the precision it is stored with
+func sqliteNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// rowsAffected returns the number of rows changed by a statement
+func rowsAffected(result sql.Result) int64 {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation on the given table and column
+func isUniqueViolation(err error, column string) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return false
+	}
+	return strings.Contains(sqliteErr.Error(), column)
+}
+
+// CheckIfEmailExists returns true if a user record with the given email exists
+func (db *SQLiteDB) CheckIfEmailExists(ctx context.Context, email string) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)"
+	err := db.queryRow(ctx, db.DB, query, email).Scan(&exists)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to check email existence for %s: %v", email, err)
+		return false, fmt.Errorf("failed to check email existence: %w", err)
+	}
+	db.logger.DebugfContext(ctx, "Email existence check for %s: %v", email, exists)
+	return exists, nil
+}
+
+// CreateUser inserts a new user and returns the user ID. Users without a role
+// are created with UserRoleUser.
+func (db *SQLiteDB) CreateUser(ctx context.Context, user *model.User) (uuid.UUID, error) {
+	query := `INSERT INTO users (id, avatar_url, auth_provider, email, full_name, password_hash, timezone, role, email_verified_at, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+			  RETURNING id`
+
+	if user.Role == "" {
+		user.Role = model.UserRoleUser
+	}
+
+	var userID uuid.UUID
+	err := db.queryRow(ctx, db.DB, query,
+		user.ID,
+		user.AvatarURL,
+		user.AuthProvider,
+		user.Email,
+		user.FullName,
+		user.PasswordHash,
+		user.Timezone,
+		user.Role,
+		user.EmailVerifiedAt,
+		sqliteNow(),
+	).Scan(&userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create user %s: %v", user.Email, err)
+		return uuid.Nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "User created successfully: %s", user.Email)
+	return userID, nil
+}
+
+// GetUserByEmail fetches a user by email. Returns nil if not found.
+func (db *SQLiteDB) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+
+	user, err := scanUser(db.queryRow(ctx, db.DB, query, email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			db.logger.DebugfContext(ctx, "User not found: %s", email)
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to get user by email %s: %v", email, err)
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	db.logger.DebugfContext(ctx, "User retrieved successfully: %s", email)
+	return user, nil
+}
+
+// GetUserByID fetches a user by ID. Returns nil if not found.
+func (db *SQLiteDB) GetUserByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+
+	user, err := scanUser(db.queryRow(ctx, db.DB, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			db.logger.DebugfContext(ctx, "User not found: %s", id)
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to get user by id %s: %v", id, err)
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// scanSQLiteAPIKey scans a single API key row in apiKeyColumns order. The
+// scopes are stored as a JSON array.
+func scanSQLiteAPIKey(row rowScanner) (*model.APIKey, error) {
+	var key model.APIKey
+	var scopes string
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&scopes,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = []model.APIKeyScope{}
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, fmt.Errorf("invalid scopes on API key %s: %w", key.ID, err)
+	}
+	return &key, nil
+}
+
+// CreateAPIKey stores a new API key, filling in created_at
+func (db *SQLiteDB) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []model.APIKeyScope{}
+	}
+	encoded, err := json.Marshal(scopes)
+	if err != nil {
+		return fmt.Errorf("failed to encode API key scopes: %w", err)
+	}
+
+	now := sqliteNow()
+	query := `INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = db.exec(ctx, db.DB, query,
+		key.ID,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		string(encoded),
+		now,
+	)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create API key for user %s: %v", key.UserID, err)
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	key.CreatedAt = now
+
+	db.logger.InfofContext(ctx, "API key %s created for user %s", key.ID, key.UserID)
+	return nil
+}
+
+// ListAPIKeysByUser returns the API keys of a user, newest first
+func (db *SQLiteDB) ListAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]*model.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC, id`
+
+	rows, err := db.query(ctx, db.DB, query, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list API keys for user %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*model.APIKey{}
+	for rows.Next() {
+		key, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// CountAPIKeysByUser returns how many API keys a user has
+func (db *SQLiteDB) CountAPIKeysByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := db.queryRow(ctx, db.DB, `SELECT COUNT(*) FROM api_keys WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+	return count, nil
+}
+
+// GetAPIKeyByHash fetches the API key with the given hash. Keys of disabled
+// accounts are treated as unknown. Returns nil if not found.
+func (db *SQLiteDB) GetAPIKeyByHash(ctx context.Context, hash []byte) (*model.APIKey, error) {
+	query := `SELECT ` + prefixColumns("k", apiKeyColumns) + ` FROM api_keys k
+			  JOIN users u ON u.id = k.user_id
+			  WHERE k.key_hash = $1 AND u.disabled_at IS NULL`
+
+	key, err := scanSQLiteAPIKey(db.queryRow(ctx, db.DB, query, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to get API key: %v", err)
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return key, nil
+}
+
+// TouchAPIKey records that a key was used at usedAt. To avoid a write on every
+// request the timestamp is only updated once per minute.
+func (db *SQLiteDB) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $2
+			  WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)`
+
+	if _, err := db.exec(ctx, db.DB, query, id, usedAt, usedAt.Add(-time.Minute)); err != nil {
+		return fmt.Errorf("failed to update API key usage: %w", err)
+	}
+	return nil
+}
+
+// DeleteAPIKey revokes an API key of a user. Returns false if nothing was deleted.
+func (db *SQLiteDB) DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result, err := db.exec(ctx, db.DB, `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to delete API key %s: %v", id, err)
+		return false, fmt.Errorf("failed to delete API key: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return false, nil
+	}
+
+	db.logger.InfofContext(ctx, "API key %s revoked for user %s", id, userID)
+	return true, nil
+}
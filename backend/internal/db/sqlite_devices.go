@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// UpsertDevice registers a push token for a user. A token that is already
+// registered, possibly by another user on the same install, is moved to this user.
+func (db *SQLiteDB) UpsertDevice(ctx context.Context, device *model.Device) error {
+	query := `INSERT INTO devices (id, user_id, token, platform, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $5)
+			  ON CONFLICT (token) DO UPDATE
+			  SET user_id = excluded.user_id, platform = excluded.platform, updated_at = excluded.updated_at
+			  RETURNING id, created_at, updated_at`
+
+	err := db.queryRow(ctx, db.DB, query,
+		device.ID,
+		device.UserID,
+		device.Token,
+		device.Platform,
+		sqliteNow(),
+	).Scan(&device.ID, &device.CreatedAt, &device.UpdatedAt)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to register device for user %s: %v", device.UserID, err)
+		return fmt.Errorf("failed to register device: %w", err)
+	}
+
+	db.logger.DebugfContext(ctx, "Device %s registered for user %s", device.ID, device.UserID)
+	return nil
+}
+
+// ListDevicesByUser returns the push tokens registered by a user
+func (db *SQLiteDB) ListDevicesByUser(ctx context.Context, userID uuid.UUID) ([]*model.Device, error) {
+	query := `SELECT id, user_id, token, platform, created_at, updated_at
+			  FROM devices WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := db.query(ctx, db.DB, query, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list devices for user %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []*model.Device{}
+	for rows.Next() {
+		var device model.Device
+		err := rows.Scan(
+			&device.ID,
+			&device.UserID,
+			&device.Token,
+			&device.Platform,
+			&device.CreatedAt,
+			&device.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+		devices = append(devices, &device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	return devices, nil
+}
+
+// DeleteDevice unregisters a push token of a user.
+// Returns false if nothing was deleted.
+func (db *SQLiteDB) DeleteDevice(ctx context.Context, userID uuid.UUID, token string) (bool, error) {
+	result, err := db.exec(ctx, db.DB, `DELETE FROM devices WHERE token = $1 AND user_id = $2`, token, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to delete device for user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to delete device: %w", err)
+	}
+	return rowsAffected(result) > 0, nil
+}
+
+// DeleteDeviceByToken removes a push token the push provider reported as invalid
+func (db *SQLiteDB) DeleteDeviceByToken(ctx context.Context, token string) error {
+	if _, err := db.exec(ctx, db.DB, `DELETE FROM devices WHERE token = $1`, token); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to prune device token: %v", err)
+		return fmt.Errorf("failed to prune device: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateEmailVerificationToken stores the hash of a token confirming email for
+// a user, replacing any token sent to the user before
+func (db *SQLiteDB) CreateEmailVerificationToken(ctx context.Context, userID uuid.UUID, email string, tokenHash []byte, expiresAt time.Time) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := db.exec(ctx, tx, `DELETE FROM email_verification_tokens WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete previous verification tokens: %w", err)
+	}
+
+	query := `INSERT INTO email_verification_tokens (id, user_id, email, token_hash, expires_at, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := db.exec(ctx, tx, query, uuid.New(), userID, email, tokenHash, expiresAt, sqliteNow()); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create verification token for user %s: %v", userID, err)
+		return fmt.Errorf("failed to create verification token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit verification token: %w", err)
+	}
+	return nil
+}
+
+// VerifyEmail consumes a verification token and marks the address it was sent
+// to as verified. If the token was sent for an email change the address becomes
+// the user's email. Returns false if the token is unknown or expired.
+func (db *SQLiteDB) VerifyEmail(ctx context.Context, tokenHash []byte) (bool, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := sqliteNow()
+	var userID uuid.UUID
+	var email string
+	err = db.queryRow(ctx, tx, `DELETE FROM email_verification_tokens
+			  WHERE token_hash = $1 AND expires_at > $2
+			  RETURNING user_id, email`, tokenHash, now).Scan(&userID, &email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to consume verification token: %w", err)
+	}
+
+	// Creating a token replaces older ones, so this is the latest address the user asked for
+	query := `UPDATE users SET email = $2, email_verified_at = $3, updated_at = $3
+			  WHERE id = $1`
+	result, err := db.exec(ctx, tx, query, userID, email, now)
+	if err != nil {
+		if isUniqueViolation(err, "users.email") {
+			return false, ErrEmailInUse
+		}
+		db.logger.DebugfContext(ctx, "Failed to verify email of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to verify email: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit email verification: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "Email verified for user %s", userID)
+	return true, nil
+}
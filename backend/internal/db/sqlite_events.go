@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// insertCareEvent appends a care event using q, filling in created_at
+func (db *SQLiteDB) insertCareEvent(ctx context.Context, q sqlQuerier, event *model.CareEvent) error {
+	query := `INSERT INTO care_events (id, plant_id, user_id, type, occurred_at, payload, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	payload := event.Payload
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+
+	now := sqliteNow()
+	_, err := db.exec(ctx, q, query,
+		event.ID,
+		event.PlantID,
+		event.UserID,
+		event.Type,
+		event.OccurredAt,
+		string(payload),
+		now,
+	)
+	if err != nil {
+		return err
+	}
+	event.CreatedAt = now
+	return nil
+}
+
+// CreateCareEvent appends an event to a plant's timeline
+func (db *SQLiteDB) CreateCareEvent(ctx context.Context, event *model.CareEvent) error {
+	if err := db.insertCareEvent(ctx, db.DB, event); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create care event for plant %s: %v", event.PlantID, err)
+		return fmt.Errorf("failed to create care event: %w", err)
+	}
+
+	db.logger.DebugfContext(ctx, "Care event %s (%s) created for plant %s", event.ID, event.Type, event.PlantID)
+	return nil
+}
+
+// ListCareEvents returns a page of a plant's timeline, newest first, scoped to the plant owner
+func (db *SQLiteDB) ListCareEvents(ctx context.Context, userID, plantID uuid.UUID, filter model.CareEventFilter) ([]*model.CareEvent, error) {
+	var query strings.Builder
+	query.WriteString(`SELECT e.id, e.plant_id, e.user_id, e.type, e.occurred_at, e.payload, e.created_at
+			  FROM care_events e
+			  JOIN plants p ON p.id = e.plant_id
+			  WHERE e.plant_id = $1 AND p.user_id = $2`)
+	args := []any{plantID, userID}
+
+	if len(filter.Types) > 0 {
+		types, err := json.Marshal(filter.Types)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode event types: %w", err)
+		}
+		args = append(args, string(types))
+		fmt.Fprintf(&query, " AND e.type IN (SELECT value FROM json_each($%d))", len(args))
+	}
+
+	if filter.BeforeID != uuid.Nil {
+		args = append(args, filter.BeforeOccurredAt, filter.BeforeID)
+		fmt.Fprintf(&query, " AND (e.occurred_at, e.id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	args = append(args, filter.Limit)
+	fmt.Fprintf(&query, " ORDER BY e.occurred_at DESC, e.id DESC LIMIT $%d", len(args))
+
+	rows, err := db.query(ctx, db.DB, query.String(), args...)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list care events for plant %s: %v", plantID, err)
+		return nil, fmt.Errorf("failed to list care events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*model.CareEvent{}
+	for rows.Next() {
+		var event model.CareEvent
+		var payload []byte
+		err := rows.Scan(
+			&event.ID,
+			&event.PlantID,
+			&event.UserID,
+			&event.Type,
+			&event.OccurredAt,
+			&payload,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan care event: %w", err)
+		}
+		event.Payload = payload
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list care events: %w", err)
+	}
+
+	return events, nil
+}
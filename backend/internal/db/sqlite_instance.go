@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anish-chanda/ferna/model"
+)
+
+// GetInstanceSettings returns the runtime settings of the instance
+func (db *SQLiteDB) GetInstanceSettings(ctx context.Context) (*model.InstanceSettings, error) {
+	query := `SELECT registration_enabled, updated_at FROM instance_settings`
+
+	var settings model.InstanceSettings
+	if err := db.queryRow(ctx, db.DB, query).Scan(&settings.RegistrationEnabled, &settings.UpdatedAt); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to get instance settings: %v", err)
+		return nil, fmt.Errorf("failed to get instance settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SetRegistrationEnabled opens or closes sign up and returns the updated settings
+func (db *SQLiteDB) SetRegistrationEnabled(ctx context.Context, enabled bool) (*model.InstanceSettings, error) {
+	query := `UPDATE instance_settings SET registration_enabled = $1, updated_at = $2`
+
+	now := sqliteNow()
+	if _, err := db.exec(ctx, db.DB, query, enabled, now); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to update registration setting: %v", err)
+		return nil, fmt.Errorf("failed to update registration setting: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "Registration enabled set to %t", enabled)
+	return &model.InstanceSettings{RegistrationEnabled: enabled, UpdatedAt: &now}, nil
+}
+
+// GetInstanceStats counts the accounts and data stored on the instance
+func (db *SQLiteDB) GetInstanceStats(ctx context.Context) (*model.InstanceStats, error) {
+	stats, err := scanInstanceStats(db.queryRow(ctx, db.DB, instanceStatsQuery))
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to get instance stats: %v", err)
+		return nil, fmt.Errorf("failed to get instance stats: %w", err)
+	}
+	return stats, nil
+}
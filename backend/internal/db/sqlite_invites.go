@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// CreateInviteCode stores a new invite code, filling in created_at
+func (db *SQLiteDB) CreateInviteCode(ctx context.Context, invite *model.InviteCode) error {
+	now := sqliteNow()
+	query := `INSERT INTO invite_codes (id, prefix, code_hash, note, max_uses, expires_at, created_by, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := db.exec(ctx, db.DB, query,
+		invite.ID,
+		invite.Prefix,
+		invite.CodeHash,
+		invite.Note,
+		invite.MaxUses,
+		invite.ExpiresAt,
+		invite.CreatedBy,
+		now,
+	)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create invite code: %v", err)
+		return fmt.Errorf("failed to create invite code: %w", err)
+	}
+	invite.CreatedAt = now
+
+	db.logger.InfofContext(ctx, "Invite code %s created", invite.ID)
+	return nil
+}
+
+// ListInviteCodes returns all invite codes, newest first
+func (db *SQLiteDB) ListInviteCodes(ctx context.Context) ([]*model.InviteCode, error) {
+	query := `SELECT ` + inviteCodeColumns + ` FROM invite_codes ORDER BY created_at DESC, id`
+
+	rows, err := db.query(ctx, db.DB, query)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list invite codes: %v", err)
+		return nil, fmt.Errorf("failed to list invite codes: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []*model.InviteCode{}
+	for rows.Next() {
+		invite, err := scanInviteCode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invite code: %w", err)
+		}
+		invites = append(invites, invite)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list invite codes: %w", err)
+	}
+
+	return invites, nil
+}
+
+// DeleteInviteCode revokes an invite code. Returns false if nothing was deleted.
+func (db *SQLiteDB) DeleteInviteCode(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := db.exec(ctx, db.DB, `DELETE FROM invite_codes WHERE id = $1`, id)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to delete invite code %s: %v", id, err)
+		return false, fmt.Errorf("failed to delete invite code: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return false, nil
+	}
+
+	db.logger.InfofContext(ctx, "Invite code %s revoked", id)
+	return true, nil
+}
+
+// RedeemInviteCode uses up one use of the invite code with the given hash if
+// it has not expired at now and has uses left. Returns nil if the code cannot
+// be redeemed.
+func (db *SQLiteDB) RedeemInviteCode(ctx context.Context, hash []byte, now time.Time) (*model.InviteCode, error) {
+	query := `UPDATE invite_codes SET uses = uses + 1
+			  WHERE code_hash = $1
+			  AND (expires_at IS NULL OR expires_at > $2)
+			  AND (max_uses IS NULL OR uses < max_uses)
+			  RETURNING ` + inviteCodeColumns
+
+	invite, err := scanInviteCode(db.queryRow(ctx, db.DB, query, hash, now))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to redeem invite code: %v", err)
+		return nil, fmt.Errorf("failed to redeem invite code: %w", err)
+	}
+	return invite, nil
+}
+
+// ReleaseInviteCode gives back a use of an invite code whose account could not be created
+func (db *SQLiteDB) ReleaseInviteCode(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE invite_codes SET uses = uses - 1 WHERE id = $1 AND uses > 0`
+
+	if _, err := db.exec(ctx, db.DB, query, id); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to release invite code %s: %v", id, err)
+		return fmt.Errorf("failed to release invite code: %w", err)
+	}
+	return nil
+}
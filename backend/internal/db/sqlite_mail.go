@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// EnqueueMail stores a rendered message in the outbox for the mail worker to send
+func (db *SQLiteDB) EnqueueMail(ctx context.Context, mail *model.OutboundMail) error {
+	query := `INSERT INTO mail_outbox (id, to_address, subject, text_body, html_body, next_attempt_at, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $6, $6)`
+
+	now := sqliteNow()
+	_, err := db.exec(ctx, db.DB, query,
+		mail.ID,
+		mail.To,
+		mail.Subject,
+		mail.TextBody,
+		mail.HTMLBody,
+		now,
+	)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to enqueue mail %s: %v", mail.ID, err)
+		return fmt.Errorf("failed to enqueue mail: %w", err)
+	}
+	mail.CreatedAt = now
+
+	db.logger.DebugfContext(ctx, "Mail %s queued", mail.ID)
+	return nil
+}
+
+// ClaimMail leases up to limit pending messages whose next attempt is due.
+// A message interrupted by a crash becomes pending again when the lease expires.
+func (db *SQLiteDB) ClaimMail(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.OutboundMail, error) {
+	query := `UPDATE mail_outbox
+			  SET attempts = attempts + 1, next_attempt_at = $2, updated_at = $4
+			  WHERE id IN (
+				  SELECT id FROM mail_outbox
+				  WHERE status = 'pending' AND next_attempt_at <= $1
+				  ORDER BY next_attempt_at
+				  LIMIT $3
+			  )
+			  RETURNING id, to_address, subject, text_body, html_body, attempts, created_at`
+
+	rows, err := db.query(ctx, db.DB, query, now, now.Add(lease), limit, sqliteNow())
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to claim mail: %v", err)
+		return nil, fmt.Errorf("failed to claim mail: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*model.OutboundMail{}
+	for rows.Next() {
+		var mail model.OutboundMail
+		err := rows.Scan(
+			&mail.ID,
+			&mail.To,
+			&mail.Subject,
+			&mail.TextBody,
+			&mail.HTMLBody,
+			&mail.Attempts,
+			&mail.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan mail: %w", err)
+		}
+		messages = append(messages, &mail)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim mail: %w", err)
+	}
+
+	return messages, nil
+}
+
+// MarkMailSent records a successfully sent message
+func (db *SQLiteDB) MarkMailSent(ctx context.Context, mailID uuid.UUID) error {
+	query := `UPDATE mail_outbox
+			  SET status = 'sent', sent_at = $2, last_error = NULL, updated_at = $2
+			  WHERE id = $1`
+
+	if _, err := db.exec(ctx, db.DB, query, mailID, sqliteNow()); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to mark mail %s as sent: %v", mailID, err)
+		return fmt.Errorf("failed to mark mail as sent: %w", err)
+	}
+	return nil
+}
+
+// MarkMailFailed records a failed attempt. The message is retried at retryAt,
+// or given up on when retryAt is nil.
+func (db *SQLiteDB) MarkMailFailed(ctx context.Context, mailID uuid.UUID, reason string, retryAt *time.Time) error {
+	query := `UPDATE mail_outbox
+			  SET status = 'failed', last_error = $2, updated_at = $3
+			  WHERE id = $1`
+	args := []any{mailID, reason, sqliteNow()}
+	if retryAt != nil {
+		query = `UPDATE mail_outbox
+				 SET last_error = $2, next_attempt_at = $4, updated_at = $3
+				 WHERE id = $1`
+		args = append(args, *retryAt)
+	}
+
+	if _, err := db.exec(ctx, db.DB, query, args...); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to record mail %s failure: %v", mailID, err)
+		return fmt.Errorf("failed to record mail failure: %w", err)
+	}
+	return nil
+}
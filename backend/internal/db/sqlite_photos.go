@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// CreatePhoto inserts a photo and its timeline event in a single transaction
+func (db *SQLiteDB) CreatePhoto(ctx context.Context, photo *model.Photo, event *model.CareEvent) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO plant_photos (id, plant_id, user_id, blob_key, thumbnail_key, content_type, width, height, size_bytes, caption, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	now := sqliteNow()
+	_, err = db.exec(ctx, tx, query,
+		photo.ID,
+		photo.PlantID,
+		photo.UserID,
+		photo.BlobKey,
+		photo.ThumbnailKey,
+		photo.ContentType,
+		photo.Width,
+		photo.Height,
+		photo.SizeBytes,
+		photo.Caption,
+		now,
+	)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create photo for plant %s: %v", photo.PlantID, err)
+		return fmt.Errorf("failed to create photo: %w", err)
+	}
+	photo.CreatedAt = now
+
+	if err := db.insertCareEvent(ctx, tx, event); err != nil {
+		return fmt.Errorf("failed to create photo event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit photo: %w", err)
+	}
+
+	db.logger.DebugfContext(ctx, "Photo %s created for plant %s", photo.ID, photo.PlantID)
+	return nil
+}
+
+// GetPhoto fetches a photo of a plant, scoped to its owner. Returns nil if not found.
+func (db *SQLiteDB) GetPhoto(ctx context.Context, userID, plantID, photoID uuid.UUID) (*model.Photo, error) {
+	query := `SELECT ` + photoColumns + ` FROM plant_photos WHERE id = $1 AND plant_id = $2 AND user_id = $3`
+
+	photo, err := scanPhoto(db.queryRow(ctx, db.DB, query, photoID, plantID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to get photo %s: %v", photoID, err)
+		return nil, fmt.Errorf("failed to get photo: %w", err)
+	}
+
+	return photo, nil
+}
+
+// ListPhotosByPlant returns the photos of a plant, newest first
+func (db *SQLiteDB) ListPhotosByPlant(ctx context.Context, userID, plantID uuid.UUID) ([]*model.Photo, error) {
+	query := `SELECT ` + photoColumns + ` FROM plant_photos
+			  WHERE plant_id = $1 AND user_id = $2
+			  ORDER BY created_at DESC, id DESC`
+
+	rows, err := db.query(ctx, db.DB, query, plantID, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list photos for plant %s: %v", plantID, err)
+		return nil, fmt.Errorf("failed to list photos: %w", err)
+	}
+	defer rows.Close()
+
+	photos := []*model.Photo{}
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan photo: %w", err)
+		}
+		photos = append(photos, photo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list photos: %w", err)
+	}
+
+	return photos, nil
+}
+
+// DeletePhoto removes a photo together with its timeline event.
+// Returns false if nothing was deleted.
+func (db *SQLiteDB) DeletePhoto(ctx context.Context, userID, plantID, photoID uuid.UUID) (bool, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := db.exec(ctx, tx, `DELETE FROM plant_photos WHERE id = $1 AND plant_id = $2 AND user_id = $3`, photoID, plantID, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to delete photo %s: %v", photoID, err)
+		return false, fmt.Errorf("failed to delete photo: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return false, nil
+	}
+
+	_, err = db.exec(ctx, tx, `DELETE FROM care_events WHERE plant_id = $1 AND type = 'photo' AND json_extract(payload, '$.photo_id') = $2`,
+		plantID, photoID.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to delete photo event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit photo deletion: %w", err)
+	}
+	return true, nil
+}
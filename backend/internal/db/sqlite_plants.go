@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// CreatePlant inserts a new plant and fills in the server generated timestamps
+func (db *SQLiteDB) CreatePlant(ctx context.Context, plant *model.Plant) error {
+	query := `INSERT INTO plants (id, user_id, name, species, location, notes, acquired_at, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)`
+
+	now := sqliteNow()
+	_, err := db.exec(ctx, db.DB, query,
+		plant.ID,
+		plant.UserID,
+		plant.Name,
+		plant.Species,
+		plant.Location,
+		plant.Notes,
+		plant.AcquiredAt,
+		now,
+	)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create plant for user %s: %v", plant.UserID, err)
+		return fmt.Errorf("failed to create plant: %w", err)
+	}
+	plant.CreatedAt = now
+	plant.UpdatedAt = now
+
+	db.logger.DebugfContext(ctx, "Plant created successfully: %s", plant.ID)
+	return nil
+}
+
+// GetPlant fetches a plant by ID, scoped to its owner. Returns nil if not found.
+func (db *SQLiteDB) GetPlant(ctx context.Context, userID, plantID uuid.UUID) (*model.Plant, error) {
+	query := `SELECT ` + plantColumns + ` FROM plants WHERE id = $1 AND user_id = $2`
+
+	plant, err := scanPlant(db.queryRow(ctx, db.DB, query, plantID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to get plant %s: %v", plantID, err)
+		return nil, fmt.Errorf("failed to get plant: %w", err)
+	}
+
+	return plant, nil
+}
+
+// ListPlantsByUser returns all plants owned by a user, oldest first
+func (db *SQLiteDB) ListPlantsByUser(ctx context.Context, userID uuid.UUID) ([]*model.Plant, error) {
+	query := `SELECT ` + plantColumns + ` FROM plants WHERE user_id = $1 ORDER BY created_at, id`
+
+	rows, err := db.query(ctx, db.DB, query, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list plants for user %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to list plants: %w", err)
+	}
+	defer rows.Close()
+
+	plants := []*model.Plant{}
+	for rows.Next() {
+		plant, err := scanPlant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan plant: %w", err)
+		}
+		plants = append(plants, plant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list plants: %w", err)
+	}
+
+	return plants, nil
+}
+
+// UpdatePlant saves the editable fields of a plant. Returns false if the plant
+// does not exist or is not owned by plant.UserID.
+func (db *SQLiteDB) UpdatePlant(ctx context.Context, plant *model.Plant) (bool, error) {
+	query := `UPDATE plants
+			  SET name = $3, species = $4, location = $5, notes = $6, acquired_at = $7, updated_at = $8
+			  WHERE id = $1 AND user_id = $2`
+
+	now := sqliteNow()
+	result, err := db.exec(ctx, db.DB, query,
+		plant.ID,
+		plant.UserID,
+		plant.Name,
+		plant.Species,
+		plant.Location,
+		plant.Notes,
+		plant.AcquiredAt,
+		now,
+	)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to update plant %s: %v", plant.ID, err)
+		return false, fmt.Errorf("failed to update plant: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return false, nil
+	}
+	plant.UpdatedAt = now
+
+	return true, nil
+}
+
+// DeletePlant removes a plant owned by the user. Returns false if nothing was deleted.
+func (db *SQLiteDB) DeletePlant(ctx context.Context, userID, plantID uuid.UUID) (bool, error) {
+	result, err := db.exec(ctx, db.DB, `DELETE FROM plants WHERE id = $1 AND user_id = $2`, plantID, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to delete plant %s: %v", plantID, err)
+		return false, fmt.Errorf("failed to delete plant: %w", err)
+	}
+
+	return rowsAffected(result) > 0, nil
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// EnqueueDueReminders queues a pending delivery on every channel for each care
+// schedule that became due within lookback of now. Reminders already queued for
+// the same occurrence, due time and channel are left alone, so calling this repeatedly is safe.
+// Returns the number of deliveries queued.
+func (db *SQLiteDB) EnqueueDueReminders(ctx context.Context, channels []string, now time.Time, lookback time.Duration) (int64, error) {
+	if len(channels) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT s.id, s.occurrence_at, s.next_due_at
+			  FROM care_schedules s
+			  JOIN users u ON u.id = s.user_id
+			  WHERE s.next_due_at <= $1 AND s.next_due_at > $2
+				AND u.deletion_scheduled_at IS NULL -- accounts about to be erased get no reminders`
+
+	rows, err := db.query(ctx, tx, query, now, now.Add(-lookback))
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to enqueue reminders: %v", err)
+		return 0, fmt.Errorf("failed to enqueue reminders: %w", err)
+	}
+	var due []*model.CareSchedule
+	for rows.Next() {
+		var schedule model.CareSchedule
+		if err := rows.Scan(&schedule.ID, &schedule.OccurrenceAt, &schedule.NextDueAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan due care schedule: %w", err)
+		}
+		due = append(due, &schedule)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to enqueue reminders: %w", err)
+	}
+
+	// SQLite cannot generate UUIDs, so deliveries are inserted one by one
+	insertQuery := `INSERT INTO reminder_deliveries (id, schedule_id, channel, occurrence_at, due_at, next_attempt_at, created_at, updated_at)
+					VALUES ($1, $2, $3, $4, $5, $6, $6, $6)
+					ON CONFLICT (schedule_id, occurrence_at, due_at, channel) DO NOTHING`
+
+	created := sqliteNow()
+	var queued int64
+	for _, schedule := range due {
+		for _, channel := range channels {
+			result, err := db.exec(ctx, tx, insertQuery, uuid.New(), schedule.ID, channel, schedule.OccurrenceAt, schedule.NextDueAt, created)
+			if err != nil {
+				db.logger.DebugfContext(ctx, "Failed to enqueue reminder for care schedule %s: %v", schedule.ID, err)
+				return 0, fmt.Errorf("failed to enqueue reminders: %w", err)
+			}
+			queued += rowsAffected(result)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit reminders: %w", err)
+	}
+	return queued, nil
+}
+
+// ClaimReminders leases up to limit pending deliveries whose next attempt is due.
+// Each claimed delivery counts as an attempt and is hidden from other claims until
+// the lease expires, so a delivery interrupted by a crash is retried later.
+func (db *SQLiteDB) ClaimReminders(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.Reminder, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	claimQuery := `UPDATE reminder_deliveries
+				   SET attempts = attempts + 1, next_attempt_at = $2, updated_at = $4
+				   WHERE id IN (
+					   SELECT id FROM reminder_deliveries
+					   WHERE status = 'pending' AND next_attempt_at <= $1
+					   ORDER BY next_attempt_at
+					   LIMIT $3
+				   )
+				   RETURNING id`
+
+	rows, err := db.query(ctx, tx, claimQuery, now, now.Add(lease), limit, sqliteNow())
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to claim reminders: %v", err)
+		return nil, fmt.Errorf("failed to claim reminders: %w", err)
+	}
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim reminders: %w", err)
+	}
+
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode claimed reminders: %w", err)
+	}
+
+	query := `SELECT d.id, d.channel, d.attempts, d.schedule_id, d.occurrence_at, d.due_at,
+					 s.user_id, s.plant_id, p.name, s.task_type, s.next_due_at = d.due_at
+			  FROM reminder_deliveries d
+			  JOIN care_schedules s ON s.id = d.schedule_id
+			  JOIN plants p ON p.id = s.plant_id
+			  WHERE d.id IN (SELECT value FROM json_each($1))
+			  ORDER BY d.due_at`
+
+	rows, err = db.query(ctx, tx, query, string(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load claimed reminders: %w", err)
+	}
+	defer rows.Close()
+
+	reminders := []*model.Reminder{}
+	for rows.Next() {
+		var reminder model.Reminder
+		err := rows.Scan(
+			&reminder.DeliveryID,
+			&reminder.Channel,
+			&reminder.Attempts,
+			&reminder.ScheduleID,
+			&reminder.OccurrenceAt,
+			&reminder.DueAt,
+			&reminder.UserID,
+			&reminder.PlantID,
+			&reminder.PlantName,
+			&reminder.TaskType,
+			&reminder.Current,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		reminders = append(reminders, &reminder)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim reminders: %w", err)
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit reminder claims: %w", err)
+	}
+	return reminders, nil
+}
+
+// MarkReminderSent records a successful delivery
+func (db *SQLiteDB) MarkReminderSent(ctx context.Context, deliveryID uuid.UUID) error {
+	query := `UPDATE reminder_deliveries
+			  SET status = 'sent', sent_at = $2, last_error = NULL, updated_at = $2
+			  WHERE id = $1`
+
+	if _, err := db.exec(ctx, db.DB, query, deliveryID, sqliteNow()); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to mark reminder %s as sent: %v", deliveryID, err)
+		return fmt.Errorf("failed to mark reminder as sent: %w", err)
+	}
+	return nil
+}
+
+// MarkReminderCancelled stops a delivery that is no longer relevant, for example
+// because the task was completed before the reminder went out
+func (db *SQLiteDB) MarkReminderCancelled(ctx context.Context, deliveryID uuid.UUID) error {
+	query := `UPDATE reminder_deliveries SET status = 'cancelled', updated_at = $2 WHERE id = $1`
+
+	if _, err := db.exec(ctx, db.DB, query, deliveryID, sqliteNow()); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to cancel reminder %s: %v", deliveryID, err)
+		return fmt.Errorf("failed to cancel reminder: %w", err)
+	}
+	return nil
+}
+
+// MarkReminderFailed records a failed attempt. The delivery is retried at
+// retryAt, or given up on when retryAt is nil.
+func (db *SQLiteDB) MarkReminderFailed(ctx context.Context, deliveryID uuid.UUID, reason string, retryAt *time.Time) error {
+	query := `UPDATE reminder_deliveries
+			  SET status = 'failed', last_error = $2, updated_at = $3
+			  WHERE id = $1`
+	args := []any{deliveryID, reason, sqliteNow()}
+	if retryAt != nil {
+		query = `UPDATE reminder_deliveries
+				 SET last_error = $2, next_attempt_at = $4, updated_at = $3
+				 WHERE id = $1`
+		args = append(args, *retryAt)
+	}
+
+	if _, err := db.exec(ctx, db.DB, query, args...); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to record reminder %s failure: %v", deliveryID, err)
+		return fmt.Errorf("failed to record reminder failure: %w", err)
+	}
+	return nil
+}
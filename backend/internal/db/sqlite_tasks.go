@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// ApplyCareTaskAction moves a care schedule to its new pending occurrence and
+// appends the action record in a single transaction. The update only applies if
+// the schedule is still at the occurrence and due time the record was computed
+// from, so concurrent actions on the same occurrence cannot both succeed.
+// If event is not nil it is appended to the plant's timeline in the same transaction.
+// Returns false if the schedule changed or is gone.
+func (db *SQLiteDB) ApplyCareTaskAction(ctx context.Context, schedule *model.CareSchedule, record *model.CareTaskRecord, event *model.CareEvent) (bool, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	updateQuery := `UPDATE care_schedules
+					SET anchor_local = $3, next_due_at = $4, occurrence_at = $5, updated_at = $8
+					WHERE id = $1 AND user_id = $2 AND occurrence_at = $6 AND next_due_at = $7`
+
+	now := sqliteNow()
+	result, err := db.exec(ctx, tx, updateQuery,
+		schedule.ID,
+		schedule.UserID,
+		schedule.Anchor.Time(),
+		schedule.NextDueAt,
+		schedule.OccurrenceAt,
+		record.OccurrenceAt,
+		record.PreviousDueAt,
+		now,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update care schedule: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		db.logger.DebugfContext(ctx, "Care schedule %s changed before %s could be applied", schedule.ID, record.Action)
+		return false, nil
+	}
+	schedule.UpdatedAt = now
+
+	insertQuery := `INSERT INTO care_task_records (id, schedule_id, user_id, action, occurrence_at, acted_at, previous_due_at, next_due_at, next_occurrence_at, created_at)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err = db.exec(ctx, tx, insertQuery,
+		record.ID,
+		record.ScheduleID,
+		record.UserID,
+		record.Action,
+		record.OccurrenceAt,
+		record.ActedAt,
+		record.PreviousDueAt,
+		record.NextDueAt,
+		record.NextOccurrenceAt,
+		now,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record care task action: %w", err)
+	}
+	record.CreatedAt = now
+
+	if event != nil {
+		if err := db.insertCareEvent(ctx, tx, event); err != nil {
+			return false, fmt.Errorf("failed to create care event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit care task action: %w", err)
+	}
+
+	db.logger.DebugfContext(ctx, "Applied %s to care schedule %s", record.Action, schedule.ID)
+	return true, nil
+}
+
+// ListCareTaskRecords returns the action records of a care schedule, newest first
+func (db *SQLiteDB) ListCareTaskRecords(ctx context.Context, userID, scheduleID uuid.UUID, limit int) ([]*model.CareTaskRecord, error) {
+	query := `SELECT ` + careTaskRecordColumns + ` FROM care_task_records
+			  WHERE schedule_id = $1 AND user_id = $2
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT $3`
+
+	rows, err := db.query(ctx, db.DB, query, scheduleID, userID, limit)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list care task records for schedule %s: %v", scheduleID, err)
+		return nil, fmt.Errorf("failed to list care task records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*model.CareTaskRecord{}
+	for rows.Next() {
+		var record model.CareTaskRecord
+		err := rows.Scan(
+			&record.ID,
+			&record.ScheduleID,
+			&record.UserID,
+			&record.Action,
+			&record.OccurrenceAt,
+			&record.ActedAt,
+			&record.PreviousDueAt,
+			&record.NextDueAt,
+			&record.NextOccurrenceAt,
+			&record.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan care task record: %w", err)
+		}
+		records = append(records, &record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list care task records: %w", err)
+	}
+
+	return records, nil
+}
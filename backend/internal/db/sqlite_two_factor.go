@@ -0,0 +1,249 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// GetTOTP returns the TOTP enrollment of a user, or nil if there is none
+func (db *SQLiteDB) GetTOTP(ctx context.Context, userID uuid.UUID) (*model.TOTP, error) {
+	query := `SELECT user_id, secret, enabled_at, last_used_step, created_at
+			  FROM user_totp WHERE user_id = $1`
+
+	var totp model.TOTP
+	err := db.queryRow(ctx, db.DB, query, userID).Scan(&totp.UserID, &totp.Secret, &totp.EnabledAt, &totp.LastUsedStep, &totp.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to get TOTP of user %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to get TOTP: %w", err)
+	}
+	return &totp, nil
+}
+
+// SaveTOTPSecret starts an enrollment, replacing an unconfirmed one. Returns
+// false if the user already has two-factor authentication enabled.
+func (db *SQLiteDB) SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) (bool, error) {
+	query := `INSERT INTO user_totp (user_id, secret, created_at, updated_at)
+			  VALUES ($1, $2, $3, $3)
+			  ON CONFLICT (user_id) DO UPDATE
+			  SET secret = excluded.secret, last_used_step = NULL, created_at = excluded.created_at, updated_at = excluded.updated_at
+			  WHERE user_totp.enabled_at IS NULL`
+
+	result, err := db.exec(ctx, db.DB, query, userID, secret, sqliteNow())
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to save TOTP secret of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to save TOTP secret: %w", err)
+	}
+	return rowsAffected(result) > 0, nil
+}
+
+// EnableTOTP confirms an enrollment with the time step of the code the user
+// entered and stores the hashes of their recovery codes in one transaction.
+// Returns false if there is no pending enrollment or the step was already used.
+func (db *SQLiteDB) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) (bool, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE user_totp SET enabled_at = $3, last_used_step = $2, updated_at = $3
+			  WHERE user_id = $1 AND enabled_at IS NULL
+			  AND (last_used_step IS NULL OR last_used_step < $2)`
+	result, err := db.exec(ctx, tx, query, userID, step, sqliteNow())
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to enable TOTP of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to enable TOTP: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return false, nil
+	}
+
+	if err := db.replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit TOTP enrollment: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "Two-factor authentication enabled for user %s", userID)
+	return true, nil
+}
+
+// DisableTOTP removes the enrollment, recovery codes and pending login
+// challenges of a user. Returns false if there was no enrollment.
+func (db *SQLiteDB) DisableTOTP(ctx context.Context, userID uuid.UUID) (bool, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := db.exec(ctx, tx, `DELETE FROM user_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to disable TOTP of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to disable TOTP: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return false, nil
+	}
+	if _, err := db.exec(ctx, tx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return false, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := db.exec(ctx, tx, `DELETE FROM login_challenges WHERE user_id = $1`, userID); err != nil {
+		return false, fmt.Errorf("failed to delete login challenges: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit TOTP removal: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "Two-factor authentication disabled for user %s", userID)
+	return true, nil
+}
+
+// UseTOTPStep records the time step of an accepted code. Returns false if the
+// step or a later one was already used, so every code works only once.
+func (db *SQLiteDB) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query := `UPDATE user_totp SET last_used_step = $2, updated_at = $3
+			  WHERE user_id = $1 AND enabled_at IS NOT NULL
+			  AND (last_used_step IS NULL OR last_used_step < $2)`
+
+	result, err := db.exec(ctx, db.DB, query, userID, step, sqliteNow())
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to record TOTP step of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to record TOTP step: %w", err)
+	}
+	return rowsAffected(result) > 0, nil
+}
+
+// ListUnusedRecoveryCodes returns the recovery codes of a user that were not used yet
+func (db *SQLiteDB) ListUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]*model.RecoveryCode, error) {
+	query := `SELECT id, user_id, code_hash FROM totp_recovery_codes
+			  WHERE user_id = $1 AND used_at IS NULL
+			  ORDER BY created_at`
+
+	rows, err := db.query(ctx, db.DB, query, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list recovery codes of user %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to list recovery codes: %w", err)
+	}
+	defer rows.Close()
+
+	codes := []*model.RecoveryCode{}
+	for rows.Next() {
+		var code model.RecoveryCode
+		if err := rows.Scan(&code.ID, &code.UserID, &code.CodeHash); err != nil {
+			return nil, fmt.Errorf("failed to scan recovery code: %w", err)
+		}
+		codes = append(codes, &code)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// UseRecoveryCode marks a recovery code as used. Returns false if it was used already.
+func (db *SQLiteDB) UseRecoveryCode(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := db.exec(ctx, db.DB, `UPDATE totp_recovery_codes SET used_at = $2 WHERE id = $1 AND used_at IS NULL`, id, sqliteNow())
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to use recovery code %s: %v", id, err)
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return rowsAffected(result) > 0, nil
+}
+
+// ReplaceRecoveryCodes swaps all recovery codes of a user for new ones
+func (db *SQLiteDB) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := db.replaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+	return nil
+}
+
+// replaceRecoveryCodes deletes the recovery codes of a user and inserts the given hashes
+func (db *SQLiteDB) replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID, hashes []string) error {
+	if _, err := db.exec(ctx, tx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	now := sqliteNow()
+	for _, hash := range hashes {
+		_, err := db.exec(ctx, tx, `INSERT INTO totp_recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)`,
+			uuid.New(), userID, hash, now)
+		if err != nil {
+			return fmt.Errorf("failed to store recovery codes: %w", err)
+		}
+	}
+	return nil
+}
+
+// CreateLoginChallenge stores the hash of a token that lets a user who entered
+// the right password finish logging in with their second factor
+func (db *SQLiteDB) CreateLoginChallenge(ctx context.Context, userID uuid.UUID, tokenHash []byte, expiresAt time.Time) error {
+	query := `INSERT INTO login_challenges (id, user_id, token_hash, expires_at, created_at)
+			  VALUES ($1, $2, $3, $4, $5)`
+
+	now := sqliteNow()
+	if _, err := db.exec(ctx, db.DB, query, uuid.New(), userID, tokenHash, expiresAt, now); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to create login challenge for user %s: %v", userID, err)
+		return fmt.Errorf("failed to create login challenge: %w", err)
+	}
+
+	// Expired challenges are useless, drop them while we are here
+	if _, err := db.exec(ctx, db.DB, `DELETE FROM login_challenges WHERE expires_at <= $1`, now); err != nil {
+		db.logger.WarnfContext(ctx, "Failed to delete expired login challenges: %v", err)
+	}
+	return nil
+}
+
+// AttemptLoginChallenge counts an attempt to answer a login challenge and
+// returns the user it belongs to. Returns uuid.Nil if the challenge is unknown,
+// expired or has used up its maxAttempts.
+func (db *SQLiteDB) AttemptLoginChallenge(ctx context.Context, tokenHash []byte, maxAttempts int) (uuid.UUID, error) {
+	query := `UPDATE login_challenges SET attempts = attempts + 1
+			  WHERE token_hash = $1 AND expires_at > $3 AND attempts < $2
+			  RETURNING user_id`
+
+	var userID uuid.UUID
+	err := db.queryRow(ctx, db.DB, query, tokenHash, maxAttempts, sqliteNow()).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to attempt login challenge: %v", err)
+		return uuid.Nil, fmt.Errorf("failed to attempt login challenge: %w", err)
+	}
+	return userID, nil
+}
+
+// DeleteLoginChallenge removes an answered challenge so it cannot be used again.
+// Returns false if it was already gone.
+func (db *SQLiteDB) DeleteLoginChallenge(ctx context.Context, tokenHash []byte) (bool, error) {
+	result, err := db.exec(ctx, db.DB, `DELETE FROM login_challenges WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete login challenge: %w", err)
+	}
+	return rowsAffected(result) > 0, nil
+}
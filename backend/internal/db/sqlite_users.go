@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// RevokeSessions invalidates every token issued to a user before now
+func (db *SQLiteDB) RevokeSessions(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE users SET sessions_revoked_at = $2, updated_at = $2 WHERE id = $1`
+
+	if _, err := db.exec(ctx, db.DB, query, userID, sqliteNow()); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to revoke sessions of user %s: %v", userID, err)
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// UpdateUserProfile saves the editable profile fields of a user. When relocate
+// is not nil the user's care schedules are passed to it in the same
+// transaction and the pending occurrences it sets are saved, so a timezone
+// change and the due dates it moves are committed together.
+// Returns false if the user does not exist.
+func (db *SQLiteDB) UpdateUserProfile(ctx context.Context, user *model.User, relocate func(*model.CareSchedule) error) (bool, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := sqliteNow()
+	query := `UPDATE users SET full_name = $2, timezone = $3, avatar_url = $4, updated_at = $5 WHERE id = $1`
+	result, err := db.exec(ctx, tx, query, user.ID, user.FullName, user.Timezone, user.AvatarURL, now)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to update profile of user %s: %v", user.ID, err)
+		return false, fmt.Errorf("failed to update profile: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return false, nil
+	}
+	user.UpdatedAt = now
+
+	if relocate != nil {
+		rows, err := db.query(ctx, tx, `SELECT `+careScheduleColumns+` FROM care_schedules WHERE user_id = $1`, user.ID)
+		if err != nil {
+			return false, fmt.Errorf("failed to load care schedules: %w", err)
+		}
+		schedules, err := collectSQLiteCareSchedules(rows)
+		if err != nil {
+			return false, fmt.Errorf("failed to load care schedules: %w", err)
+		}
+
+		for _, schedule := range schedules {
+			if err := relocate(schedule); err != nil {
+				return false, err
+			}
+			_, err := db.exec(ctx, tx, `UPDATE care_schedules SET next_due_at = $2, occurrence_at = $3, updated_at = $4 WHERE id = $1`,
+				schedule.ID, schedule.NextDueAt, schedule.OccurrenceAt, now)
+			if err != nil {
+				return false, fmt.Errorf("failed to update care schedule %s: %w", schedule.ID, err)
+			}
+		}
+		db.logger.DebugfContext(ctx, "Moved %d care schedules of user %s to %s", len(schedules), user.ID, user.Timezone)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit profile: %w", err)
+	}
+	return true, nil
+}
+
+// UpdatePassword sets a new password hash for a user and invalidates every
+// token issued before now
+func (db *SQLiteDB) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2, sessions_revoked_at = $3, updated_at = $3 WHERE id = $1`
+
+	if _, err := db.exec(ctx, db.DB, query, userID, passwordHash, sqliteNow()); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to update password of user %s: %v", userID, err)
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "Password changed for user %s", userID)
+	return nil
+}
+
+// ListUsers returns all users, oldest first
+func (db *SQLiteDB) ListUsers(ctx context.Context) ([]*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY created_at, id`
+
+	rows, err := db.query(ctx, db.DB, query)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list users: %v", err)
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*model.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, nil
+}
+
+// SetUserRole changes the role of a user. Returns false if the user does not exist.
+func (db *SQLiteDB) SetUserRole(ctx context.Context, userID uuid.UUID, role model.UserRole) (bool, error) {
+	query := `UPDATE users SET role = $2, updated_at = $3 WHERE id = $1`
+
+	result, err := db.exec(ctx, db.DB, query, userID, role, sqliteNow())
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to set role of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to set user role: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return false, nil
+	}
+
+	db.logger.InfofContext(ctx, "Role of user %s set to %s", userID, role)
+	return true, nil
+}
+
+// SetUserDisabled disables or re-enables an account. Disabling also revokes
+// every session of the user. Returns false if the user does not exist.
+func (db *SQLiteDB) SetUserDisabled(ctx context.Context, userID uuid.UUID, disabled bool) (bool, error) {
+	query := `UPDATE users SET disabled_at = NULL, updated_at = $2 WHERE id = $1`
+	if disabled {
+		query = `UPDATE users SET disabled_at = COALESCE(disabled_at, $2), sessions_revoked_at = $2, updated_at = $2
+				 WHERE id = $1`
+	}
+
+	result, err := db.exec(ctx, db.DB, query, userID, sqliteNow())
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to update disabled state of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to update disabled state: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return false, nil
+	}
+
+	if disabled {
+		db.logger.InfofContext(ctx, "User %s disabled", userID)
+	} else {
+		db.logger.InfofContext(ctx, "User %s enabled", userID)
+	}
+	return true, nil
+}
+
+// collectSQLiteCareSchedules scans all rows into a slice of care schedules
+func collectSQLiteCareSchedules(rows *sql.Rows) ([]*model.CareSchedule, error) {
+	defer rows.Close()
+
+	schedules := []*model.CareSchedule{}
+	for rows.Next() {
+		schedule, err := scanCareSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan care schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
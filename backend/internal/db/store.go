@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// Driver names a database backend
+type Driver string
+
+const (
+	DriverPostgres Driver = "postgres"
+	DriverSQLite   Driver = "sqlite"
+)
+
+// Driver returns the database backend selected by the scheme of the DSN, or
+// an empty string if the scheme is not supported
+func (c Config) Driver() Driver {
+	scheme, _, found := strings.Cut(c.DSN, ":")
+	if !found {
+		return ""
+	}
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return DriverPostgres
+	case "sqlite", "sqlite3":
+		return DriverSQLite
+	default:
+		return ""
+	}
+}
+
+// Store is the persistence layer of the API. PostgresDB and SQLiteDB implement
+// it, so handlers and workers do not depend on a particular database.
+type Store interface {
+	Ping(ctx context.Context) error
+	Close()
+
+	// Users
+	CheckIfEmailExists(ctx context.Context, email string) (bool, error)
+	CreateUser(ctx context.Context, user *model.User) (uuid.UUID, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*model.User, error)
+	RevokeSessions(ctx context.Context, userID uuid.UUID) error
+	UpdateUserProfile(ctx context.Context, user *model.User, relocate func(*model.CareSchedule) error) (bool, error)
+	UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	ListUsers(ctx context.Context) ([]*model.User, error)
+	SetUserRole(ctx context.Context, userID uuid.UUID, role model.UserRole) (bool, error)
+	SetUserDisabled(ctx context.Context, userID uuid.UUID, disabled bool) (bool, error)
+
+	// Instance administration
+	GetInstanceSettings(ctx context.Context) (*model.InstanceSettings, error)
+	SetRegistrationEnabled(ctx context.Context, enabled bool) (*model.InstanceSettings, error)
+	GetInstanceStats(ctx context.Context) (*model.InstanceStats, error)
+
+	// Invite codes
+	CreateInviteCode(ctx context.Context, invite *model.InviteCode) error
+	ListInviteCodes(ctx context.Context) ([]*model.InviteCode, error)
+	DeleteInviteCode(ctx context.Context, id uuid.UUID) (bool, error)
+	RedeemInviteCode(ctx context.Context, hash []byte, now time.Time) (*model.InviteCode, error)
+	ReleaseInviteCode(ctx context.Context, id uuid.UUID) error
+
+	// Account deletion
+	ScheduleUserDeletion(ctx context.Context, userID uuid.UUID, at time.Time) (*time.Time, error)
+	CancelUserDeletion(ctx context.Context, userID uuid.UUID) (bool, error)
+	ListUsersDueForDeletion(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
+	EraseUser(ctx context.Context, userID uuid.UUID, avatarKeys []string, dueBy *time.Time) (bool, error)
+	ClaimFileDeletions(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.FileDeletion, error)
+	CompleteFileDeletion(ctx context.Context, id uuid.UUID) error
+	MarkFileDeletionFailed(ctx context.Context, id uuid.UUID, reason string, retryAt time.Time) error
+
+	// OAuth identities
+	GetUserByIdentity(ctx context.Context, provider model.AuthProvider, subject string) (*model.User, error)
+	CreateOAuthUser(ctx context.Context, user *model.User, identity *model.UserIdentity) error
+	LinkIdentity(ctx context.Context, identity *model.UserIdentity) error
+	ListIdentitiesByUser(ctx context.Context, userID uuid.UUID) ([]*model.UserIdentity, error)
+
+	// Email verification and password reset
+	CreateEmailVerificationToken(ctx context.Context, userID uuid.UUID, email string, tokenHash []byte, expiresAt time.Time) error
+	VerifyEmail(ctx context.Context, tokenHash []byte) (bool, error)
+	CreatePasswordResetToken(ctx context.Context, userID uuid.UUID, tokenHash []byte, expiresAt time.Time) error
+	GetPasswordResetUserID(ctx context.Context, tokenHash []byte) (uuid.UUID, error)
+	ResetPassword(ctx context.Context, tokenHash []byte, passwordHash string) (bool, error)
+
+	// Two-factor authentication
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*model.TOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) (bool, error)
+	EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) (bool, error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID) (bool, error)
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	ListUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]*model.RecoveryCode, error)
+	UseRecoveryCode(ctx context.Context, id uuid.UUID) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error
+	CreateLoginChallenge(ctx context.Context, userID uuid.UUID, tokenHash []byte, expiresAt time.Time) error
+	AttemptLoginChallenge(ctx context.Context, tokenHash []byte, maxAttempts int) (uuid.UUID, error)
+	DeleteLoginChallenge(ctx context.Context, tokenHash []byte) (bool, error)
+
+	// Login throttling
+	LoginBlockedUntil(ctx context.Context, ip, account string, now time.Time) (*time.Time, error)
+	ReserveLoginAttempt(ctx context.Context, scope model.ThrottleScope, key string, now, windowStart time.Time, limit int, lockedUntil time.Time) (int, error)
+	ReleaseLoginAttempt(ctx context.Context, scope model.ThrottleScope, key string, lockedUntil time.Time) error
+	BlockLogin(ctx context.Context, scope model.ThrottleScope, key string, until time.Time) error
+	ClearLoginFailures(ctx context.Context, scope model.ThrottleScope, key string) error
+	DeleteStaleLoginThrottles(ctx context.Context, before, now time.Time) (int64, error)
+
+	// API keys
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	ListAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]*model.APIKey, error)
+	CountAPIKeysByUser(ctx context.Context, userID uuid.UUID) (int, error)
+	GetAPIKeyByHash(ctx context.Context, hash []byte) (*model.APIKey, error)
+	TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+	DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) (bool, error)
+
+	// Plants
+	CreatePlant(ctx context.Context, plant *model.Plant) error
+	GetPlant(ctx context.Context, userID, plantID uuid.UUID) (*model.Plant, error)
+	ListPlantsByUser(ctx context.Context, userID uuid.UUID) ([]*model.Plant, error)
+	UpdatePlant(ctx context.Context, plant *model.Plant) (bool, error)
+	DeletePlant(ctx context.Context, userID, plantID uuid.UUID) (bool, error)
+
+	// Photos
+	CreatePhoto(ctx context.Context, photo *model.Photo, event *model.CareEvent) error
+	GetPhoto(ctx context.Context, userID, plantID, photoID uuid.UUID) (*model.Photo, error)
+	ListPhotosByPlant(ctx context.Context, userID, plantID uuid.UUID) ([]*model.Photo, error)
+	DeletePhoto(ctx context.Context, userID, plantID, photoID uuid.UUID) (bool, error)
+
+	// Care events
+	CreateCareEvent(ctx context.Context, event *model.CareEvent) error
+	ListCareEvents(ctx context.Context, userID, plantID uuid.UUID, filter model.CareEventFilter) ([]*model.CareEvent, error)
+
+	// Care schedules and task actions
+	CreateCareSchedule(ctx context.Context, schedule *model.CareSchedule) error
+	GetCareSchedule(ctx context.Context, userID, plantID, scheduleID uuid.UUID) (*model.CareSchedule, error)
+	ListCareSchedulesByPlant(ctx context.Context, userID, plantID uuid.UUID) ([]*model.CareSchedule, error)
+	ListCareSchedulesDueBefore(ctx context.Context, userID uuid.UUID, until time.Time) ([]*model.CareSchedule, error)
+	UpdateCareSchedule(ctx context.Context, schedule *model.CareSchedule, resetState bool) (bool, error)
+	DeleteCareSchedule(ctx context.Context, userID, plantID, scheduleID uuid.UUID) (bool, error)
+	ApplyCareTaskAction(ctx context.Context, schedule *model.CareSchedule, record *model.CareTaskRecord, event *model.CareEvent) (bool, error)
+	ListCareTaskRecords(ctx context.Context, userID, scheduleID uuid.UUID, limit int) ([]*model.CareTaskRecord, error)
+
+	// Push devices
+	UpsertDevice(ctx context.Context, device *model.Device) error
+	ListDevicesByUser(ctx context.Context, userID uuid.UUID) ([]*model.Device, error)
+	DeleteDevice(ctx context.Context, userID uuid.UUID, token string) (bool, error)
+	DeleteDeviceByToken(ctx context.Context, token string) error
+
+	// Reminder deliveries
+	EnqueueDueReminders(ctx context.Context, channels []string, now time.Time, lookback time.Duration) (int64, error)
+	ClaimReminders(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.Reminder, error)
+	MarkReminderSent(ctx context.Context, deliveryID uuid.UUID) error
+	MarkReminderCancelled(ctx context.Context, deliveryID uuid.UUID) error
+	MarkReminderFailed(ctx context.Context, deliveryID uuid.UUID, reason string, retryAt *time.Time) error
+
+	// Mail outbox
+	EnqueueMail(ctx context.Context, mail *model.OutboundMail) error
+	ClaimMail(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.OutboundMail, error)
+	MarkMailSent(ctx context.Context, mailID uuid.UUID) error
+	MarkMailFailed(ctx context.Context, mailID uuid.UUID, reason string, retryAt *time.Time) error
+}
+
+// Both backends must implement the whole Store
+var (
+	_ Store = (*PostgresDB)(nil)
+	_ Store = (*SQLiteDB)(nil)
+)
+
+// rowScanner is a single result row of either backend
+type rowScanner interface {
+	Scan(dest ...any) error
+}
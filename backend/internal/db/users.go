@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const userColumns = `id, avatar_url, auth_provider, created_at, email, full_name, password_hash, timezone, updated_at, email_verified_at, sessions_revoked_at, deletion_scheduled_at, role, disabled_at`
+
+// scanUser scans a single user row in userColumns order
+func scanUser(row rowScanner) (*model.User, error) {
+	var user model.User
+	err := row.Scan(
+		&user.ID,
+		&user.AvatarURL,
+		&user.AuthProvider,
+		&user.CreatedAt,
+		&user.Email,
+		&user.FullName,
+		&user.PasswordHash,
+		&user.Timezone,
+		&user.UpdatedAt,
+		&user.EmailVerifiedAt,
+		&user.SessionsRevokedAt,
+		&user.DeletionScheduledAt,
+		&user.Role,
+		&user.DisabledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RevokeSessions invalidates every token issued to a user before now
+func (db *PostgresDB) RevokeSessions(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE users SET sessions_revoked_at = NOW(), updated_at = NOW() WHERE id = $1`
+
+	if _, err := db.Pool.Exec(ctx, query, userID); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to revoke sessions of user %s: %v", userID, err)
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// UpdateUserProfile saves the editable profile fields of a user. When relocate
+// is not nil the user's care schedules are locked and passed to it in the same
+// transaction and the pending occurrences it sets are saved, so a timezone
+// change and the due dates it moves are committed together.
+// Returns false if the user does not exist.
+func (db *PostgresDB) UpdateUserProfile(ctx context.Context, user *model.User, relocate func(*model.CareSchedule) error) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE users SET full_name = $2, timezone = $3, avatar_url = $4, updated_at = NOW()
+			  WHERE id = $1
+			  RETURNING updated_at`
+	err = tx.QueryRow(ctx, query, user.ID, user.FullName, user.Timezone, user.AvatarURL).Scan(&user.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		db.logger.DebugfContext(ctx, "Failed to update profile of user %s: %v", user.ID, err)
+		return false, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	if relocate != nil {
+		rows, err := tx.Query(ctx, `SELECT `+careScheduleColumns+` FROM care_schedules WHERE user_id = $1 FOR UPDATE`, user.ID)
+		if err != nil {
+			return false, fmt.Errorf("failed to lock care schedules: %w", err)
+		}
+		schedules, err := collectCareSchedules(rows)
+		if err != nil {
+			return false, fmt.Errorf("failed to lock care schedules: %w", err)
+		}
+
+		for _, schedule := range schedules {
+			if err := relocate(schedule); err != nil {
+				return false, err
+			}
+			_, err := tx.Exec(ctx, `UPDATE care_schedules SET next_due_at = $2, occurrence_at = $3, updated_at = NOW() WHERE id = $1`,
+				schedule.ID, schedule.NextDueAt, schedule.OccurrenceAt)
+			if err != nil {
+				return false, fmt.Errorf("failed to update care schedule %s: %w", schedule.ID, err)
+			}
+		}
+		db.logger.DebugfContext(ctx, "Moved %d care schedules of user %s to %s", len(schedules), user.ID, user.Timezone)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit profile: %w", err)
+	}
+	return true, nil
+}
+
+// UpdatePassword sets a new password hash for a user and invalidates every
+// token issued before now
+func (db *PostgresDB) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2, sessions_revoked_at = NOW(), updated_at = NOW() WHERE id = $1`
+
+	if _, err := db.Pool.Exec(ctx, query, userID, passwordHash); err != nil {
+		db.logger.DebugfContext(ctx, "Failed to update password of user %s: %v", userID, err)
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	db.logger.InfofContext(ctx, "Password changed for user %s", userID)
+	return nil
+}
+
+// ListUsers returns all users, oldest first
+func (db *PostgresDB) ListUsers(ctx context.Context) ([]*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY created_at, id`
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to list users: %v", err)
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*model.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, nil
+}
+
+// SetUserRole changes the role of a user. Returns false if the user does not exist.
+func (db *PostgresDB) SetUserRole(ctx context.Context, userID uuid.UUID, role model.UserRole) (bool, error) {
+	query := `UPDATE users SET role = $2, updated_at = NOW() WHERE id = $1`
+
+	tag, err := db.Pool.Exec(ctx, query, userID, role)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to set role of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to set user role: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	db.logger.InfofContext(ctx, "Role of user %s set to %s", userID, role)
+	return true, nil
+}
+
+// SetUserDisabled disables or re-enables an account. Disabling also revokes
+// every session of the user. Returns false if the user does not exist.
+func (db *PostgresDB) SetUserDisabled(ctx context.Context, userID uuid.UUID, disabled bool) (bool, error) {
+	query := `UPDATE users SET disabled_at = NULL, updated_at = NOW() WHERE id = $1`
+	if disabled {
+		query = `UPDATE users SET disabled_at = COALESCE(disabled_at, NOW()), sessions_revoked_at = NOW(), updated_at = NOW()
+				 WHERE id = $1`
+	}
+
+	tag, err := db.Pool.Exec(ctx, query, userID)
+	if err != nil {
+		db.logger.DebugfContext(ctx, "Failed to update disabled state of user %s: %v", userID, err)
+		return false, fmt.Errorf("failed to update disabled state: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if disabled {
+		db.logger.InfofContext(ctx, "User %s disabled", userID)
+	} else {
+		db.logger.InfofContext(ctx, "User %s enabled", userID)
+	}
+	return true, nil
+}
+
+// prefixColumns qualifies each column of a comma separated list with a table alias
+func prefixColumns(alias, columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, column := range parts {
+		parts[i] = alias + "." + strings.TrimSpace(column)
+	}
+	return strings.Join(parts, ", ")
+}
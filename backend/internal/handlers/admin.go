@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/account"
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/registration"
+	"github.com/anish-chanda/ferna/model"
+)
+
+const (
+	// maxInviteNoteLength bounds the note admins keep on an invite code
+	maxInviteNoteLength = 200
+)
+
+// The admin handlers rely on the route being wrapped with the Authenticator's
+// Admin or RequireRole middleware and do not check roles themselves.
+
+type AdminUserListResponse struct {
+	Success bool          `json:"success"`
+	Users   []*model.User `json:"users"`
+}
+
+type InstanceStatsResponse struct {
+	Success bool                 `json:"success"`
+	Stats   *model.InstanceStats `json:"stats"`
+}
+
+// RegistrationSettingsResponse carries the configured registration mode and
+// whether admins currently allow sign up
+type RegistrationSettingsResponse struct {
+	Success  bool                    `json:"success"`
+	Mode     registration.Mode       `json:"mode"`
+	Settings *model.InstanceSettings `json:"settings"`
+}
+
+// CreateInviteCodeRequest sets the limits of a new invite code, absent limits mean unlimited
+type CreateInviteCodeRequest struct {
+	Note      string     `json:"note"`
+	MaxUses   *int       `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateInviteCodeResponse carries the full code, which is only shown once
+type CreateInviteCodeResponse struct {
+	Success bool              `json:"success"`
+	Code    string            `json:"code"`
+	Invite  *model.InviteCode `json:"invite"`
+}
+
+type InviteCodeListResponse struct {
+	Success bool                `json:"success"`
+	Invites []*model.InviteCode `json:"invites"`
+}
+
+// UpdateRegistrationRequest opens or closes sign up
+type UpdateRegistrationRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// AdminListUsersHandler returns every account on the instance without password hashes
+func AdminListUsersHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		users, err := database.ListUsers(ctx)
+		if err != nil {
+			logger.Debugf("Database error listing users: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		for _, user := range users {
+			user.PasswordHash = nil
+		}
+
+		writeJSONResponse(w, AdminUserListResponse{Success: true, Users: users}, http.StatusOK)
+	}
+}
+
+// AdminDeleteUserHandler erases an account and all its data right away,
+// skipping any grace period. Admins delete their own account through DELETE /api/me.
+func AdminDeleteUserHandler(eraser *account.Eraser, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		adminID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		userID, ok := pathUUID(w, r, "id")
+		if !ok {
+			return
+		}
+		if userID == adminID {
+			writeErrorResponse(w, "Delete your own account through /api/me", http.StatusBadRequest)
+			return
+		}
+
+		if err := eraser.Erase(ctx, userID); err != nil {
+			if errors.Is(err, account.ErrUserNotFound) {
+				writeErrorResponse(w, "User not found", http.StatusNotFound)
+				return
+			}
+			logger.Errorf("Account deletion of user %s by admin %s failed: %v", userID, adminID, err)
+			writeErrorResponse(w, "Failed to delete account", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Infof("Admin %s deleted account %s", adminID, userID)
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Account deleted",
+		}, http.StatusOK)
+	}
+}
+
+// InstanceStatsHandler returns counts of the accounts and data on the instance
+func InstanceStatsHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		stats, err := database.GetInstanceStats(ctx)
+		if err != nil {
+			logger.Debugf("Database error getting instance stats: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, InstanceStatsResponse{Success: true, Stats: stats}, http.StatusOK)
+	}
+}
+
+// GetRegistrationHandler reports whether new accounts can sign up
+func GetRegistrationHandler(database db.Store, policy *registration.Policy, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		settings, err := database.GetInstanceSettings(ctx)
+		if err != nil {
+			logger.Debugf("Database error getting instance settings: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, RegistrationSettingsResponse{Success: true, Mode: policy.Mode(), Settings: settings}, http.StatusOK)
+	}
+}
+
+// UpdateRegistrationHandler opens or closes sign up for new accounts.
+// Existing accounts can sign in either way. Sign up stays closed while the
+// configured registration mode is closed.
+func UpdateRegistrationHandler(database db.Store, policy *registration.Policy, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req UpdateRegistrationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in update registration request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		if req.Enabled == nil {
+			writeErrorResponse(w, "enabled is required", http.StatusBadRequest)
+			return
+		}
+
+		settings, err := database.SetRegistrationEnabled(ctx, *req.Enabled)
+		if err != nil {
+			logger.Debugf("Registration setting update failed: %v", err)
+			writeErrorResponse(w, "Failed to update registration setting", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Infof("Admin %s set registration enabled to %t", userID, *req.Enabled)
+		writeJSONResponse(w, RegistrationSettingsResponse{Success: true, Mode: policy.Mode(), Settings: settings}, http.StatusOK)
+	}
+}
+
+// CreateInviteCodeHandler mints an invite code for invite-only registration
+func CreateInviteCodeHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req CreateInviteCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in create invite code request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		note, err := validateInviteCodeRequest(req, time.Now())
+		if err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		code, invite, err := registration.NewInviteCode(userID, note, req.MaxUses, req.ExpiresAt)
+		if err != nil {
+			logger.Errorf("Failed to generate invite code: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if err := database.CreateInviteCode(ctx, invite); err != nil {
+			logger.Debugf("Invite code creation failed: %v", err)
+			writeErrorResponse(w, "Failed to create invite code", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, CreateInviteCodeResponse{Success: true, Code: code, Invite: invite}, http.StatusCreated)
+	}
+}
+
+// ListInviteCodesHandler returns all invite codes without their secrets
+func ListInviteCodesHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		invites, err := database.ListInviteCodes(ctx)
+		if err != nil {
+			logger.Debugf("Database error listing invite codes: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, InviteCodeListResponse{Success: true, Invites: invites}, http.StatusOK)
+	}
+}
+
+// DeleteInviteCodeHandler revokes an invite code. Accounts created with it are kept.
+func DeleteInviteCodeHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		inviteID, ok := pathUUID(w, r, "id")
+		if !ok {
+			return
+		}
+
+		deleted, err := database.DeleteInviteCode(ctx, inviteID)
+		if err != nil {
+			logger.Debugf("Invite code deletion failed: %v", err)
+			writeErrorResponse(w, "Failed to revoke invite code", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			writeErrorResponse(w, "Invite code not found", http.StatusNotFound)
+			return
+		}
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Invite code revoked",
+		}, http.StatusOK)
+	}
+}
+
+// validateInviteCodeRequest checks the limits of a new invite code and returns its trimmed note
+func validateInviteCodeRequest(req CreateInviteCodeRequest, now time.Time) (*string, error) {
+	if req.MaxUses != nil && *req.MaxUses < 1 {
+		return nil, fmt.Errorf("max_uses must be at least 1")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+
+	note := strings.TrimSpace(req.Note)
+	if len(note) > maxInviteNoteLength {
+		return nil, fmt.Errorf("note must be at most %d characters long", maxInviteNoteLength)
+	}
+	return optionalString(note), nil
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/auth"
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxAPIKeysPerUser bounds how many keys a user can hold at once
+	maxAPIKeysPerUser = 25
+	// maxAPIKeyNameLength bounds the label of a key
+	maxAPIKeyNameLength = 100
+)
+
+type CreateAPIKeyRequest struct {
+	Name   string              `json:"name"`
+	Scopes []model.APIKeyScope `json:"scopes"`
+}
+
+// CreateAPIKeyResponse carries the full key, which is only shown once
+type CreateAPIKeyResponse struct {
+	Success bool          `json:"success"`
+	Key     string        `json:"key"`
+	APIKey  *model.APIKey `json:"api_key"`
+}
+
+type APIKeyListResponse struct {
+	Success bool            `json:"success"`
+	APIKeys []*model.APIKey `json:"api_keys"`
+}
+
+// CreateAPIKeyHandler creates a personal API key for the authenticated user
+func CreateAPIKeyHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in create API key request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		name, scopes, err := validateAPIKeyRequest(req)
+		if err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		count, err := database.CountAPIKeysByUser(ctx, userID)
+		if err != nil {
+			logger.Debugf("Database error counting API keys: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if count >= maxAPIKeysPerUser {
+			writeErrorResponse(w, fmt.Sprintf("at most %d API keys are allowed, revoke one first", maxAPIKeysPerUser), http.StatusConflict)
+			return
+		}
+
+		key, prefix, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			logger.Errorf("Failed to generate API key: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		apiKey := &model.APIKey{
+			ID:      uuid.New(),
+			UserID:  userID,
+			Name:    name,
+			Prefix:  prefix,
+			KeyHash: hash,
+			Scopes:  scopes,
+		}
+		if err := database.CreateAPIKey(ctx, apiKey); err != nil {
+			logger.Debugf("API key creation failed: %v", err)
+			writeErrorResponse(w, "Failed to create API key", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, CreateAPIKeyResponse{Success: true, Key: key, APIKey: apiKey}, http.StatusCreated)
+	}
+}
+
+// ListAPIKeysHandler returns the API keys of the authenticated user without their secrets
+func ListAPIKeysHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		keys, err := database.ListAPIKeysByUser(ctx, userID)
+		if err != nil {
+			logger.Debugf("Database error listing API keys: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, APIKeyListResponse{Success: true, APIKeys: keys}, http.StatusOK)
+	}
+}
+
+// DeleteAPIKeyHandler revokes an API key of the authenticated user
+func DeleteAPIKeyHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		keyID, ok := pathUUID(w, r, "id")
+		if !ok {
+			return
+		}
+
+		deleted, err := database.DeleteAPIKey(ctx, userID, keyID)
+		if err != nil {
+			logger.Debugf("API key deletion failed: %v", err)
+			writeErrorResponse(w, "Failed to revoke API key", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			writeErrorResponse(w, "API key not found", http.StatusNotFound)
+			return
+		}
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "API key revoked",
+		}, http.StatusOK)
+	}
+}
+
+// validateAPIKeyRequest returns the trimmed name and the deduplicated scopes of a create request
+func validateAPIKeyRequest(req CreateAPIKeyRequest) (string, []model.APIKeyScope, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return "", nil, fmt.Errorf("name is required")
+	}
+	if len(name) > maxAPIKeyNameLength {
+		return "", nil, fmt.Errorf("name must be at most %d characters long", maxAPIKeyNameLength)
+	}
+
+	scopes := []model.APIKeyScope{}
+	seen := make(map[model.APIKeyScope]bool)
+	for _, scope := range req.Scopes {
+		if !scope.Valid() {
+			return "", nil, fmt.Errorf("scopes must be any of: read, plants, tasks")
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return name, scopes, nil
+}
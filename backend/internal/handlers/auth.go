@@ -11,6 +11,10 @@ import (
 	"github.com/anish-chanda/ferna/internal/auth"
 	"github.com/anish-chanda/ferna/internal/db"
 	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/mail"
+	"github.com/anish-chanda/ferna/internal/registration"
+	"github.com/anish-chanda/ferna/internal/schedule"
+	"github.com/anish-chanda/ferna/internal/throttle"
 	"github.com/anish-chanda/ferna/model"
 	"github.com/google/uuid"
 )
@@ -20,6 +24,8 @@ type SignupRequest struct {
 	Password string `json:"password"`
 	FullName string `json:"full_name"`
 	Timezone string `json:"timezone"`
+	// InviteCode is required while registration is invite-only
+	InviteCode string `json:"invite_code"`
 }
 
 type SignupResponse struct {
@@ -37,11 +43,17 @@ type LoginResponse struct {
 	Success bool      `json:"success"`
 	UserID  uuid.UUID `json:"user_id,omitempty"`
 	Message string    `json:"message"`
+	// TwoFactorRequired is set when the login has to be completed with a
+	// second factor at /api/auth/login/2fa using ChallengeToken
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
-// SignupHandler handles user registration for local auth provider
-func SignupHandler(database *db.PostgresDB, logger *logger.ServiceLogger) http.HandlerFunc {
+// SignupHandler handles user registration for local auth provider and emails a
+// link to verify the address. policy decides whether the account can be created.
+func SignupHandler(database db.Store, policy *registration.Policy, mailer *mail.Mailer, verifyURL string, verifyTTL time.Duration, logger *logger.ServiceLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
 		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 		defer cancel()
 
@@ -63,7 +75,24 @@ func SignupHandler(database *db.PostgresDB, logger *logger.ServiceLogger) http.H
 		// Normalize email
 		email := strings.TrimSpace(strings.ToLower(req.Email))
 
-		// Check if email already exists
+		// Check the registration mode, redeeming the invite code if one is needed.
+		// The configuration only allows domains mode if the email has to be
+		// verified before login, so the address can be trusted here. This comes
+		// before the existence check so that callers the policy refuses cannot
+		// find out which emails are registered.
+		release, err := policy.Admit(ctx, email, req.InviteCode, true)
+		if err != nil {
+			writeRegistrationError(w, logger, err)
+			return
+		}
+		created := false
+		defer func() {
+			if !created {
+				release()
+			}
+		}()
+
+		// Check if email already exists, the deferred release gives the invite code back
 		exists, err := database.CheckIfEmailExists(ctx, email)
 		if err != nil {
 			logger.Debugf("Database error checking email existence: %v", err)
@@ -76,7 +105,7 @@ func SignupHandler(database *db.PostgresDB, logger *logger.ServiceLogger) http.H
 		}
 
 		// Hash password
-		hashedPassword, err := auth.HashPassword(req.Password)
+		hashedPassword, err := auth.HashPassword(ctx, req.Password)
 		if err != nil {
 			logger.Debugf("Password hashing failed: %v", err)
 			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
@@ -109,8 +138,15 @@ func SignupHandler(database *db.PostgresDB, logger *logger.ServiceLogger) http.H
 			return
 		}
 
+		created = true
 		logger.Infof("User registered successfully: %s", email)
 
+		// The account exists at this point, a failed email can be retried through the resend endpoint
+		user.ID = createdUserID
+		if err := sendEmailVerification(ctx, database, mailer, verifyURL, verifyTTL, user, email); err != nil {
+			logger.Errorf("Failed to send verification email to new user %s: %v", createdUserID, err)
+		}
+
 		// Return success response
 		response := SignupResponse{
 			Success: true,
@@ -124,9 +160,14 @@ func SignupHandler(database *db.PostgresDB, logger *logger.ServiceLogger) http.H
 	}
 }
 
-// LoginHandler handles user authentication for local auth provider
-func LoginHandler(database *db.PostgresDB, logger *logger.ServiceLogger) http.HandlerFunc {
+// LoginHandler handles user authentication for local auth provider. Accounts
+// with two-factor authentication get a challenge to answer with their code
+// instead of a session; all others get the same JWT session as /auth/local/login.
+// Adding ?sess=1 limits the session cookie to the browser session. Repeated
+// failures are answered with 429 before the password is even checked.
+func LoginHandler(database db.Store, sessions *auth.Sessions, throttler *throttle.Throttler, requireVerifiedEmail bool, logger *logger.ServiceLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
 		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 		defer cancel()
 
@@ -147,6 +188,14 @@ func LoginHandler(database *db.PostgresDB, logger *logger.ServiceLogger) http.Ha
 		// Normalize email
 		email := strings.TrimSpace(strings.ToLower(req.Email))
 
+		// Refuse throttled attempts before spending an Argon2 computation on them
+		ip := throttler.ClientIP(r)
+		attempt, ok := beginLoginAttempt(ctx, w, throttler, logger, ip, email)
+		if !ok {
+			return
+		}
+		defer releaseLoginAttempt(ctx, attempt, logger)
+
 		// Get user from database
 		user, err := database.GetUserByEmail(ctx, email)
 		if err != nil {
@@ -156,6 +205,7 @@ func LoginHandler(database *db.PostgresDB, logger *logger.ServiceLogger) http.Ha
 		}
 		if user == nil {
 			logger.Debugf("Login attempt for non-existent user: %s", email)
+			recordLoginFailure(ctx, attempt, logger)
 			writeErrorResponse(w, "Invalid email or password", http.StatusUnauthorized)
 			return
 		}
@@ -163,12 +213,13 @@ func LoginHandler(database *db.PostgresDB, logger *logger.ServiceLogger) http.Ha
 		// Check if user is local auth provider and has password
 		if user.AuthProvider != model.AuthProviderLocal || user.PasswordHash == nil {
 			logger.Debugf("Login attempt for non-local user: %s", email)
+			recordLoginFailure(ctx, attempt, logger)
 			writeErrorResponse(w, "Invalid email or password", http.StatusUnauthorized)
 			return
 		}
 
 		// Verify password
-		valid, err := auth.VerifyPassword(req.Password, *user.PasswordHash)
+		valid, err := auth.VerifyPassword(ctx, req.Password, *user.PasswordHash)
 		if err != nil {
 			logger.Debugf("Password verification error: %v", err)
 			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
@@ -176,23 +227,119 @@ func LoginHandler(database *db.PostgresDB, logger *logger.ServiceLogger) http.Ha
 		}
 		if !valid {
 			logger.Debugf("Invalid password for user: %s", email)
+			recordLoginFailure(ctx, attempt, logger)
 			writeErrorResponse(w, "Invalid email or password", http.StatusUnauthorized)
 			return
 		}
 
-		logger.Infof("User logged in successfully: %s", email)
+		if user.DisabledAt != nil {
+			logger.Debugf("Login attempt for disabled account: %s", email)
+			writeErrorResponse(w, "Account is disabled", http.StatusForbidden)
+			return
+		}
 
-		// Return success response
-		response := LoginResponse{
-			Success: true,
-			UserID:  user.ID,
-			Message: "Login successful",
+		if requireVerifiedEmail && user.EmailVerifiedAt == nil {
+			logger.Debugf("Login attempt with unverified email: %s", email)
+			writeErrorResponse(w, "Email address is not verified", http.StatusForbidden)
+			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(response)
+		totp, err := database.GetTOTP(ctx, user.ID)
+		if err != nil {
+			logger.Debugf("Database error getting TOTP: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if totp.Enabled() {
+			logger.Debugf("Password accepted, awaiting second factor: %s", email)
+			writeLoginChallenge(ctx, w, database, logger, user)
+			return
+		}
+
+		// With two-factor authentication the failures are only cleared once the code is entered
+		recordLoginSuccess(ctx, attempt, logger)
+		issueSession(w, r, sessions, logger, user)
+	}
+}
+
+// beginLoginAttempt reserves a login attempt from ip for email, writing a 429
+// response if either is currently blocked. Throttle errors let the attempt
+// through, untracked, rather than locking everyone out while the database is
+// struggling. Attempts that are not failed or succeeded must be released.
+func beginLoginAttempt(ctx context.Context, w http.ResponseWriter, throttler *throttle.Throttler, logger *logger.ServiceLogger, ip, email string) (*throttle.Attempt, bool) {
+	attempt, wait, err := throttler.Begin(ctx, ip, email)
+	if err != nil {
+		logger.Errorf("Failed to check login throttle: %v", err)
+		return nil, true
+	}
+	if wait > 0 {
+		logger.Debugf("Throttled login attempt for %s from %s", email, ip)
+		throttle.WriteTooManyRequests(w, wait)
+		return nil, false
+	}
+	return attempt, true
+}
+
+// recordLoginFailure counts a login attempt as failed
+func recordLoginFailure(ctx context.Context, attempt *throttle.Attempt, logger *logger.ServiceLogger) {
+	if _, err := attempt.Fail(ctx); err != nil {
+		logger.Errorf("Failed to record login failure: %v", err)
+	}
+}
+
+// recordLoginSuccess forgets the failed logins of the account of a login attempt
+func recordLoginSuccess(ctx context.Context, attempt *throttle.Attempt, logger *logger.ServiceLogger) {
+	if err := attempt.Succeed(ctx); err != nil {
+		logger.Errorf("Failed to clear login failures: %v", err)
+	}
+}
+
+// releaseLoginAttempt takes back a login attempt that neither failed nor
+// succeeded, it does nothing once the attempt has ended
+func releaseLoginAttempt(ctx context.Context, attempt *throttle.Attempt, logger *logger.ServiceLogger) {
+	if err := attempt.Release(ctx); err != nil {
+		logger.Errorf("Failed to release login attempt: %v", err)
+	}
+}
+
+// writeLoginChallenge answers a login of a user with two-factor authentication
+// with a challenge token for /api/auth/login/2fa instead of a session
+func writeLoginChallenge(ctx context.Context, w http.ResponseWriter, database db.Store, logger *logger.ServiceLogger, user *model.User) {
+	challenge, hash, err := auth.GenerateToken()
+	if err != nil {
+		logger.Errorf("Failed to generate login challenge: %v", err)
+		writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := database.CreateLoginChallenge(ctx, user.ID, hash, time.Now().UTC().Add(loginChallengeTTL)); err != nil {
+		logger.Debugf("Login challenge creation failed: %v", err)
+		writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
+
+	writeJSONResponse(w, LoginResponse{
+		Success:           true,
+		Message:           "Enter the code from your authenticator app",
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge,
+	}, http.StatusOK)
+}
+
+// issueSession completes a login by setting the JWT session of user on the response
+func issueSession(w http.ResponseWriter, r *http.Request, sessions *auth.Sessions, logger *logger.ServiceLogger, user *model.User) {
+	if _, err := sessions.Issue(w, user, r.URL.Query().Get("sess") == "1"); err != nil {
+		logger.Errorf("Failed to issue session for user %s: %v", user.ID, err)
+		writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Infof("User logged in successfully: %s", user.Email)
+
+	writeJSONResponse(w, LoginResponse{
+		Success: true,
+		UserID:  user.ID,
+		Message: "Login successful",
+	}, http.StatusOK)
 }
 
 // Helper functions
@@ -201,11 +348,8 @@ func validateSignupRequest(req SignupRequest) error {
 	if strings.TrimSpace(req.Email) == "" {
 		return fmt.Errorf("email is required")
 	}
-	if strings.TrimSpace(req.Password) == "" {
-		return fmt.Errorf("password is required")
-	}
-	if len(strings.TrimSpace(req.Password)) < 6 {
-		return fmt.Errorf("password must be at least 6 characters long")
+	if err := validatePassword(req.Password); err != nil {
+		return err
 	}
 	if strings.TrimSpace(req.FullName) == "" {
 		return fmt.Errorf("full name is required")
@@ -213,15 +357,42 @@ func validateSignupRequest(req SignupRequest) error {
 	if strings.TrimSpace(req.Timezone) == "" {
 		return fmt.Errorf("timezone is required")
 	}
+	if _, err := schedule.LoadLocation(req.Timezone); err != nil {
+		return fmt.Errorf("timezone must be a valid IANA timezone name")
+	}
 
-	// Basic email validation
-	if !strings.Contains(req.Email, "@") || !strings.Contains(req.Email, ".") {
+	return validateEmail(req.Email)
+}
+
+// validateEmail applies the basic email format check shared by signup and email changes
+func validateEmail(email string) error {
+	if !strings.Contains(email, "@") || !strings.Contains(email, ".") {
 		return fmt.Errorf("invalid email format")
 	}
+	return nil
+}
 
+// validatePassword applies the password rules shared by signup and password changes
+func validatePassword(password string) error {
+	if strings.TrimSpace(password) == "" {
+		return fmt.Errorf("password is required")
+	}
+	if len(strings.TrimSpace(password)) < 6 {
+		return fmt.Errorf("password must be at least 6 characters long")
+	}
 	return nil
 }
 
+// writeRegistrationError writes the response for a sign up the registration policy did not admit
+func writeRegistrationError(w http.ResponseWriter, logger *logger.ServiceLogger, err error) {
+	if registration.Refused(err) {
+		writeErrorResponse(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	logger.Debugf("Registration check failed: %v", err)
+	writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+}
+
 func writeErrorResponse(w http.ResponseWriter, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -233,3 +404,36 @@ func writeErrorResponse(w http.ResponseWriter, message string, statusCode int) {
 
 	json.NewEncoder(w).Encode(response)
 }
+
+func writeJSONResponse(w http.ResponseWriter, payload interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// requestLogger returns the logger the access log middleware put into the
+// request context, so handler log lines carry the request ID, or fallback
+func requestLogger(r *http.Request, fallback *logger.ServiceLogger) *logger.ServiceLogger {
+	return logger.FromContext(r.Context(), fallback)
+}
+
+// requireUserID extracts the authenticated user ID, writing a 401 response if missing
+func requireUserID(w http.ResponseWriter, r *http.Request, logger *logger.ServiceLogger) (uuid.UUID, bool) {
+	userID, err := auth.UserIDFromRequest(r)
+	if err != nil {
+		logger.Debugf("Unauthenticated request to %s: %v", r.URL.Path, err)
+		writeErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// pathUUID parses a UUID path value, writing a 400 response if it is invalid
+func pathUUID(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		writeErrorResponse(w, "Invalid resource ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
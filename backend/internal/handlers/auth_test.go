@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/db/dbtest"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/mail"
+	"github.com/anish-chanda/ferna/internal/registration"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// createTestUser stores a local account for email
+func createTestUser(t *testing.T, database db.Store, email string) *model.User {
+	t.Helper()
+	hash := "unused"
+	user := &model.User{
+		ID:           uuid.New(),
+		AuthProvider: model.AuthProviderLocal,
+		Email:        email,
+		FullName:     "User",
+		PasswordHash: &hash,
+		Timezone:     "UTC",
+	}
+	if _, err := database.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	return user
+}
+
+// signup posts a sign up for email to a SignupHandler in the given mode and
+// returns the status and message of the response
+func signup(t *testing.T, database db.Store, mode registration.Mode, email, inviteCode string) (int, string) {
+	t.Helper()
+	log := logger.New(logger.Config{Level: "error"})
+	policy, err := registration.NewPolicy(database, registration.Config{Mode: mode}, log)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	mailer, err := mail.NewMailer(database, mail.Config{Interval: time.Minute, BatchSize: 1, MaxAttempts: 1}, mail.NewLogSender(log), log)
+	if err != nil {
+		t.Fatalf("NewMailer failed: %v", err)
+	}
+	handler := SignupHandler(database, policy, mailer, "https://ferna.example/verify", time.Hour, log)
+
+	body, _ := json.Marshal(SignupRequest{
+		Email:      email,
+		Password:   "password",
+		FullName:   "User",
+		Timezone:   "UTC",
+		InviteCode: inviteCode,
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/auth/signup", strings.NewReader(string(body))))
+
+	var response struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return rec.Code, response.Message
+}
+
+// TestSignupRefusalHidesRegisteredEmails checks that a sign up the policy
+// refuses gets the same answer whether or not the email is registered
+func TestSignupRefusalHidesRegisteredEmails(t *testing.T) {
+	database := dbtest.NewSQLite(t)
+	createTestUser(t, database, "user@example.com")
+
+	tests := []struct {
+		mode registration.Mode
+		want error
+	}{
+		{registration.ModeClosed, registration.ErrClosed},
+		{registration.ModeInvite, registration.ErrInviteRequired},
+	}
+	for _, tt := range tests {
+		for _, email := range []string{"user@example.com", "new@example.com"} {
+			status, message := signup(t, database, tt.mode, email, "")
+			if status != http.StatusForbidden || message != tt.want.Error() {
+				t.Errorf("%s mode sign up of %s = %d %q, want %d %q", tt.mode, email, status, message, http.StatusForbidden, tt.want)
+			}
+		}
+	}
+}
+
+// TestSignupRegisteredEmailReleasesInvite checks that an invite code redeemed
+// for an email that turns out to be registered can still be used
+func TestSignupRegisteredEmailReleasesInvite(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.NewSQLite(t)
+	admin := createTestUser(t, database, "admin@example.com")
+
+	maxUses := 1
+	code, invite, err := registration.NewInviteCode(admin.ID, nil, &maxUses, nil)
+	if err != nil {
+		t.Fatalf("NewInviteCode failed: %v", err)
+	}
+	if err := database.CreateInviteCode(ctx, invite); err != nil {
+		t.Fatalf("CreateInviteCode failed: %v", err)
+	}
+
+	if status, message := signup(t, database, registration.ModeInvite, "admin@example.com", code); status != http.StatusConflict {
+		t.Fatalf("sign up of a registered email = %d %q, want %d", status, message, http.StatusConflict)
+	}
+	if status, message := signup(t, database, registration.ModeInvite, "new@example.com", code); status != http.StatusCreated {
+		t.Fatalf("sign up with the released invite = %d %q, want %d", status, message, http.StatusCreated)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// maxDeviceTokenLength bounds the size of a push token
+const maxDeviceTokenLength = 4096
+
+// DeviceRequest is the body for registering or unregistering a push token
+type DeviceRequest struct {
+	Token    string               `json:"token"`
+	Platform model.DevicePlatform `json:"platform"`
+}
+
+type DeviceResponse struct {
+	Success bool          `json:"success"`
+	Device  *model.Device `json:"device"`
+}
+
+// RegisterDeviceHandler registers the push token of an app install for the authenticated user.
+// Registering the same token again refreshes it.
+func RegisterDeviceHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req DeviceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in register device request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		token, err := validateDeviceToken(req.Token)
+		if err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !req.Platform.Valid() {
+			writeErrorResponse(w, "platform must be one of: android, ios, web", http.StatusBadRequest)
+			return
+		}
+
+		device := &model.Device{
+			ID:       uuid.New(),
+			UserID:   userID,
+			Token:    token,
+			Platform: req.Platform,
+		}
+		if err := database.UpsertDevice(ctx, device); err != nil {
+			logger.Debugf("Device registration failed: %v", err)
+			writeErrorResponse(w, "Failed to register device", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, DeviceResponse{Success: true, Device: device}, http.StatusOK)
+	}
+}
+
+// UnregisterDeviceHandler removes a push token of the authenticated user, e.g. on logout
+func UnregisterDeviceHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req DeviceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in unregister device request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		token, err := validateDeviceToken(req.Token)
+		if err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := database.DeleteDevice(ctx, userID, token)
+		if err != nil {
+			logger.Debugf("Device deletion failed: %v", err)
+			writeErrorResponse(w, "Failed to unregister device", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			writeErrorResponse(w, "Device not found", http.StatusNotFound)
+			return
+		}
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Device unregistered",
+		}, http.StatusOK)
+	}
+}
+
+// validateDeviceToken trims a push token and checks its length
+func validateDeviceToken(token string) (string, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("token is required")
+	}
+	if len(token) > maxDeviceTokenLength {
+		return "", fmt.Errorf("token must be at most %d characters long", maxDeviceTokenLength)
+	}
+	return token, nil
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTimelineLimit = 50
+	maxTimelineLimit     = 100
+	// maxCareEventPayload bounds the size of the free-form event payload
+	maxCareEventPayload = 16 * 1024
+)
+
+// CareEventRequest is the body for logging an event on a plant
+type CareEventRequest struct {
+	Type       model.CareEventType `json:"type"`
+	OccurredAt *time.Time          `json:"occurred_at"`
+	Payload    json.RawMessage     `json:"payload"`
+}
+
+type CareEventResponse struct {
+	Success bool             `json:"success"`
+	Event   *model.CareEvent `json:"event"`
+}
+
+type TimelineResponse struct {
+	Success    bool               `json:"success"`
+	Events     []*model.CareEvent `json:"events"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// CreateCareEventHandler appends an event such as a watering or a note to a plant's timeline
+func CreateCareEventHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		plant, ok := requirePlant(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		var req CareEventRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in create care event request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if !req.Type.Valid() {
+			writeErrorResponse(w, fmt.Sprintf("invalid type %q", req.Type), http.StatusBadRequest)
+			return
+		}
+		if req.Type == model.CareEventPhoto {
+			writeErrorResponse(w, "photo events are created by uploading a photo", http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now().UTC()
+		occurredAt := now
+		if req.OccurredAt != nil {
+			occurredAt = req.OccurredAt.UTC()
+			if occurredAt.After(now) {
+				writeErrorResponse(w, "occurred_at cannot be in the future", http.StatusBadRequest)
+				return
+			}
+		}
+
+		payload := bytes.TrimSpace(req.Payload)
+		if len(payload) == 0 || bytes.Equal(payload, []byte("null")) {
+			payload = []byte("{}")
+		}
+		if len(payload) > maxCareEventPayload {
+			writeErrorResponse(w, fmt.Sprintf("payload must be at most %d bytes", maxCareEventPayload), http.StatusBadRequest)
+			return
+		}
+		if payload[0] != '{' {
+			writeErrorResponse(w, "payload must be a JSON object", http.StatusBadRequest)
+			return
+		}
+
+		event := &model.CareEvent{
+			ID:         uuid.New(),
+			PlantID:    plant.ID,
+			UserID:     userID,
+			Type:       req.Type,
+			OccurredAt: occurredAt,
+			Payload:    payload,
+		}
+		if err := database.CreateCareEvent(ctx, event); err != nil {
+			logger.Debugf("Care event creation failed: %v", err)
+			writeErrorResponse(w, "Failed to create care event", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, CareEventResponse{Success: true, Event: event}, http.StatusCreated)
+	}
+}
+
+// PlantTimelineHandler returns a page of a plant's history, newest first.
+// Query parameters: limit, cursor (from next_cursor of the previous page) and
+// type, which may be repeated or comma separated.
+func PlantTimelineHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		plant, ok := requirePlant(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		filter, err := parseTimelineQuery(r)
+		if err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit := filter.Limit
+		filter.Limit++ // fetch one extra row to know whether there is a next page
+
+		events, err := database.ListCareEvents(ctx, userID, plant.ID, filter)
+		if err != nil {
+			logger.Debugf("Database error listing care events: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		response := TimelineResponse{Success: true, Events: events}
+		if len(events) > limit {
+			response.Events = events[:limit]
+			last := response.Events[limit-1]
+			response.NextCursor = encodeTimelineCursor(last.OccurredAt, last.ID)
+		}
+
+		writeJSONResponse(w, response, http.StatusOK)
+	}
+}
+
+// parseTimelineQuery reads the limit, cursor and type query parameters
+func parseTimelineQuery(r *http.Request) (model.CareEventFilter, error) {
+	query := r.URL.Query()
+	filter := model.CareEventFilter{Limit: defaultTimelineLimit}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxTimelineLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxTimelineLimit)
+		}
+		filter.Limit = limit
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		occurredAt, id, err := decodeTimelineCursor(raw)
+		if err != nil {
+			return filter, fmt.Errorf("invalid cursor")
+		}
+		filter.BeforeOccurredAt = occurredAt
+		filter.BeforeID = id
+	}
+
+	for _, value := range query["type"] {
+		for _, raw := range strings.Split(value, ",") {
+			t := model.CareEventType(strings.TrimSpace(raw))
+			if t == "" {
+				continue
+			}
+			if !t.Valid() {
+				return filter, fmt.Errorf("invalid type %q", t)
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
+
+	return filter, nil
+}
+
+// encodeTimelineCursor returns an opaque cursor pointing just past the given event
+func encodeTimelineCursor(occurredAt time.Time, id uuid.UUID) string {
+	raw := occurredAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeTimelineCursor parses a cursor created by encodeTimelineCursor
+func decodeTimelineCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	ts, rawID, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, fmt.Errorf("malformed cursor")
+	}
+	occurredAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	return occurredAt, id, nil
+}
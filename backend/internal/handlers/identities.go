@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/auth"
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/throttle"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+type LinkIdentityRequest struct {
+	Password string `json:"password"`
+	// Code is a TOTP or recovery code, required if the account has two-factor authentication
+	Code string `json:"code,omitempty"`
+}
+
+// LinkIdentityHandler links the OAuth identity of the calling token to the local
+// account that already uses its email. The account password proves ownership,
+// so a provider account with the same email cannot take over the account.
+// Accounts with two-factor authentication need their second factor as well,
+// otherwise the linked identity would sign in without it. Guesses of either
+// are throttled like logins.
+func LinkIdentityHandler(database db.Store, throttler *throttle.Throttler, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		link, err := auth.PendingLinkFromRequest(r)
+		if err != nil {
+			logger.Debugf("Link request without pending identity: %v", err)
+			writeErrorResponse(w, "No account link pending, sign in with Google or Facebook first", http.StatusBadRequest)
+			return
+		}
+		provider := model.AuthProvider(link.Provider)
+		// An unverified provider email says nothing about who owns the account
+		if !link.EmailVerified {
+			logger.Debugf("Refusing to link %s identity with unverified email %s", provider, link.Email)
+			writeErrorResponse(w, "Your "+link.Provider+" email address is not verified", http.StatusForbidden)
+			return
+		}
+
+		var req LinkIdentityRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in link request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		if req.Password == "" {
+			writeErrorResponse(w, "password is required", http.StatusBadRequest)
+			return
+		}
+
+		linked, err := database.GetUserByIdentity(ctx, provider, link.Subject)
+		if err != nil {
+			logger.Debugf("Database error getting user by identity: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if linked != nil {
+			writeErrorResponse(w, "This account is already linked, sign in again", http.StatusConflict)
+			return
+		}
+
+		user, err := database.GetUserByEmail(ctx, link.Email)
+		if err != nil {
+			logger.Debugf("Database error getting user: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil || user.AuthProvider != model.AuthProviderLocal || user.PasswordHash == nil {
+			writeErrorResponse(w, "No account with a password uses this email, sign in with your existing login instead", http.StatusConflict)
+			return
+		}
+
+		attempt, ok := beginLoginAttempt(ctx, w, throttler, logger, throttler.ClientIP(r), user.Email)
+		if !ok {
+			return
+		}
+		defer releaseLoginAttempt(ctx, attempt, logger)
+
+		valid, err := auth.VerifyPassword(ctx, req.Password, *user.PasswordHash)
+		if err != nil {
+			logger.Debugf("Password verification error: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			logger.Debugf("Invalid password linking %s identity to user %s", provider, user.ID)
+			recordLoginFailure(ctx, attempt, logger)
+			writeErrorResponse(w, "Invalid password", http.StatusUnauthorized)
+			return
+		}
+
+		totp, err := database.GetTOTP(ctx, user.ID)
+		if err != nil {
+			logger.Debugf("Database error getting TOTP: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if totp.Enabled() {
+			if strings.TrimSpace(req.Code) == "" {
+				writeErrorResponse(w, "code is required, enter the code from your authenticator app", http.StatusUnauthorized)
+				return
+			}
+			valid, err := verifySecondFactor(ctx, database, totp, req.Code, true)
+			if err != nil {
+				logger.Debugf("Second factor verification failed: %v", err)
+				writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if !valid {
+				logger.Debugf("Invalid second factor linking %s identity to user %s", provider, user.ID)
+				recordLoginFailure(ctx, attempt, logger)
+				writeErrorResponse(w, "Invalid code", http.StatusUnauthorized)
+				return
+			}
+		}
+		recordLoginSuccess(ctx, attempt, logger)
+
+		identity := &model.UserIdentity{
+			ID:       uuid.New(),
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  link.Subject,
+			Email:    &link.Email,
+		}
+		if err := database.LinkIdentity(ctx, identity); err != nil {
+			logger.Debugf("Identity link failed: %v", err)
+			writeErrorResponse(w, "Failed to link account", http.StatusInternalServerError)
+			return
+		}
+
+		// The current token carries no user ID, the next login resolves the identity
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Account linked, sign in again to continue",
+		}, http.StatusOK)
+	}
+}
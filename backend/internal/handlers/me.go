@@ -0,0 +1,444 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/account"
+	"github.com/anish-chanda/ferna/internal/auth"
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/mail"
+	"github.com/anish-chanda/ferna/internal/schedule"
+	"github.com/anish-chanda/ferna/internal/throttle"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxFullNameLength bounds the display name of a user
+	maxFullNameLength = 200
+	// maxAvatarURLLength bounds the avatar link of a user
+	maxAvatarURLLength = 2048
+)
+
+// UpdateProfileRequest holds the profile fields to change, absent fields are left as they are
+type UpdateProfileRequest struct {
+	FullName  *string `json:"full_name"`
+	Timezone  *string `json:"timezone"`
+	AvatarURL *string `json:"avatar_url"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type ProfileResponse struct {
+	Success     bool        `json:"success"`
+	User        *model.User `json:"user"`
+	HasPassword bool        `json:"has_password"`
+}
+
+// GetProfileHandler returns the profile of the authenticated user
+func GetProfileHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		user, ok := requireUser(ctx, w, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		writeJSONResponse(w, newProfileResponse(user), http.StatusOK)
+	}
+}
+
+// UpdateProfileHandler changes the name, timezone or avatar of the authenticated
+// user. A new timezone moves the pending occurrence of every care schedule to
+// the same wall-clock time in that timezone.
+func UpdateProfileHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req UpdateProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in update profile request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireUser(ctx, w, database, logger, userID)
+		if !ok {
+			return
+		}
+		// Schedules were computed in the stored timezone, or in UTC if it was never valid
+		oldLoc, _ := schedule.LoadLocation(user.Timezone)
+
+		newLoc, err := applyProfileRequest(user, req)
+		if err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var relocate func(*model.CareSchedule) error
+		if newLoc != nil && newLoc.String() != oldLoc.String() {
+			relocate = func(s *model.CareSchedule) error {
+				from, err := schedule.SeriesFor(s, oldLoc)
+				if err != nil {
+					return fmt.Errorf("invalid rule on care schedule %s: %w", s.ID, err)
+				}
+				to, err := schedule.SeriesFor(s, newLoc)
+				if err != nil {
+					return fmt.Errorf("invalid rule on care schedule %s: %w", s.ID, err)
+				}
+				setCareScheduleState(s, schedule.Relocate(from, to, careScheduleState(s)))
+				return nil
+			}
+		}
+
+		updated, err := database.UpdateUserProfile(ctx, user, relocate)
+		if err != nil {
+			logger.Debugf("Profile update failed: %v", err)
+			writeErrorResponse(w, "Failed to update profile", http.StatusInternalServerError)
+			return
+		}
+		if !updated {
+			writeErrorResponse(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		writeJSONResponse(w, newProfileResponse(user), http.StatusOK)
+	}
+}
+
+// ChangePasswordHandler sets a new password after checking the current one.
+// Every session, including the current one, has to sign in again afterwards.
+func ChangePasswordHandler(database db.Store, throttler *throttle.Throttler, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req ChangePasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in change password request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		if err := validatePassword(req.NewPassword); err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireUser(ctx, w, database, logger, userID)
+		if !ok {
+			return
+		}
+		if user.AuthProvider != model.AuthProviderLocal || user.PasswordHash == nil {
+			writeErrorResponse(w, "This account signs in through "+string(user.AuthProvider)+" and has no password", http.StatusConflict)
+			return
+		}
+		if !checkCurrentPassword(ctx, w, r, throttler, logger, user, req.CurrentPassword) {
+			return
+		}
+
+		hashedPassword, err := auth.HashPassword(ctx, req.NewPassword)
+		if err != nil {
+			logger.Debugf("Password hashing failed: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if err := database.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+			logger.Debugf("Password change failed: %v", err)
+			writeErrorResponse(w, "Failed to change password", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Password changed, sign in again with the new password",
+		}, http.StatusOK)
+	}
+}
+
+// ChangeEmailHandler starts an email change by sending a verification link to
+// the new address. The account keeps its current email until the link is used.
+func ChangeEmailHandler(database db.Store, mailer *mail.Mailer, verifyURL string, ttl time.Duration, throttler *throttle.Throttler, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req ChangeEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in change email request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		email := strings.TrimSpace(strings.ToLower(req.Email))
+		if email == "" {
+			writeErrorResponse(w, "email is required", http.StatusBadRequest)
+			return
+		}
+		if err := validateEmail(email); err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireUser(ctx, w, database, logger, userID)
+		if !ok {
+			return
+		}
+		if email == user.Email {
+			writeErrorResponse(w, "This is already your email address", http.StatusBadRequest)
+			return
+		}
+		// Accounts without a password only have their session to prove ownership
+		if user.PasswordHash != nil && !checkCurrentPassword(ctx, w, r, throttler, logger, user, req.Password) {
+			return
+		}
+
+		exists, err := database.CheckIfEmailExists(ctx, email)
+		if err != nil {
+			logger.Debugf("Database error checking email existence: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			writeErrorResponse(w, "Email already registered", http.StatusConflict)
+			return
+		}
+
+		if err := sendEmailVerification(ctx, database, mailer, verifyURL, ttl, user, email); err != nil {
+			logger.Errorf("Failed to send email change verification for user %s: %v", user.ID, err)
+			writeErrorResponse(w, "Failed to send verification email", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "A verification link has been sent to the new address, your email changes once it is confirmed",
+		}, http.StatusAccepted)
+	}
+}
+
+// applyProfileRequest validates the fields present in req and copies them onto
+// user. It returns the new location if the timezone is changed.
+func applyProfileRequest(user *model.User, req UpdateProfileRequest) (*time.Location, error) {
+	if req.FullName != nil {
+		name := strings.TrimSpace(*req.FullName)
+		if name == "" {
+			return nil, fmt.Errorf("full name is required")
+		}
+		if len(name) > maxFullNameLength {
+			return nil, fmt.Errorf("full name must be at most %d characters long", maxFullNameLength)
+		}
+		user.FullName = name
+	}
+
+	if req.AvatarURL != nil {
+		avatarURL := optionalString(*req.AvatarURL)
+		if avatarURL != nil {
+			if len(*avatarURL) > maxAvatarURLLength {
+				return nil, fmt.Errorf("avatar_url must be at most %d characters long", maxAvatarURLLength)
+			}
+			u, err := url.Parse(*avatarURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("avatar_url must be an http or https URL")
+			}
+		}
+		user.AvatarURL = avatarURL
+	}
+
+	var loc *time.Location
+	if req.Timezone != nil {
+		var err error
+		loc, err = schedule.LoadLocation(*req.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("timezone must be a valid IANA timezone name")
+		}
+		user.Timezone = loc.String()
+	}
+	return loc, nil
+}
+
+// requireUser loads the user with the given ID, writing an error response if it is missing
+func requireUser(ctx context.Context, w http.ResponseWriter, database db.Store, logger *logger.ServiceLogger, userID uuid.UUID) (*model.User, bool) {
+	user, err := database.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.Debugf("Database error getting user %s: %v", userID, err)
+		writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if user == nil {
+		writeErrorResponse(w, "User not found", http.StatusNotFound)
+		return nil, false
+	}
+	return user, true
+}
+
+// checkCurrentPassword verifies password against the user's password hash,
+// writing an error response if it does not match. Guesses count towards the
+// login throttle, so a stolen session cannot be used to brute force the password.
+func checkCurrentPassword(ctx context.Context, w http.ResponseWriter, r *http.Request, throttler *throttle.Throttler, logger *logger.ServiceLogger, user *model.User, password string) bool {
+	if password == "" || user.PasswordHash == nil {
+		writeErrorResponse(w, "Current password is incorrect", http.StatusUnauthorized)
+		return false
+	}
+
+	attempt, ok := beginLoginAttempt(ctx, w, throttler, logger, throttler.ClientIP(r), user.Email)
+	if !ok {
+		return false
+	}
+	defer releaseLoginAttempt(ctx, attempt, logger)
+
+	valid, err := auth.VerifyPassword(ctx, password, *user.PasswordHash)
+	if err != nil {
+		logger.Debugf("Password verification error: %v", err)
+		writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if !valid {
+		logger.Debugf("Invalid current password for user %s", user.ID)
+		recordLoginFailure(ctx, attempt, logger)
+		writeErrorResponse(w, "Current password is incorrect", http.StatusUnauthorized)
+		return false
+	}
+	recordLoginSuccess(ctx, attempt, logger)
+	return true
+}
+
+// newProfileResponse wraps a user for the profile endpoints without its password hash
+func newProfileResponse(user *model.User) ProfileResponse {
+	profile := *user
+	profile.PasswordHash = nil
+	return ProfileResponse{Success: true, User: &profile, HasPassword: user.PasswordHash != nil}
+}
+
+// DeleteAccountRequest confirms an account deletion with the current password
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccountHandler deletes the account of the authenticated user with all
+// its data. With a grace period the deletion is only scheduled and can be
+// cancelled until then, otherwise the account is erased right away.
+func DeleteAccountHandler(database db.Store, eraser *account.Eraser, throttler *throttle.Throttler, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req DeleteAccountRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				logger.Debugf("Invalid JSON in delete account request: %v", err)
+				writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+				return
+			}
+		}
+
+		user, ok := requireUser(ctx, w, database, logger, userID)
+		if !ok {
+			return
+		}
+		// Accounts without a password only have their session to prove ownership
+		if user.PasswordHash != nil && !checkCurrentPassword(ctx, w, r, throttler, logger, user, req.Password) {
+			return
+		}
+
+		scheduledAt, err := eraser.Schedule(ctx, userID)
+		if err != nil {
+			if errors.Is(err, account.ErrUserNotFound) {
+				writeErrorResponse(w, "User not found", http.StatusNotFound)
+				return
+			}
+			logger.Errorf("Account deletion failed for user %s: %v", userID, err)
+			writeErrorResponse(w, "Failed to delete account", http.StatusInternalServerError)
+			return
+		}
+
+		if scheduledAt == nil {
+			writeJSONResponse(w, map[string]interface{}{
+				"success": true,
+				"message": "Account deleted",
+			}, http.StatusOK)
+			return
+		}
+		writeJSONResponse(w, map[string]interface{}{
+			"success":               true,
+			"message":               "Account scheduled for deletion, it can be restored until then",
+			"deletion_scheduled_at": scheduledAt,
+		}, http.StatusAccepted)
+	}
+}
+
+// CancelAccountDeletionHandler withdraws a scheduled deletion of the authenticated user's account
+func CancelAccountDeletionHandler(eraser *account.Eraser, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		cancelled, err := eraser.Cancel(ctx, userID)
+		if err != nil {
+			logger.Debugf("Cancelling account deletion failed: %v", err)
+			writeErrorResponse(w, "Failed to cancel account deletion", http.StatusInternalServerError)
+			return
+		}
+		if !cancelled {
+			writeErrorResponse(w, "No account deletion is scheduled", http.StatusNotFound)
+			return
+		}
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Account deletion cancelled",
+		}, http.StatusOK)
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/auth"
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/mail"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ForgotPasswordHandler emails a password reset link to a local account. The
+// response is the same whether or not the email belongs to an account, and the
+// lookup runs in the background so response times do not reveal it either.
+func ForgotPasswordHandler(database db.Store, mailer *mail.Mailer, resetURL string, ttl time.Duration, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		var req ForgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in forgot password request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		email := strings.TrimSpace(strings.ToLower(req.Email))
+		if email == "" {
+			writeErrorResponse(w, "email is required", http.StatusBadRequest)
+			return
+		}
+
+		go sendPasswordReset(database, mailer, resetURL, ttl, logger, email)
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "If an account exists for this email, a password reset link has been sent",
+		}, http.StatusAccepted)
+	}
+}
+
+// sendPasswordReset creates a reset token for the local account behind email and queues the reset email
+func sendPasswordReset(database db.Store, mailer *mail.Mailer, resetURL string, ttl time.Duration, logger *logger.ServiceLogger, email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := database.GetUserByEmail(ctx, email)
+	if err != nil {
+		logger.Errorf("Database error getting user for password reset: %v", err)
+		return
+	}
+	if user == nil || user.AuthProvider != model.AuthProviderLocal || user.PasswordHash == nil {
+		logger.Debugf("Password reset requested for unknown or non-local account")
+		return
+	}
+
+	token, tokenHash, err := auth.GenerateToken()
+	if err != nil {
+		logger.Errorf("Failed to generate reset token: %v", err)
+		return
+	}
+	if err := database.CreatePasswordResetToken(ctx, user.ID, tokenHash, time.Now().UTC().Add(ttl)); err != nil {
+		logger.Errorf("Failed to store reset token: %v", err)
+		return
+	}
+
+	err = mailer.Enqueue(ctx, user.Email, mail.TemplatePasswordReset, map[string]any{
+		"Name":      user.FullName,
+		"Link":      tokenLink(resetURL, token),
+		"ExpiresIn": mail.HumanDuration(ttl),
+	})
+	if err != nil {
+		logger.Errorf("Failed to queue password reset email: %v", err)
+		return
+	}
+	logger.Infof("Password reset requested for user %s", user.ID)
+}
+
+// ResetPasswordHandler sets a new password using a reset token and signs the user out everywhere
+func ResetPasswordHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		var req ResetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in reset password request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		token := strings.TrimSpace(req.Token)
+		if token == "" {
+			writeErrorResponse(w, "token is required", http.StatusBadRequest)
+			return
+		}
+		if err := validatePassword(req.Password); err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Check the token before spending time on hashing the new password
+		tokenHash := auth.HashToken(token)
+		userID, err := database.GetPasswordResetUserID(ctx, tokenHash)
+		if err != nil {
+			logger.Debugf("Database error looking up reset token: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if userID == uuid.Nil {
+			writeErrorResponse(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+
+		passwordHash, err := auth.HashPassword(ctx, req.Password)
+		if err != nil {
+			logger.Debugf("Password hashing failed: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		reset, err := database.ResetPassword(ctx, tokenHash, passwordHash)
+		if err != nil {
+			logger.Debugf("Password reset failed: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !reset {
+			writeErrorResponse(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Password has been reset, please log in again",
+		}, http.StatusOK)
+	}
+}
+
+// tokenLink appends a token query parameter to base
+func tokenLink(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/photo"
+	"github.com/anish-chanda/ferna/internal/storage"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxPhotoCaptionLength bounds the caption sent alongside a photo
+	maxPhotoCaptionLength = 1000
+	// multipartOverhead is allowed on top of the photo size for headers and other fields
+	multipartOverhead = 64 * 1024
+	// photoTransferTimeout bounds uploading or downloading a photo. It replaces
+	// the server's read and write timeouts, which are too short for slow clients.
+	photoTransferTimeout = 60 * time.Second
+)
+
+type PhotoResponse struct {
+	Success bool         `json:"success"`
+	Photo   *model.Photo `json:"photo"`
+}
+
+type PhotoListResponse struct {
+	Success bool           `json:"success"`
+	Photos  []*model.Photo `json:"photos"`
+}
+
+// UploadPhotoHandler accepts a multipart upload with a "photo" file field and an
+// optional "caption" field. The image is sniffed, stripped of metadata and
+// stored with a thumbnail, and a photo event is added to the plant's timeline.
+func UploadPhotoHandler(database db.Store, store storage.BlobStore, maxUploadBytes int64, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithDeadline(r.Context(), extendDeadlines(w, logger))
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		plant, ok := requirePlant(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes+multipartOverhead)
+		data, caption, err := readPhotoUpload(r, maxUploadBytes)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.Is(err, errPhotoTooLarge) || errors.As(err, &maxBytesErr) {
+				writeErrorResponse(w, fmt.Sprintf("photo must be at most %d bytes", maxUploadBytes), http.StatusRequestEntityTooLarge)
+				return
+			}
+			logger.Debugf("Invalid photo upload: %v", err)
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		processed, err := photo.Process(ctx, data)
+		if err != nil {
+			if errors.Is(err, photo.ErrUnsupportedType) {
+				writeErrorResponse(w, err.Error(), http.StatusUnsupportedMediaType)
+				return
+			}
+			if ctx.Err() != nil {
+				logger.Warnf("Timed out waiting to process photo for plant %s", plant.ID)
+				writeErrorResponse(w, "Too many photos are being processed, try again later", http.StatusServiceUnavailable)
+				return
+			}
+			logger.Debugf("Photo processing failed: %v", err)
+			writeErrorResponse(w, "Invalid image", http.StatusBadRequest)
+			return
+		}
+
+		photoID := uuid.New()
+		ext := "jpg"
+		if processed.ContentType == "image/png" {
+			ext = "png"
+		}
+		prefix := fmt.Sprintf("photos/%s/%s/%s", userID, plant.ID, photoID)
+		record := &model.Photo{
+			ID:           photoID,
+			PlantID:      plant.ID,
+			UserID:       userID,
+			BlobKey:      prefix + "." + ext,
+			ThumbnailKey: prefix + "_thumb.jpg",
+			ContentType:  processed.ContentType,
+			Width:        processed.Width,
+			Height:       processed.Height,
+			SizeBytes:    int64(len(processed.Data)),
+			Caption:      caption,
+		}
+
+		if err := store.Put(ctx, record.BlobKey, bytes.NewReader(processed.Data), record.SizeBytes, record.ContentType); err != nil {
+			logger.Errorf("Failed to store photo %s: %v", photoID, err)
+			writeErrorResponse(w, "Failed to store photo", http.StatusInternalServerError)
+			return
+		}
+		if err := store.Put(ctx, record.ThumbnailKey, bytes.NewReader(processed.Thumbnail), int64(len(processed.Thumbnail)), "image/jpeg"); err != nil {
+			logger.Errorf("Failed to store thumbnail for photo %s: %v", photoID, err)
+			deletePhotoBlobs(store, logger, record)
+			writeErrorResponse(w, "Failed to store photo", http.StatusInternalServerError)
+			return
+		}
+
+		payload, _ := json.Marshal(map[string]interface{}{
+			"photo_id": photoID,
+			"caption":  caption,
+		})
+		event := &model.CareEvent{
+			ID:         uuid.New(),
+			PlantID:    plant.ID,
+			UserID:     userID,
+			Type:       model.CareEventPhoto,
+			OccurredAt: time.Now().UTC(),
+			Payload:    payload,
+		}
+
+		if err := database.CreatePhoto(ctx, record, event); err != nil {
+			logger.Debugf("Photo creation failed: %v", err)
+			deletePhotoBlobs(store, logger, record)
+			writeErrorResponse(w, "Failed to create photo", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Debugf("Photo %s uploaded for plant %s", photoID, plant.ID)
+		writeJSONResponse(w, PhotoResponse{Success: true, Photo: record}, http.StatusCreated)
+	}
+}
+
+// ListPhotosHandler returns the photos of a plant, newest first
+func ListPhotosHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		plant, ok := requirePlant(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		photos, err := database.ListPhotosByPlant(ctx, userID, plant.ID)
+		if err != nil {
+			logger.Debugf("Database error listing photos: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, PhotoListResponse{Success: true, Photos: photos}, http.StatusOK)
+	}
+}
+
+// PhotoContentHandler streams the image data of a photo, or its thumbnail when thumbnail is true
+func PhotoContentHandler(database db.Store, store storage.BlobStore, thumbnail bool, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithDeadline(r.Context(), extendDeadlines(w, logger))
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		record, ok := requirePhoto(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		key, contentType := record.BlobKey, record.ContentType
+		if thumbnail {
+			key, contentType = record.ThumbnailKey, "image/jpeg"
+		}
+
+		blob, err := store.Get(ctx, key)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				logger.Warnf("Blob %s of photo %s is missing", key, record.ID)
+				writeErrorResponse(w, "Photo not found", http.StatusNotFound)
+				return
+			}
+			logger.Errorf("Failed to read photo %s: %v", record.ID, err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer blob.Close()
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "private, max-age=86400, immutable")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if !thumbnail {
+			w.Header().Set("Content-Length", strconv.FormatInt(record.SizeBytes, 10))
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, blob); err != nil {
+			logger.Debugf("Failed to stream photo %s: %v", record.ID, err)
+		}
+	}
+}
+
+// DeletePhotoHandler removes a photo, its timeline event and its blobs
+func DeletePhotoHandler(database db.Store, store storage.BlobStore, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		record, ok := requirePhoto(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		deleted, err := database.DeletePhoto(ctx, userID, record.PlantID, record.ID)
+		if err != nil {
+			logger.Debugf("Photo deletion failed: %v", err)
+			writeErrorResponse(w, "Failed to delete photo", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			writeErrorResponse(w, "Photo not found", http.StatusNotFound)
+			return
+		}
+		deletePhotoBlobs(store, logger, record)
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Photo deleted",
+		}, http.StatusOK)
+	}
+}
+
+var errPhotoTooLarge = errors.New("photo too large")
+
+// readPhotoUpload reads the photo file and caption from a multipart request
+func readPhotoUpload(r *http.Request, maxUploadBytes int64) ([]byte, *string, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("expected a multipart/form-data request")
+	}
+
+	var data []byte
+	var caption *string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch part.FormName() {
+		case "photo":
+			data, err = io.ReadAll(io.LimitReader(part, maxUploadBytes+1))
+			if err != nil {
+				return nil, nil, err
+			}
+			if int64(len(data)) > maxUploadBytes {
+				return nil, nil, errPhotoTooLarge
+			}
+		case "caption":
+			raw, err := io.ReadAll(io.LimitReader(part, maxPhotoCaptionLength+1))
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(raw) > maxPhotoCaptionLength {
+				return nil, nil, fmt.Errorf("caption must be at most %d characters long", maxPhotoCaptionLength)
+			}
+			caption = optionalString(string(raw))
+		}
+		part.Close()
+	}
+
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("photo is required")
+	}
+	return data, caption, nil
+}
+
+// requirePhoto loads the photo from the {id} and {photoID} path values, writing an error response if it is missing
+func requirePhoto(ctx context.Context, w http.ResponseWriter, r *http.Request, database db.Store, logger *logger.ServiceLogger, userID uuid.UUID) (*model.Photo, bool) {
+	plantID, ok := pathUUID(w, r, "id")
+	if !ok {
+		return nil, false
+	}
+	photoID, ok := pathUUID(w, r, "photoID")
+	if !ok {
+		return nil, false
+	}
+
+	record, err := database.GetPhoto(ctx, userID, plantID, photoID)
+	if err != nil {
+		logger.Debugf("Database error getting photo: %v", err)
+		writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if record == nil {
+		writeErrorResponse(w, "Photo not found", http.StatusNotFound)
+		return nil, false
+	}
+	return record, true
+}
+
+// deletePhotoBlobs removes the image and thumbnail of a photo. Failures are only
+// logged since the database no longer references the blobs.
+func deletePhotoBlobs(store storage.BlobStore, logger *logger.ServiceLogger, record *model.Photo) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, key := range []string{record.BlobKey, record.ThumbnailKey} {
+		if err := store.Delete(ctx, key); err != nil {
+			logger.Warnf("Failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+// extendDeadlines moves the connection's read and write deadlines to
+// photoTransferTimeout from now and returns the new deadline, so the handler's
+// own timeout is the one that cuts off slow transfers
+func extendDeadlines(w http.ResponseWriter, logger *logger.ServiceLogger) time.Time {
+	deadline := time.Now().Add(photoTransferTimeout)
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		logger.Debugf("Failed to extend read deadline: %v", err)
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil {
+		logger.Debugf("Failed to extend write deadline: %v", err)
+	}
+	return deadline
+}
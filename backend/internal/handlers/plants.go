@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/storage"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+const maxPlantNameLength = 200
+
+// PlantRequest is the body for creating or patching a plant.
+// On PATCH, omitted fields are left unchanged and empty strings clear optional fields.
+type PlantRequest struct {
+	Name       *string    `json:"name"`
+	Species    *string    `json:"species"`
+	Location   *string    `json:"location"`
+	Notes      *string    `json:"notes"`
+	AcquiredAt *time.Time `json:"acquired_at"`
+}
+
+type PlantResponse struct {
+	Success bool         `json:"success"`
+	Plant   *model.Plant `json:"plant"`
+}
+
+type PlantListResponse struct {
+	Success bool           `json:"success"`
+	Plants  []*model.Plant `json:"plants"`
+}
+
+// ListPlantsHandler returns all plants of the authenticated user
+func ListPlantsHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		plants, err := database.ListPlantsByUser(ctx, userID)
+		if err != nil {
+			logger.Debugf("Database error listing plants: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, PlantListResponse{Success: true, Plants: plants}, http.StatusOK)
+	}
+}
+
+// CreatePlantHandler adds a plant for the authenticated user
+func CreatePlantHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req PlantRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in create plant request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		if req.Name == nil {
+			writeErrorResponse(w, "name is required", http.StatusBadRequest)
+			return
+		}
+
+		plant := &model.Plant{
+			ID:     uuid.New(),
+			UserID: userID,
+		}
+		if err := applyPlantRequest(plant, req); err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := database.CreatePlant(ctx, plant); err != nil {
+			logger.Debugf("Plant creation failed: %v", err)
+			writeErrorResponse(w, "Failed to create plant", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Debugf("Plant %s created for user %s", plant.ID, userID)
+		writeJSONResponse(w, PlantResponse{Success: true, Plant: plant}, http.StatusCreated)
+	}
+}
+
+// GetPlantHandler returns a single plant of the authenticated user
+func GetPlantHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		plant, ok := requirePlant(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		writeJSONResponse(w, PlantResponse{Success: true, Plant: plant}, http.StatusOK)
+	}
+}
+
+// UpdatePlantHandler patches a plant of the authenticated user
+func UpdatePlantHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req PlantRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in update plant request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		plant, ok := requirePlant(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		if err := applyPlantRequest(plant, req); err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		updated, err := database.UpdatePlant(ctx, plant)
+		if err != nil {
+			logger.Debugf("Plant update failed: %v", err)
+			writeErrorResponse(w, "Failed to update plant", http.StatusInternalServerError)
+			return
+		}
+		if !updated {
+			writeErrorResponse(w, "Plant not found", http.StatusNotFound)
+			return
+		}
+
+		writeJSONResponse(w, PlantResponse{Success: true, Plant: plant}, http.StatusOK)
+	}
+}
+
+// DeletePlantHandler removes a plant of the authenticated user along with its stored photos
+func DeletePlantHandler(database db.Store, store storage.BlobStore, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		plantID, ok := pathUUID(w, r, "id")
+		if !ok {
+			return
+		}
+
+		// Photo rows cascade with the plant, so collect their blobs first
+		photos, err := database.ListPhotosByPlant(ctx, userID, plantID)
+		if err != nil {
+			logger.Debugf("Database error listing photos: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		deleted, err := database.DeletePlant(ctx, userID, plantID)
+		if err != nil {
+			logger.Debugf("Plant deletion failed: %v", err)
+			writeErrorResponse(w, "Failed to delete plant", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			writeErrorResponse(w, "Plant not found", http.StatusNotFound)
+			return
+		}
+		for _, record := range photos {
+			deletePhotoBlobs(store, logger, record)
+		}
+
+		logger.Debugf("Plant %s deleted for user %s", plantID, userID)
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Plant deleted",
+		}, http.StatusOK)
+	}
+}
+
+// requirePlant loads the plant from the {id} path value, writing an error response if it is missing
+func requirePlant(ctx context.Context, w http.ResponseWriter, r *http.Request, database db.Store, logger *logger.ServiceLogger, userID uuid.UUID) (*model.Plant, bool) {
+	plantID, ok := pathUUID(w, r, "id")
+	if !ok {
+		return nil, false
+	}
+
+	plant, err := database.GetPlant(ctx, userID, plantID)
+	if err != nil {
+		logger.Debugf("Database error getting plant: %v", err)
+		writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if plant == nil {
+		writeErrorResponse(w, "Plant not found", http.StatusNotFound)
+		return nil, false
+	}
+	return plant, true
+}
+
+// applyPlantRequest copies the fields present in req onto plant and validates the result
+func applyPlantRequest(plant *model.Plant, req PlantRequest) error {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return fmt.Errorf("name cannot be empty")
+		}
+		if len(name) > maxPlantNameLength {
+			return fmt.Errorf("name must be at most %d characters long", maxPlantNameLength)
+		}
+		plant.Name = name
+	}
+	if req.Species != nil {
+		plant.Species = optionalString(*req.Species)
+	}
+	if req.Location != nil {
+		plant.Location = optionalString(*req.Location)
+	}
+	if req.Notes != nil {
+		plant.Notes = optionalString(*req.Notes)
+	}
+	if req.AcquiredAt != nil {
+		acquiredAt := req.AcquiredAt.UTC()
+		plant.AcquiredAt = &acquiredAt
+	}
+	return nil
+}
+
+// optionalString trims s and returns nil when it is empty
+func optionalString(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/schedule"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// CareScheduleRequest is the body for creating or patching a care schedule.
+// Recurrence is given either as interval_days or as an RRULE string.
+type CareScheduleRequest struct {
+	TaskType     *model.CareTaskType `json:"task_type"`
+	IntervalDays *int                `json:"interval_days"`
+	RRule        *string             `json:"rrule"`
+	Anchor       *model.LocalTime    `json:"anchor"`
+	Notes        *string             `json:"notes"`
+}
+
+type CareScheduleResponse struct {
+	Success  bool                `json:"success"`
+	Schedule *model.CareSchedule `json:"schedule"`
+}
+
+type CareScheduleListResponse struct {
+	Success   bool                  `json:"success"`
+	Schedules []*model.CareSchedule `json:"schedules"`
+}
+
+// ListCareSchedulesHandler returns the care schedules of a plant
+func ListCareSchedulesHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		plant, ok := requirePlant(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		schedules, err := database.ListCareSchedulesByPlant(ctx, userID, plant.ID)
+		if err != nil {
+			logger.Debugf("Database error listing care schedules: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, CareScheduleListResponse{Success: true, Schedules: schedules}, http.StatusOK)
+	}
+}
+
+// CreateCareScheduleHandler adds a care schedule to a plant
+func CreateCareScheduleHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		plant, ok := requirePlant(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		var req CareScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in create care schedule request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		if req.TaskType == nil {
+			writeErrorResponse(w, "task_type is required", http.StatusBadRequest)
+			return
+		}
+		if req.IntervalDays == nil && req.RRule == nil {
+			writeErrorResponse(w, "interval_days or rrule is required", http.StatusBadRequest)
+			return
+		}
+
+		loc, err := userLocation(ctx, database, logger, userID)
+		if err != nil {
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now().Truncate(time.Minute)
+		if req.Anchor == nil {
+			// Default to starting the series right away in the owner's timezone
+			anchor := model.LocalTime(wallClock(now.In(loc)))
+			req.Anchor = &anchor
+		}
+
+		careSchedule := &model.CareSchedule{
+			ID:      uuid.New(),
+			PlantID: plant.ID,
+			UserID:  userID,
+		}
+		series, err := applyCareScheduleRequest(careSchedule, req, loc)
+		if err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		setCareScheduleState(careSchedule, schedule.Start(series, now))
+
+		if err := database.CreateCareSchedule(ctx, careSchedule); err != nil {
+			logger.Debugf("Care schedule creation failed: %v", err)
+			writeErrorResponse(w, "Failed to create care schedule", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Debugf("Care schedule %s created for plant %s", careSchedule.ID, plant.ID)
+		writeJSONResponse(w, CareScheduleResponse{Success: true, Schedule: careSchedule}, http.StatusCreated)
+	}
+}
+
+// GetCareScheduleHandler returns a single care schedule of a plant
+func GetCareScheduleHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		careSchedule, ok := requireCareSchedule(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		writeJSONResponse(w, CareScheduleResponse{Success: true, Schedule: careSchedule}, http.StatusOK)
+	}
+}
+
+// UpdateCareScheduleHandler patches a care schedule. Changing the recurrence or
+// anchor recomputes the pending occurrence from now and drops any snooze.
+func UpdateCareScheduleHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req CareScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in update care schedule request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		careSchedule, ok := requireCareSchedule(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		loc, err := userLocation(ctx, database, logger, userID)
+		if err != nil {
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		series, err := applyCareScheduleRequest(careSchedule, req, loc)
+		if err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Other changes keep the stored pending occurrence, which a task action
+		// may have moved since the schedule was read
+		resetState := req.IntervalDays != nil || req.RRule != nil || req.Anchor != nil
+		if resetState {
+			setCareScheduleState(careSchedule, schedule.Start(series, time.Now().Truncate(time.Minute)))
+		}
+
+		updated, err := database.UpdateCareSchedule(ctx, careSchedule, resetState)
+		if err != nil {
+			logger.Debugf("Care schedule update failed: %v", err)
+			writeErrorResponse(w, "Failed to update care schedule", http.StatusInternalServerError)
+			return
+		}
+		if !updated {
+			writeErrorResponse(w, "Care schedule not found", http.StatusNotFound)
+			return
+		}
+
+		writeJSONResponse(w, CareScheduleResponse{Success: true, Schedule: careSchedule}, http.StatusOK)
+	}
+}
+
+// DeleteCareScheduleHandler removes a care schedule from a plant
+func DeleteCareScheduleHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		plantID, ok := pathUUID(w, r, "id")
+		if !ok {
+			return
+		}
+		scheduleID, ok := pathUUID(w, r, "scheduleID")
+		if !ok {
+			return
+		}
+
+		deleted, err := database.DeleteCareSchedule(ctx, userID, plantID, scheduleID)
+		if err != nil {
+			logger.Debugf("Care schedule deletion failed: %v", err)
+			writeErrorResponse(w, "Failed to delete care schedule", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			writeErrorResponse(w, "Care schedule not found", http.StatusNotFound)
+			return
+		}
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Care schedule deleted",
+		}, http.StatusOK)
+	}
+}
+
+// applyCareScheduleRequest copies the fields present in req onto s, validates
+// the recurrence and returns the resulting series in loc
+func applyCareScheduleRequest(s *model.CareSchedule, req CareScheduleRequest, loc *time.Location) (schedule.Series, error) {
+	if req.TaskType != nil {
+		if !req.TaskType.Valid() {
+			return schedule.Series{}, fmt.Errorf("invalid task_type %q", *req.TaskType)
+		}
+		s.TaskType = *req.TaskType
+	}
+
+	switch {
+	case req.IntervalDays != nil && req.RRule != nil:
+		return schedule.Series{}, fmt.Errorf("only one of interval_days and rrule may be set")
+	case req.IntervalDays != nil:
+		rule := schedule.IntervalRule(*req.IntervalDays)
+		if err := rule.Validate(); err != nil {
+			return schedule.Series{}, fmt.Errorf("invalid interval_days: %v", err)
+		}
+		s.RRule = rule.String()
+	case req.RRule != nil:
+		rule, err := schedule.ParseRule(*req.RRule)
+		if err != nil {
+			return schedule.Series{}, fmt.Errorf("invalid rrule: %v", err)
+		}
+		s.RRule = rule.String()
+	}
+
+	if req.Anchor != nil {
+		s.Anchor = *req.Anchor
+	}
+	if req.Notes != nil {
+		s.Notes = optionalString(*req.Notes)
+	}
+
+	series, err := schedule.SeriesFor(s, loc)
+	if err != nil {
+		return schedule.Series{}, fmt.Errorf("invalid rrule: %v", err)
+	}
+	return series, nil
+}
+
+// requireCareSchedule loads the care schedule from the {id} and {scheduleID} path values,
+// writing an error response if it is missing
+func requireCareSchedule(ctx context.Context, w http.ResponseWriter, r *http.Request, database db.Store, logger *logger.ServiceLogger, userID uuid.UUID) (*model.CareSchedule, bool) {
+	plantID, ok := pathUUID(w, r, "id")
+	if !ok {
+		return nil, false
+	}
+	scheduleID, ok := pathUUID(w, r, "scheduleID")
+	if !ok {
+		return nil, false
+	}
+
+	careSchedule, err := database.GetCareSchedule(ctx, userID, plantID, scheduleID)
+	if err != nil {
+		logger.Debugf("Database error getting care schedule: %v", err)
+		writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if careSchedule == nil {
+		writeErrorResponse(w, "Care schedule not found", http.StatusNotFound)
+		return nil, false
+	}
+	return careSchedule, true
+}
+
+// userLocation returns the timezone of a user, falling back to UTC if it is not a valid IANA name
+func userLocation(ctx context.Context, database db.Store, logger *logger.ServiceLogger, userID uuid.UUID) (*time.Location, error) {
+	user, err := database.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.Debugf("Database error getting user %s: %v", userID, err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %s not found", userID)
+	}
+
+	loc, err := schedule.LoadLocation(user.Timezone)
+	if err != nil {
+		logger.Warnf("Using UTC for user %s: %v", userID, err)
+	}
+	return loc, nil
+}
+
+// careScheduleState returns the pending occurrence of a care schedule
+func careScheduleState(s *model.CareSchedule) schedule.State {
+	return schedule.State{OccurrenceAt: s.OccurrenceAt, DueAt: s.NextDueAt}
+}
+
+// setCareScheduleState stores a pending occurrence on a care schedule
+func setCareScheduleState(s *model.CareSchedule, st schedule.State) {
+	s.OccurrenceAt = st.OccurrenceAt
+	s.NextDueAt = st.DueAt
+}
+
+// wallClock returns the date and clock of t as a UTC time
+func wallClock(t time.Time) time.Time {
+	y, m, d := t.Date()
+	hour, min, sec := t.Clock()
+	return time.Date(y, m, d, hour, min, sec, 0, time.UTC)
+}
@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/schedule"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxDueTasksHorizon bounds how far ahead due tasks can be listed
+	maxDueTasksHorizon = 366 * 24 * time.Hour
+	// maxOccurrencesPerSchedule bounds the expanded occurrences of a single schedule
+	maxOccurrencesPerSchedule = 100
+	// maxCareTaskRecords bounds the number of action records returned for a schedule
+	maxCareTaskRecords = 100
+)
+
+type CareTaskListResponse struct {
+	Success bool              `json:"success"`
+	Until   time.Time         `json:"until"`
+	Tasks   []*model.CareTask `json:"tasks"`
+}
+
+// ListDueTasksHandler lists the care task occurrences of the authenticated user that
+// are due at or before the "until" query parameter (RFC 3339, defaults to now).
+// Overdue occurrences are included and flagged.
+func ListDueTasksHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		now := time.Now().UTC()
+		until := now
+		if raw := r.URL.Query().Get("until"); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				writeErrorResponse(w, "until must be an RFC 3339 timestamp", http.StatusBadRequest)
+				return
+			}
+			until = parsed.UTC()
+		}
+		if until.Sub(now) > maxDueTasksHorizon {
+			writeErrorResponse(w, "until must be within one year from now", http.StatusBadRequest)
+			return
+		}
+
+		schedules, err := database.ListCareSchedulesDueBefore(ctx, userID, until)
+		if err != nil {
+			logger.Debugf("Database error listing due care schedules: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		tasks := []*model.CareTask{}
+		if len(schedules) > 0 {
+			plants, err := database.ListPlantsByUser(ctx, userID)
+			if err != nil {
+				logger.Debugf("Database error listing plants: %v", err)
+				writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			plantNames := make(map[uuid.UUID]string, len(plants))
+			for _, plant := range plants {
+				plantNames[plant.ID] = plant.Name
+			}
+
+			loc, err := userLocation(ctx, database, logger, userID)
+			if err != nil {
+				writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			for _, careSchedule := range schedules {
+				series, err := schedule.SeriesFor(careSchedule, loc)
+				if err != nil {
+					logger.Warnf("Skipping care schedule %s with invalid rule: %v", careSchedule.ID, err)
+					continue
+				}
+				for _, dueAt := range dueOccurrences(series, careSchedule, until) {
+					tasks = append(tasks, &model.CareTask{
+						ScheduleID: careSchedule.ID,
+						PlantID:    careSchedule.PlantID,
+						PlantName:  plantNames[careSchedule.PlantID],
+						TaskType:   careSchedule.TaskType,
+						DueAt:      dueAt,
+						Overdue:    dueAt.Before(now),
+					})
+				}
+			}
+		}
+
+		sort.SliceStable(tasks, func(i, j int) bool {
+			return tasks[i].DueAt.Before(tasks[j].DueAt)
+		})
+
+		writeJSONResponse(w, CareTaskListResponse{Success: true, Until: until, Tasks: tasks}, http.StatusOK)
+	}
+}
+
+// CareTaskActionRequest is the body of a care task action. Which fields apply depends on the action:
+//   - complete: at (optional, defaults to now)
+//   - snooze: either duration (e.g. "2h") or until
+//   - skip: no fields
+//   - reschedule: anchor, the new start of the series in the owner's timezone
+//
+// occurrence_at may be sent with any action to make it apply only if the
+// schedule is still waiting on that occurrence.
+type CareTaskActionRequest struct {
+	At           *time.Time       `json:"at"`
+	Duration     *string          `json:"duration"`
+	Until        *time.Time       `json:"until"`
+	Anchor       *model.LocalTime `json:"anchor"`
+	OccurrenceAt *time.Time       `json:"occurrence_at"`
+}
+
+type CareTaskActionResponse struct {
+	Success  bool                  `json:"success"`
+	Schedule *model.CareSchedule   `json:"schedule"`
+	Record   *model.CareTaskRecord `json:"record"`
+}
+
+type CareTaskRecordListResponse struct {
+	Success bool                    `json:"success"`
+	Records []*model.CareTaskRecord `json:"records"`
+}
+
+// CareTaskActionHandler applies a complete, snooze, skip or reschedule action to a care schedule
+func CareTaskActionHandler(database db.Store, logger *logger.ServiceLogger, action model.CareTaskAction) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req CareTaskActionRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+				logger.Debugf("Invalid JSON in %s request: %v", action, err)
+				writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+				return
+			}
+		}
+
+		careSchedule, ok := requireCareSchedule(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+		if req.OccurrenceAt != nil && !req.OccurrenceAt.Equal(careSchedule.OccurrenceAt) {
+			writeErrorResponse(w, "Care task occurrence has already changed", http.StatusConflict)
+			return
+		}
+
+		loc, err := userLocation(ctx, database, logger, userID)
+		if err != nil {
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now().UTC()
+		previous := careScheduleState(careSchedule)
+		record := &model.CareTaskRecord{
+			ID:            uuid.New(),
+			ScheduleID:    careSchedule.ID,
+			UserID:        userID,
+			Action:        action,
+			OccurrenceAt:  previous.OccurrenceAt,
+			ActedAt:       now,
+			PreviousDueAt: previous.DueAt,
+		}
+
+		next, err := nextCareTaskState(careSchedule, req, loc, now, record)
+		if err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		setCareScheduleState(careSchedule, next)
+		record.NextDueAt = next.DueAt
+		record.NextOccurrenceAt = next.OccurrenceAt
+
+		// Completing a task is also something that happened to the plant
+		var event *model.CareEvent
+		if action == model.CareTaskActionComplete {
+			payload, _ := json.Marshal(map[string]interface{}{
+				"schedule_id":   careSchedule.ID,
+				"record_id":     record.ID,
+				"occurrence_at": record.OccurrenceAt,
+			})
+			event = &model.CareEvent{
+				ID:         uuid.New(),
+				PlantID:    careSchedule.PlantID,
+				UserID:     userID,
+				Type:       careSchedule.TaskType.EventType(),
+				OccurredAt: record.ActedAt,
+				Payload:    payload,
+			}
+		}
+
+		applied, err := database.ApplyCareTaskAction(ctx, careSchedule, record, event)
+		if err != nil {
+			logger.Debugf("Care task %s failed: %v", action, err)
+			writeErrorResponse(w, "Failed to update care task", http.StatusInternalServerError)
+			return
+		}
+		if !applied {
+			writeErrorResponse(w, "Care task occurrence has already changed", http.StatusConflict)
+			return
+		}
+
+		logger.Debugf("Care task %s applied to schedule %s, next due %s", action, careSchedule.ID, next.DueAt)
+		writeJSONResponse(w, CareTaskActionResponse{Success: true, Schedule: careSchedule, Record: record}, http.StatusOK)
+	}
+}
+
+// ListCareTaskRecordsHandler returns the most recent actions taken on a care schedule
+func ListCareTaskRecordsHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		careSchedule, ok := requireCareSchedule(ctx, w, r, database, logger, userID)
+		if !ok {
+			return
+		}
+
+		records, err := database.ListCareTaskRecords(ctx, userID, careSchedule.ID, maxCareTaskRecords)
+		if err != nil {
+			logger.Debugf("Database error listing care task records: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, CareTaskRecordListResponse{Success: true, Records: records}, http.StatusOK)
+	}
+}
+
+// nextCareTaskState computes the pending occurrence after applying record.Action.
+// Reschedule also updates the anchor of s, complete sets record.ActedAt to the completion time.
+func nextCareTaskState(s *model.CareSchedule, req CareTaskActionRequest, loc *time.Location, now time.Time, record *model.CareTaskRecord) (schedule.State, error) {
+	series, err := schedule.SeriesFor(s, loc)
+	if err != nil {
+		return schedule.State{}, fmt.Errorf("care schedule has an invalid rule: %v", err)
+	}
+	current := careScheduleState(s)
+
+	switch record.Action {
+	case model.CareTaskActionComplete:
+		at := now
+		if req.At != nil {
+			at = req.At.UTC()
+		}
+		record.ActedAt = at
+		return schedule.Complete(series, current, at, now)
+
+	case model.CareTaskActionSnooze:
+		var until time.Time
+		switch {
+		case req.Duration != nil && req.Until != nil:
+			return schedule.State{}, fmt.Errorf("only one of duration and until may be set")
+		case req.Duration != nil:
+			d, err := time.ParseDuration(*req.Duration)
+			if err != nil || d <= 0 {
+				return schedule.State{}, fmt.Errorf("duration must be a positive duration such as \"30m\" or \"2h\"")
+			}
+			until = now.Add(d)
+		case req.Until != nil:
+			until = req.Until.UTC()
+		default:
+			return schedule.State{}, fmt.Errorf("duration or until is required")
+		}
+		return schedule.Snooze(series, current, until, now)
+
+	case model.CareTaskActionSkip:
+		return schedule.Skip(series, current), nil
+
+	case model.CareTaskActionReschedule:
+		if req.Anchor == nil {
+			return schedule.State{}, fmt.Errorf("anchor is required")
+		}
+		s.Anchor = *req.Anchor
+		series, err = schedule.SeriesFor(s, loc)
+		if err != nil {
+			return schedule.State{}, fmt.Errorf("care schedule has an invalid rule: %v", err)
+		}
+		return schedule.Reschedule(series, now.Truncate(time.Minute)), nil
+	}
+
+	return schedule.State{}, fmt.Errorf("unknown action %q", record.Action)
+}
+
+// dueOccurrences expands a schedule from its pending occurrence up to until.
+// The first entry is the effective due time, which is later than the
+// occurrence itself when it was snoozed. A snooze may end right on the
+// following occurrence, so expansion continues after the later of the two.
+func dueOccurrences(series schedule.Series, s *model.CareSchedule, until time.Time) []time.Time {
+	var out []time.Time
+	if s.NextDueAt.After(until) {
+		return out
+	}
+	out = append(out, s.NextDueAt.UTC())
+	after := s.OccurrenceAt
+	if s.NextDueAt.After(after) {
+		after = s.NextDueAt
+	}
+	for occ := series.Next(after); !occ.After(until) && len(out) < maxOccurrencesPerSchedule; occ = series.Next(occ) {
+		out = append(out, occ.UTC())
+	}
+	return out
+}
@@ -0,0 +1,531 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/auth"
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/throttle"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+const (
+	// loginChallengeTTL is how long a user has to enter the second factor after the password
+	loginChallengeTTL = 5 * time.Minute
+	// maxLoginChallengeAttempts bounds the codes that can be tried against one challenge
+	maxLoginChallengeAttempts = 5
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type TwoFactorStatusResponse struct {
+	Success                bool `json:"success"`
+	Enabled                bool `json:"enabled"`
+	Pending                bool `json:"pending"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorSetupResponse carries the secret to add to an authenticator app,
+// either typed in or scanned as a QR code of the provisioning URI
+type TwoFactorSetupResponse struct {
+	Success         bool   `json:"success"`
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodesResponse carries recovery codes, which are only shown once
+type RecoveryCodesResponse struct {
+	Success       bool     `json:"success"`
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorStatusHandler reports whether the authenticated user has two-factor authentication enabled
+func TwoFactorStatusHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		totp, err := database.GetTOTP(ctx, userID)
+		if err != nil {
+			logger.Debugf("Database error getting TOTP: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		response := TwoFactorStatusResponse{Success: true, Enabled: totp.Enabled(), Pending: totp != nil && !totp.Enabled()}
+		if totp.Enabled() {
+			codes, err := database.ListUnusedRecoveryCodes(ctx, userID)
+			if err != nil {
+				logger.Debugf("Database error listing recovery codes: %v", err)
+				writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			response.RecoveryCodesRemaining = len(codes)
+		}
+
+		writeJSONResponse(w, response, http.StatusOK)
+	}
+}
+
+// SetupTwoFactorHandler starts the TOTP enrollment of a local account. The
+// enrollment only takes effect once it is confirmed with a code.
+func SetupTwoFactorHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+		user, ok := requireUser(ctx, w, database, logger, userID)
+		if !ok {
+			return
+		}
+		// Accounts signing in through an OAuth provider rely on the provider's second factor
+		if user.PasswordHash == nil {
+			writeErrorResponse(w, "Two-factor authentication is only available for accounts with a password", http.StatusConflict)
+			return
+		}
+
+		secret, err := auth.GenerateTOTPSecret()
+		if err != nil {
+			logger.Errorf("Failed to generate TOTP secret: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		saved, err := database.SaveTOTPSecret(ctx, userID, secret)
+		if err != nil {
+			logger.Debugf("TOTP enrollment failed: %v", err)
+			writeErrorResponse(w, "Failed to set up two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+		if !saved {
+			writeErrorResponse(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+
+		writeJSONResponse(w, TwoFactorSetupResponse{
+			Success:         true,
+			Secret:          secret,
+			ProvisioningURI: auth.TOTPProvisioningURI(user.Email, secret),
+		}, http.StatusOK)
+	}
+}
+
+// ConfirmTwoFactorHandler enables two-factor authentication once the user
+// proves their authenticator app works, and returns their recovery codes
+func ConfirmTwoFactorHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req TwoFactorCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in confirm 2FA request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		totp, err := database.GetTOTP(ctx, userID)
+		if err != nil {
+			logger.Debugf("Database error getting TOTP: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if totp == nil {
+			writeErrorResponse(w, "Two-factor authentication setup has not been started", http.StatusConflict)
+			return
+		}
+		if totp.Enabled() {
+			writeErrorResponse(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+
+		step, valid := auth.ValidateTOTP(totp.Secret, req.Code, time.Now())
+		if !valid {
+			writeErrorResponse(w, "Invalid code", http.StatusBadRequest)
+			return
+		}
+
+		codes, hashes, err := newRecoveryCodes(ctx)
+		if err != nil {
+			logger.Errorf("Failed to generate recovery codes: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		enabled, err := database.EnableTOTP(ctx, userID, step, hashes)
+		if err != nil {
+			logger.Debugf("Enabling TOTP failed: %v", err)
+			writeErrorResponse(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+		if !enabled {
+			// Another request confirmed the enrollment or used the code first
+			writeErrorResponse(w, "Invalid code", http.StatusBadRequest)
+			return
+		}
+
+		writeJSONResponse(w, RecoveryCodesResponse{
+			Success:       true,
+			Message:       "Two-factor authentication enabled, store the recovery codes in a safe place",
+			RecoveryCodes: codes,
+		}, http.StatusOK)
+	}
+}
+
+// DisableTwoFactorHandler turns two-factor authentication off after checking
+// both the password and a current code or recovery code
+func DisableTwoFactorHandler(database db.Store, throttler *throttle.Throttler, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req DisableTwoFactorRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in disable 2FA request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireUser(ctx, w, database, logger, userID)
+		if !ok {
+			return
+		}
+		if !checkCurrentPassword(ctx, w, r, throttler, logger, user, req.Password) {
+			return
+		}
+		totp, ok := requireTOTP(ctx, w, database, logger, userID)
+		if !ok {
+			return
+		}
+		if !checkSecondFactor(ctx, w, database, logger, totp, req.Code, true) {
+			return
+		}
+
+		if _, err := database.DisableTOTP(ctx, userID); err != nil {
+			logger.Debugf("Disabling TOTP failed: %v", err)
+			writeErrorResponse(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Two-factor authentication disabled",
+		}, http.StatusOK)
+	}
+}
+
+// RegenerateRecoveryCodesHandler replaces all recovery codes of the
+// authenticated user, e.g. after most of them were used or they were lost
+func RegenerateRecoveryCodesHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		userID, ok := requireUserID(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req TwoFactorCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in regenerate recovery codes request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		totp, ok := requireTOTP(ctx, w, database, logger, userID)
+		if !ok {
+			return
+		}
+		// Only the authenticator app proves the user still has their second factor
+		if !checkSecondFactor(ctx, w, database, logger, totp, req.Code, false) {
+			return
+		}
+
+		codes, hashes, err := newRecoveryCodes(ctx)
+		if err != nil {
+			logger.Errorf("Failed to generate recovery codes: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if err := database.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+			logger.Debugf("Replacing recovery codes failed: %v", err)
+			writeErrorResponse(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, RecoveryCodesResponse{
+			Success:       true,
+			Message:       "New recovery codes generated, the previous ones no longer work",
+			RecoveryCodes: codes,
+		}, http.StatusOK)
+	}
+}
+
+// CompleteTwoFactorLoginHandler finishes a login that LoginHandler answered
+// with a challenge, issuing the session once the second factor checks out.
+// Wrong codes count as failed logins of the account.
+func CompleteTwoFactorLoginHandler(database db.Store, sessions *auth.Sessions, throttler *throttle.Throttler, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		var req TwoFactorLoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in 2FA login request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		challenge := strings.TrimSpace(req.ChallengeToken)
+		if challenge == "" || strings.TrimSpace(req.Code) == "" {
+			writeErrorResponse(w, "challenge_token and code are required", http.StatusBadRequest)
+			return
+		}
+		challengeHash := auth.HashToken(challenge)
+
+		userID, err := database.AttemptLoginChallenge(ctx, challengeHash, maxLoginChallengeAttempts)
+		if err != nil {
+			logger.Debugf("Login challenge lookup failed: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if userID == uuid.Nil {
+			writeErrorResponse(w, "Login expired, sign in again", http.StatusUnauthorized)
+			return
+		}
+
+		totp, err := database.GetTOTP(ctx, userID)
+		if err != nil {
+			logger.Debugf("Database error getting TOTP: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !totp.Enabled() {
+			writeErrorResponse(w, "Login expired, sign in again", http.StatusUnauthorized)
+			return
+		}
+		user, ok := requireUser(ctx, w, database, logger, userID)
+		if !ok {
+			return
+		}
+		if user.DisabledAt != nil {
+			writeErrorResponse(w, "Account is disabled", http.StatusForbidden)
+			return
+		}
+
+		ip := throttler.ClientIP(r)
+		attempt, ok := beginLoginAttempt(ctx, w, throttler, logger, ip, user.Email)
+		if !ok {
+			return
+		}
+		defer releaseLoginAttempt(ctx, attempt, logger)
+		valid, err := verifySecondFactor(ctx, database, totp, req.Code, true)
+		if err != nil {
+			logger.Debugf("Second factor verification failed: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			logger.Debugf("Invalid second factor at login for user %s", userID)
+			recordLoginFailure(ctx, attempt, logger)
+			writeErrorResponse(w, "Invalid code", http.StatusUnauthorized)
+			return
+		}
+
+		// A challenge completes a single login even if two requests raced past the attempt count
+		deleted, err := database.DeleteLoginChallenge(ctx, challengeHash)
+		if err != nil {
+			logger.Debugf("Deleting login challenge failed: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			writeErrorResponse(w, "Login expired, sign in again", http.StatusUnauthorized)
+			return
+		}
+
+		recordLoginSuccess(ctx, attempt, logger)
+		issueSession(w, r, sessions, logger, user)
+	}
+}
+
+// OAuthTwoFactorChallengeHandler continues an OAuth login of an account with
+// two-factor authentication. The provider token carries no user until then,
+// so it is exchanged for the same challenge a password login gets and the
+// session is issued by CompleteTwoFactorLoginHandler.
+func OAuthTwoFactorChallengeHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		login, err := auth.PendingLoginFromRequest(r)
+		if err != nil {
+			logger.Debugf("Challenge request without pending login: %v", err)
+			writeErrorResponse(w, "No login awaiting a second factor, sign in again", http.StatusBadRequest)
+			return
+		}
+
+		user, err := database.GetUserByIdentity(ctx, model.AuthProvider(login.Provider), login.Subject)
+		if err != nil {
+			logger.Debugf("Database error getting user by identity: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			writeErrorResponse(w, "Login expired, sign in again", http.StatusUnauthorized)
+			return
+		}
+		if user.DisabledAt != nil {
+			writeErrorResponse(w, "Account is disabled", http.StatusForbidden)
+			return
+		}
+
+		totp, err := database.GetTOTP(ctx, user.ID)
+		if err != nil {
+			logger.Debugf("Database error getting TOTP: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !totp.Enabled() {
+			// Two-factor authentication was turned off since, a new login gets a session directly
+			writeErrorResponse(w, "Login expired, sign in again", http.StatusUnauthorized)
+			return
+		}
+
+		logger.Debugf("%s login accepted, awaiting second factor: %s", login.Provider, user.Email)
+		writeLoginChallenge(ctx, w, database, logger, user)
+	}
+}
+
+// requireTOTP loads the enabled TOTP enrollment of a user, writing a 409 response if there is none
+func requireTOTP(ctx context.Context, w http.ResponseWriter, database db.Store, logger *logger.ServiceLogger, userID uuid.UUID) (*model.TOTP, bool) {
+	totp, err := database.GetTOTP(ctx, userID)
+	if err != nil {
+		logger.Debugf("Database error getting TOTP: %v", err)
+		writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !totp.Enabled() {
+		writeErrorResponse(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return nil, false
+	}
+	return totp, true
+}
+
+// checkSecondFactor verifies a TOTP code, or a recovery code if allowRecovery
+// is set, and uses it up. It writes a 401 response if the code is invalid.
+func checkSecondFactor(ctx context.Context, w http.ResponseWriter, database db.Store, logger *logger.ServiceLogger, totp *model.TOTP, code string, allowRecovery bool) bool {
+	valid, err := verifySecondFactor(ctx, database, totp, code, allowRecovery)
+	if err != nil {
+		logger.Debugf("Second factor verification failed: %v", err)
+		writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if !valid {
+		logger.Debugf("Invalid second factor for user %s", totp.UserID)
+		writeErrorResponse(w, "Invalid code", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// verifySecondFactor accepts a TOTP code, or a recovery code if allowRecovery is set, and uses it up
+func verifySecondFactor(ctx context.Context, database db.Store, totp *model.TOTP, code string, allowRecovery bool) (bool, error) {
+	if auth.IsTOTPCode(code) {
+		return useTOTPCode(ctx, database, totp, code)
+	}
+	if allowRecovery {
+		return useRecoveryCode(ctx, database, totp, code)
+	}
+	return false, nil
+}
+
+// useTOTPCode accepts a TOTP code that has not been used before
+func useTOTPCode(ctx context.Context, database db.Store, totp *model.TOTP, code string) (bool, error) {
+	step, valid := auth.ValidateTOTP(totp.Secret, code, time.Now())
+	if !valid {
+		return false, nil
+	}
+	return database.UseTOTPStep(ctx, totp.UserID, step)
+}
+
+// useRecoveryCode accepts an unused recovery code and marks it as used
+func useRecoveryCode(ctx context.Context, database db.Store, totp *model.TOTP, code string) (bool, error) {
+	code = auth.NormalizeRecoveryCode(code)
+	if code == "" {
+		return false, nil
+	}
+
+	codes, err := database.ListUnusedRecoveryCodes(ctx, totp.UserID)
+	if err != nil {
+		return false, err
+	}
+	for _, stored := range codes {
+		match, err := auth.VerifyPassword(ctx, code, stored.CodeHash)
+		if err != nil {
+			return false, err
+		}
+		if match {
+			return database.UseRecoveryCode(ctx, stored.ID)
+		}
+	}
+	return false, nil
+}
+
+// newRecoveryCodes generates a set of recovery codes and their hashes
+func newRecoveryCodes(ctx context.Context) ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		if hashes[i], err = auth.HashPassword(ctx, auth.NormalizeRecoveryCode(code)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/auth"
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/mail"
+	"github.com/anish-chanda/ferna/model"
+)
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// VerifyEmailHandler confirms an email address using the token from the verification email
+func VerifyEmailHandler(database db.Store, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		var req VerifyEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in verify email request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		token := strings.TrimSpace(req.Token)
+		if token == "" {
+			writeErrorResponse(w, "token is required", http.StatusBadRequest)
+			return
+		}
+
+		verified, err := database.VerifyEmail(ctx, auth.HashToken(token))
+		if errors.Is(err, db.ErrEmailInUse) {
+			writeErrorResponse(w, "Email is already in use by another account", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logger.Debugf("Email verification failed: %v", err)
+			writeErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !verified {
+			writeErrorResponse(w, "Invalid or expired verification token", http.StatusBadRequest)
+			return
+		}
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Email address verified",
+		}, http.StatusOK)
+	}
+}
+
+// ResendVerificationHandler sends a new verification email to an unverified
+// account. Like the password reset it does not reveal whether the account exists.
+func ResendVerificationHandler(database db.Store, mailer *mail.Mailer, verifyURL string, ttl time.Duration, logger *logger.ServiceLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+		var req ResendVerificationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Debugf("Invalid JSON in resend verification request: %v", err)
+			writeErrorResponse(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		email := strings.TrimSpace(strings.ToLower(req.Email))
+		if email == "" {
+			writeErrorResponse(w, "email is required", http.StatusBadRequest)
+			return
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			user, err := database.GetUserByEmail(ctx, email)
+			if err != nil {
+				logger.Errorf("Database error getting user for verification: %v", err)
+				return
+			}
+			if user == nil || user.EmailVerifiedAt != nil {
+				return
+			}
+			if err := sendEmailVerification(ctx, database, mailer, verifyURL, ttl, user, user.Email); err != nil {
+				logger.Errorf("Failed to resend verification email: %v", err)
+			}
+		}()
+
+		writeJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "If an unverified account exists for this email, a verification link has been sent",
+		}, http.StatusAccepted)
+	}
+}
+
+// sendEmailVerification creates a token confirming email for user and queues the verification email to it
+func sendEmailVerification(ctx context.Context, database db.Store, mailer *mail.Mailer, verifyURL string, ttl time.Duration, user *model.User, email string) error {
+	token, tokenHash, err := auth.GenerateToken()
+	if err != nil {
+		return err
+	}
+	if err := database.CreateEmailVerificationToken(ctx, user.ID, email, tokenHash, time.Now().UTC().Add(ttl)); err != nil {
+		return err
+	}
+	return mailer.Enqueue(ctx, email, mail.TemplateVerifyEmail, map[string]any{
+		"Name":      user.FullName,
+		"Link":      tokenLink(verifyURL, token),
+		"ExpiresIn": mail.HumanDuration(ttl),
+	})
+}
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/storage"
+	"github.com/google/uuid"
+)
+
+// Database checks that the database answers a ping
+func Database(ping func(ctx context.Context) error) CheckFunc {
+	return func(ctx context.Context) error {
+		return ping(ctx)
+	}
+}
+
+// Migrations checks that the last schema migration did not fail halfway
+func Migrations(version func(ctx context.Context) (uint, bool, error)) CheckFunc {
+	return func(ctx context.Context) error {
+		v, dirty, err := version(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("database is in a dirty state at version %d", v)
+		}
+		return nil
+	}
+}
+
+// BlobStore checks that blobs can be written, read back and deleted. Every
+// instance probes with its own key so concurrent probes do not interfere.
+func BlobStore(blobs storage.BlobStore) CheckFunc {
+	key := "health/" + uuid.NewString()
+	const content = "ok"
+
+	return func(ctx context.Context) error {
+		if err := blobs.Put(ctx, key, strings.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+			return fmt.Errorf("write failed: %w", err)
+		}
+		defer blobs.Delete(context.WithoutCancel(ctx), key)
+
+		r, err := blobs.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("read failed: %w", err)
+		}
+		defer r.Close()
+		got, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("read failed: %w", err)
+		}
+		if string(got) != content {
+			return errors.New("read back different content")
+		}
+		return nil
+	}
+}
+
+// Heartbeat checks that a background worker showed a sign of life within maxAge
+func Heartbeat(last func() time.Time, maxAge time.Duration) CheckFunc {
+	return func(ctx context.Context) error {
+		beat := last()
+		if beat.IsZero() {
+			return errors.New("worker has not started")
+		}
+		if age := time.Since(beat); age > maxAge {
+			return fmt.Errorf("no heartbeat for %s", age.Round(time.Second))
+		}
+		return nil
+	}
+}
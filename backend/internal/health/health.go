@@ -0,0 +1,147 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/logger"
+)
+
+// checkTimeout bounds a single component check so a hanging dependency fails the probe instead of the prober
+const checkTimeout = 3 * time.Second
+
+// CheckFunc reports whether a component works, returning nil if it does
+type CheckFunc func(ctx context.Context) error
+
+// ComponentStatus is the outcome of a single check
+type ComponentStatus struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the response of a probe
+type Report struct {
+	Status     string                     `json:"status"`
+	Service    string                     `json:"service"`
+	Timestamp  time.Time                  `json:"timestamp"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+// Statuses of a probe and its components
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusAlive    = "alive"
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker runs the readiness checks of the service's dependencies
+type Checker struct {
+	service string
+	checks  []namedCheck
+	logger  *logger.ServiceLogger
+}
+
+// NewChecker creates a Checker without any checks
+func NewChecker(service string, logger *logger.ServiceLogger) *Checker {
+	return &Checker{service: service, logger: logger.WithField("component", "health")}
+}
+
+// Add registers a check for the named component. Checks are meant to be
+// added during setup, before the handlers serve requests.
+func (c *Checker) Add(name string, check CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Run executes all checks concurrently and reports whether every component is up
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{
+		Status:     StatusReady,
+		Service:    c.service,
+		Timestamp:  time.Now().UTC(),
+		Components: make(map[string]ComponentStatus, len(c.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := runCheck(ctx, nc.check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[nc.name] = status
+			if status.Status != StatusUp {
+				report.Status = StatusNotReady
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// runCheck executes a single check within checkTimeout
+func runCheck(ctx context.Context, check CheckFunc) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	status := ComponentStatus{
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// LiveHandler answers the liveness probe. It checks no dependencies: a process
+// that can answer is alive, and restarting it would not fix a database outage.
+func (c *Checker) LiveHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, Report{
+		Status:    StatusAlive,
+		Service:   c.service,
+		Timestamp: time.Now().UTC(),
+	}, http.StatusOK)
+}
+
+// ReadyHandler answers the readiness probe with the status of every component,
+// using 503 when any of them is down so no traffic is routed to the instance
+func (c *Checker) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+
+	statusCode := http.StatusOK
+	if report.Status != StatusReady {
+		statusCode = http.StatusServiceUnavailable
+		for name, component := range report.Components {
+			if component.Status != StatusUp {
+				c.logger.Warnf("Readiness check %s failed: %s", name, component.Error)
+			}
+		}
+	}
+	writeReport(w, report, statusCode)
+}
+
+func writeReport(w http.ResponseWriter, report Report, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	// Probes must always see the current state
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextKey is the context key of the request-scoped logger
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying logger
+func NewContext(ctx context.Context, logger *ServiceLogger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or fallback if there is none
+func FromContext(ctx context.Context, fallback *ServiceLogger) *ServiceLogger {
+	if logger, ok := ctx.Value(contextKey{}).(*ServiceLogger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// WithContext binds ctx to the logger, so its lines carry the trace and span
+// ID of the span in ctx, if any, and can be found from a trace and the other
+// way round
+func (sl *ServiceLogger) WithContext(ctx context.Context) *ServiceLogger {
+	return &ServiceLogger{
+		logger: sl.logger.With().Ctx(ctx).Logger(),
+	}
+}
+
+// traceHook adds the trace and span ID of the span in the context of a log
+// line. The context is the one passed to a *Context method, or else the one
+// bound with WithContext.
+type traceHook struct{}
+
+func (traceHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	spanContext := trace.SpanContextFromContext(e.GetCtx())
+	if !spanContext.IsValid() {
+		return
+	}
+	e.Str("trace_id", spanContext.TraceID().String()).
+		Str("span_id", spanContext.SpanID().String())
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestLogger returns a logger writing JSON lines to buf, with the hooks of New
+func newTestLogger(buf *bytes.Buffer) *ServiceLogger {
+	return &ServiceLogger{logger: zerolog.New(buf).Hook(traceHook{})}
+}
+
+// spanContext returns ctx carrying a sampled remote span
+func spanContext(t *testing.T, traceID, spanID string) context.Context {
+	t.Helper()
+	tid, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		t.Fatalf("invalid trace ID: %v", err)
+	}
+	sid, err := trace.SpanIDFromHex(spanID)
+	if err != nil {
+		t.Fatalf("invalid span ID: %v", err)
+	}
+	return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+}
+
+// lastLine decodes the last log line written to buf
+func lastLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	var line map[string]any
+	if err := json.Unmarshal(lines[len(lines)-1], &line); err != nil {
+		t.Fatalf("failed to decode log line: %v", err)
+	}
+	return line
+}
+
+func TestTraceHook(t *testing.T) {
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID       = "00f067aa0ba902b7"
+		otherTraceID = "0af7651916cd43dd8448eb211c80319c"
+		otherSpanID  = "b7ad6b7169203331"
+	)
+	ctx := spanContext(t, traceID, spanID)
+	var buf bytes.Buffer
+	log := newTestLogger(&buf)
+
+	tests := []struct {
+		name    string
+		log     func()
+		traceID string
+		spanID  string
+	}{
+		{"no context", func() { log.Infof("message") }, "", ""},
+		{"context without span", func() { log.InfofContext(context.Background(), "message") }, "", ""},
+		{"context of the call", func() { log.ErrorfContext(ctx, "message") }, traceID, spanID},
+		{"bound context", func() { log.WithContext(ctx).WithField("key", "value").Warnf("message") }, traceID, spanID},
+		{
+			"context of the call over the bound one",
+			func() { log.WithContext(ctx).InfofContext(spanContext(t, otherTraceID, otherSpanID), "message") },
+			otherTraceID, otherSpanID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.log()
+			line := lastLine(t, &buf)
+			gotTrace, _ := line["trace_id"].(string)
+			gotSpan, _ := line["span_id"].(string)
+			if gotTrace != tt.traceID || gotSpan != tt.spanID {
+				t.Errorf("logged trace %q span %q, want %q %q", gotTrace, gotSpan, tt.traceID, tt.spanID)
+			}
+		})
+	}
+}
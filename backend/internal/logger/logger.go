@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -60,13 +61,15 @@ func New(config Config) *ServiceLogger {
 		logger = zerolog.New(consoleWriter).With().
 			Timestamp().
 			Str("service", config.Service).
-			Logger()
+			Logger().
+			Hook(traceHook{})
 	} else {
 		// Use default JSON output to stdout
 		logger = zerolog.New(os.Stdout).With().
 			Timestamp().
 			Str("service", config.Service).
-			Logger()
+			Logger().
+			Hook(traceHook{})
 	}
 
 	return &ServiceLogger{
@@ -139,6 +142,26 @@ func (sl *ServiceLogger) Errorf(format string, args ...interface{}) {
 	sl.logger.Error().Msgf(format, args...)
 }
 
+// DebugfContext logs a formatted debug message with the trace of ctx
+func (sl *ServiceLogger) DebugfContext(ctx context.Context, format string, args ...interface{}) {
+	sl.logger.Debug().Ctx(ctx).Msgf(format, args...)
+}
+
+// InfofContext logs a formatted info message with the trace of ctx
+func (sl *ServiceLogger) InfofContext(ctx context.Context, format string, args ...interface{}) {
+	sl.logger.Info().Ctx(ctx).Msgf(format, args...)
+}
+
+// WarnfContext logs a formatted warning message with the trace of ctx
+func (sl *ServiceLogger) WarnfContext(ctx context.Context, format string, args ...interface{}) {
+	sl.logger.Warn().Ctx(ctx).Msgf(format, args...)
+}
+
+// ErrorfContext logs a formatted error message with the trace of ctx
+func (sl *ServiceLogger) ErrorfContext(ctx context.Context, format string, args ...interface{}) {
+	sl.logger.Error().Ctx(ctx).Msgf(format, args...)
+}
+
 // Fatal logs a fatal message and exits
 func (sl *ServiceLogger) Fatal(msg string) {
 	sl.logger.Fatal().Msg(msg)
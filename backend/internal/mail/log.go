@@ -0,0 +1,25 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/anish-chanda/ferna/internal/logger"
+)
+
+// LogSender writes messages to the service log instead of sending them, for development
+type LogSender struct {
+	logger *logger.ServiceLogger
+}
+
+// NewLogSender creates a sender that only logs messages
+func NewLogSender(logger *logger.ServiceLogger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg *Message) error {
+	s.logger.WithContext(ctx).WithFields(map[string]interface{}{
+		"to":      msg.To,
+		"subject": msg.Subject,
+	}).Infof("Mail not sent (log driver):\n%s", msg.Text)
+	return nil
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/logger"
+)
+
+// Message is a rendered email with a plain text and an HTML body
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers a single message
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Driver selects the Sender implementation
+type Driver string
+
+const (
+	DriverLog  Driver = "log"
+	DriverSMTP Driver = "smtp"
+)
+
+// Config represents mail configuration
+type Config struct {
+	Driver      Driver
+	From        string        // Sender address, e.g. "Ferna <no-reply@example.com>"
+	SMTP        SMTPConfig    // Used by the smtp driver
+	Interval    time.Duration // How often the outbox is polled
+	BatchSize   int           // Messages claimed per query
+	MaxAttempts int           // Attempts before a message is given up on
+}
+
+// NewSender creates the Sender selected by cfg.Driver
+func NewSender(cfg Config, logger *logger.ServiceLogger) (Sender, error) {
+	switch cfg.Driver {
+	case DriverLog:
+		return NewLogSender(logger), nil
+	case DriverSMTP:
+		return NewSMTPSender(cfg.SMTP, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// validateAddress checks that addr is a single bare email address
+func validateAddress(addr string) error {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil || parsed.Address != addr {
+		return fmt.Errorf("invalid email address %q", addr)
+	}
+	return nil
+}
+
+// HumanDuration formats a duration for emails, e.g. "1 hour" or "30 minutes"
+func HumanDuration(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(int(d.Round(time.Minute)/time.Minute), "minute")
+	}
+}
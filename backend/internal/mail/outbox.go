@@ -0,0 +1,228 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// tracer records outbox runs as traces, so their log lines can be found from one
+var tracer = otel.Tracer("github.com/anish-chanda/ferna/internal/mail")
+
+const (
+	// claimLease hides a claimed message from other workers; it must outlast sendTimeout
+	claimLease = 5 * time.Minute
+	// sendTimeout bounds a single Send call
+	sendTimeout = time.Minute
+	// baseRetryDelay is the delay after the first failed attempt, doubled on every further failure
+	baseRetryDelay = time.Minute
+	// maxRetryDelay caps the delay between attempts
+	maxRetryDelay = 6 * time.Hour
+)
+
+// Mailer renders messages into the outbox and sends them from a background
+// worker, so mail queued before a restart or during an SMTP outage is still sent
+type Mailer struct {
+	db       db.Store
+	config   Config
+	sender   Sender
+	renderer *Renderer
+	logger   *logger.ServiceLogger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+// NewMailer creates a mailer delivering through sender
+func NewMailer(database db.Store, config Config, sender Sender, logger *logger.ServiceLogger) (*Mailer, error) {
+	if config.Interval <= 0 {
+		return nil, errors.New("mail interval must be positive")
+	}
+	if config.BatchSize <= 0 {
+		return nil, errors.New("mail batch size must be positive")
+	}
+	if config.MaxAttempts <= 0 {
+		return nil, errors.New("mail max attempts must be positive")
+	}
+
+	renderer, err := NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mailer{
+		db:       database,
+		config:   config,
+		sender:   sender,
+		renderer: renderer,
+		logger:   logger.WithField("component", "mail"),
+	}, nil
+}
+
+// Enqueue renders the named template for to and stores it in the outbox
+func (m *Mailer) Enqueue(ctx context.Context, to, template string, data any) error {
+	if err := validateAddress(to); err != nil {
+		return err
+	}
+	msg, err := m.renderer.Render(template, to, data)
+	if err != nil {
+		return err
+	}
+
+	return m.db.EnqueueMail(ctx, &model.OutboundMail{
+		ID:       uuid.New(),
+		To:       msg.To,
+		Subject:  msg.Subject,
+		TextBody: msg.Text,
+		HTMLBody: msg.HTML,
+	})
+}
+
+// Start runs the outbox worker in a background goroutine until Stop is called
+func (m *Mailer) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.done != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+		m.run(ctx)
+	}()
+}
+
+// Stop signals the worker to stop and waits for the message being sent to
+// finish, or for ctx to expire. Unsent messages stay in the outbox.
+func (m *Mailer) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	cancel, done := m.cancel, m.done
+	m.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+		m.logger.Info("Mail worker stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("mail worker did not stop in time: %w", ctx.Err())
+	}
+}
+
+// run processes the outbox immediately and then on every tick until ctx is cancelled
+func (m *Mailer) run(ctx context.Context) {
+	m.logger.InfofContext(ctx, "Mail worker started - Driver: %s, Interval: %s", m.config.Driver, m.config.Interval)
+
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Process(ctx); err != nil && ctx.Err() == nil {
+			m.logger.ErrorfContext(ctx, "Mail processing failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process sends all pending messages that are due, stopping early when ctx is cancelled
+func (m *Mailer) Process(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "mail.process")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	for ctx.Err() == nil {
+		messages, err := m.db.ClaimMail(ctx, time.Now().UTC(), claimLease, m.config.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range messages {
+			// Finish a claimed message even during shutdown so it is not left leased
+			m.send(context.WithoutCancel(ctx), msg)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		if len(messages) < m.config.BatchSize {
+			break
+		}
+	}
+	return nil
+}
+
+// send delivers a claimed message and records the outcome
+func (m *Mailer) send(ctx context.Context, msg *model.OutboundMail) {
+	log := m.logger.WithContext(ctx).WithFields(map[string]interface{}{
+		"mail_id": msg.ID.String(),
+		"attempt": msg.Attempts,
+	})
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	err := m.sender.Send(sendCtx, &Message{
+		To:      msg.To,
+		Subject: msg.Subject,
+		Text:    msg.TextBody,
+		HTML:    msg.HTMLBody,
+	})
+	cancel()
+
+	if err == nil {
+		if err := m.db.MarkMailSent(ctx, msg.ID); err != nil {
+			// The lease will expire and the message is sent again
+			log.Errorf("Failed to mark mail as sent: %v", err)
+		}
+		return
+	}
+
+	var retryAt *time.Time
+	if msg.Attempts < m.config.MaxAttempts {
+		next := time.Now().UTC().Add(retryDelay(msg.Attempts))
+		retryAt = &next
+		log.Warnf("Sending mail failed, retrying at %s: %v", next.Format(time.RFC3339), err)
+	} else {
+		log.Errorf("Sending mail failed, giving up: %v", err)
+	}
+	if err := m.db.MarkMailFailed(ctx, msg.ID, err.Error(), retryAt); err != nil {
+		log.Errorf("Failed to record mail failure: %v", err)
+	}
+}
+
+// retryDelay returns the exponential backoff after the given number of attempts
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var Templates embed.FS
+
+// Names of the embedded templates. Each has a NAME.txt.tmpl defining the
+// subject and text body and a NAME.html.tmpl with the HTML body.
+const (
+	TemplateReminder      = "reminder"
+	TemplateVerifyEmail   = "verify_email"
+	TemplatePasswordReset = "password_reset"
+	TemplateAccountLocked = "account_locked"
+)
+
+// Renderer turns the embedded templates into messages
+type Renderer struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// NewRenderer parses all embedded templates
+func NewRenderer() (*Renderer, error) {
+	files, err := fs.Glob(Templates, "templates/*.txt.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mail templates: %w", err)
+	}
+
+	r := &Renderer{
+		text: make(map[string]*texttemplate.Template, len(files)),
+		html: make(map[string]*htmltemplate.Template, len(files)),
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(file, "templates/"), ".txt.tmpl")
+
+		text, err := texttemplate.ParseFS(Templates, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mail template %s: %w", file, err)
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("mail template %s does not define a subject", file)
+		}
+		html, err := htmltemplate.ParseFS(Templates, "templates/"+name+".html.tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mail template %s: %w", name, err)
+		}
+
+		r.text[name] = text
+		r.html[name] = html
+	}
+	return r, nil
+}
+
+// Render executes the named template with data into a message addressed to to
+func (r *Renderer) Render(name, to string, data any) (*Message, error) {
+	text, ok := r.text[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown mail template %q", name)
+	}
+
+	var subject, textBody, htmlBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+	if err := text.Execute(&textBody, data); err != nil {
+		return nil, fmt.Errorf("failed to render text of %s: %w", name, err)
+	}
+	if err := r.html[name].Execute(&htmlBody, data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML of %s: %w", name, err)
+	}
+
+	return &Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    textBody.String(),
+		HTML:    htmlBody.String(),
+	}, nil
+}
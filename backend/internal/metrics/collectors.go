@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector exposes pgx connection pool statistics, read on every scrape
+type poolCollector struct {
+	stats func() *pgxpool.Stat
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquires             *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	canceledAcquires     *prometheus.Desc
+	emptyAcquires        *prometheus.Desc
+	newConns             *prometheus.Desc
+	maxLifetimeDestroyed *prometheus.Desc
+	maxIdleDestroyed     *prometheus.Desc
+}
+
+// NewPoolCollector creates a collector for the pool statistics returned by stats,
+// such as PostgresDB.Stats. SQLite databases have no pool to report on.
+func NewPoolCollector(stats func() *pgxpool.Stat) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		stats:                stats,
+		acquiredConns:        desc("acquired_conns", "Connections currently in use."),
+		idleConns:            desc("idle_conns", "Idle connections in the pool."),
+		constructingConns:    desc("constructing_conns", "Connections currently being established."),
+		totalConns:           desc("total_conns", "Connections in the pool, in use or idle."),
+		maxConns:             desc("max_conns", "Maximum size of the pool."),
+		acquires:             desc("acquires_total", "Successful connection acquisitions."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Time spent waiting for connections."),
+		canceledAcquires:     desc("canceled_acquires_total", "Acquisitions cancelled by their context."),
+		emptyAcquires:        desc("empty_acquires_total", "Acquisitions that had to wait because the pool was empty."),
+		newConns:             desc("new_conns_total", "Connections opened."),
+		maxLifetimeDestroyed: desc("max_lifetime_destroyed_total", "Connections closed for exceeding their maximum lifetime."),
+		maxIdleDestroyed:     desc("max_idle_destroyed_total", "Connections closed for exceeding their maximum idle time."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(c.acquiredConns, float64(s.AcquiredConns()))
+	gauge(c.idleConns, float64(s.IdleConns()))
+	gauge(c.constructingConns, float64(s.ConstructingConns()))
+	gauge(c.totalConns, float64(s.TotalConns()))
+	gauge(c.maxConns, float64(s.MaxConns()))
+	counter(c.acquires, float64(s.AcquireCount()))
+	counter(c.acquireDuration, s.AcquireDuration().Seconds())
+	counter(c.canceledAcquires, float64(s.CanceledAcquireCount()))
+	counter(c.emptyAcquires, float64(s.EmptyAcquireCount()))
+	counter(c.newConns, float64(s.NewConnsCount()))
+	counter(c.maxLifetimeDestroyed, float64(s.MaxLifetimeDestroyCount()))
+	counter(c.maxIdleDestroyed, float64(s.MaxIdleDestroyCount()))
+}
+
+// VersionFunc returns the current migration version and whether the last migration failed halfway
+type VersionFunc func(ctx context.Context) (version uint, dirty bool, err error)
+
+// migrationCollector exposes the schema migration version, read on every scrape
+type migrationCollector struct {
+	version VersionFunc
+
+	versionDesc *prometheus.Desc
+	dirtyDesc   *prometheus.Desc
+}
+
+// NewMigrationCollector creates a collector for the migration version returned by version
+func NewMigrationCollector(version VersionFunc) prometheus.Collector {
+	return &migrationCollector{
+		version:     version,
+		versionDesc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "migration", "version"), "Current database schema migration version.", nil, nil),
+		dirtyDesc:   prometheus.NewDesc(prometheus.BuildFQName(namespace, "migration", "dirty"), "1 if the last schema migration failed halfway.", nil, nil),
+	}
+}
+
+// Describe lists the descriptors up front, collecting would query the database
+func (c *migrationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.versionDesc
+	ch <- c.dirtyDesc
+}
+
+func (c *migrationCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	version, dirty, err := c.version(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.versionDesc, err)
+		return
+	}
+	dirtyValue := 0.0
+	if dirty {
+		dirtyValue = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.versionDesc, prometheus.GaugeValue, float64(version))
+	ch <- prometheus.MustNewConstMetric(c.dirtyDesc, prometheus.GaugeValue, dirtyValue)
+}
@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "ferna"
+
+// Registry holds all ferna metrics together with the Go runtime and process
+// collectors. A dedicated registry keeps metrics of libraries that register
+// with the global one out of the endpoint.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	// RemindersQueued counts reminder deliveries queued for due care tasks
+	RemindersQueued = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reminders",
+		Name:      "queued_total",
+		Help:      "Reminder deliveries queued for due care tasks.",
+	})
+
+	// ReminderDeliveries counts reminder delivery attempts by channel and outcome
+	ReminderDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reminders",
+		Name:      "deliveries_total",
+		Help:      "Reminder delivery attempts by channel and outcome (sent, retry, failed, cancelled).",
+	}, []string{"channel", "outcome"})
+
+	// ReminderDispatchErrors counts dispatch runs that failed as a whole, e.g. on database errors
+	ReminderDispatchErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reminders",
+		Name:      "dispatch_errors_total",
+		Help:      "Reminder dispatch runs that failed.",
+	})
+
+	// Argon2Duration observes how long password hashing and verification take
+	Argon2Duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "argon2_duration_seconds",
+		Help:      "Duration of Argon2id computations by operation (hash, verify).",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+)
+
+// Outcomes of a reminder delivery attempt
+const (
+	OutcomeSent      = "sent"
+	OutcomeRetry     = "retry"
+	OutcomeFailed    = "failed"
+	OutcomeCancelled = "cancelled"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		httpInFlight,
+		RemindersQueued,
+		ReminderDeliveries,
+		ReminderDispatchErrors,
+		Argon2Duration,
+	)
+}
+
+// Handler serves the metrics of Registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Instrument records request counts and latencies per route pattern. It has to
+// wrap the ServeMux directly, as the mux stores the matched pattern on the
+// request it is given.
+func Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		httpInFlight.Inc()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		defer func() {
+			httpInFlight.Dec()
+			status := rec.status
+			if rvr := recover(); rvr != nil {
+				// Recovery further out answers with a 500
+				status = http.StatusInternalServerError
+				defer panic(rvr)
+			}
+
+			// Unmatched paths are grouped so scanners cannot blow up the label set
+			route := r.Pattern
+			if _, path, found := strings.Cut(route, " "); found {
+				// The method is a label of its own
+				route = path
+			}
+			if route == "" {
+				route = "unmatched"
+			}
+			httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+			httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// statusRecorder remembers the status code written by the wrapped handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
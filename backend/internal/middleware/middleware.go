@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	// RequestIDHeader carries the ID correlating a request across services and log lines
+	RequestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds request IDs accepted from clients
+	maxRequestIDLength = 128
+)
+
+// Middleware wraps an http.Handler
+type Middleware func(http.Handler) http.Handler
+
+// Chain applies middlewares to h so that the first one runs first
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+type requestIDKey struct{}
+
+// RequestID keeps a valid X-Request-ID sent by the client or a proxy and
+// assigns a new one otherwise. The ID is echoed in the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestIDFromContext returns the ID of the request ctx belongs to, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts IDs of printable ASCII that cannot break log lines or headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLog puts a logger carrying the request ID, trace, method and path into
+// the request context and logs every request once it is done, with its status,
+// size, latency and, if it was authenticated, the user.
+func AccessLog(base *logger.ServiceLogger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			reqLogger := base.WithContext(r.Context()).WithFields(map[string]interface{}{
+				"request_id": RequestIDFromContext(r.Context()),
+				"method":     r.Method,
+				"path":       r.URL.Path,
+			})
+			info := &requestInfo{}
+			ctx := logger.NewContext(r.Context(), reqLogger)
+			ctx = context.WithValue(ctx, requestInfoKey{}, info)
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			fields := map[string]interface{}{
+				"status":      rec.status,
+				"bytes":       rec.bytes,
+				"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+				"remote_addr": r.RemoteAddr,
+			}
+			if info.userID != "" {
+				fields["user_id"] = info.userID
+			}
+			entry := reqLogger.WithFields(fields)
+			msg := r.Method + " " + r.URL.Path
+			if rec.status >= http.StatusInternalServerError {
+				entry.Error(msg)
+			} else {
+				entry.Info(msg)
+			}
+		})
+	}
+}
+
+type requestInfoKey struct{}
+
+// requestInfo collects details learned by inner handlers for the access log
+type requestInfo struct {
+	userID string
+}
+
+// SetUser records the authenticated user of a request for the access log and
+// adds it to the request logger. Returns r with the updated logger.
+func SetUser(r *http.Request, userID string) *http.Request {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		info.userID = userID
+	}
+	reqLogger := logger.FromContext(r.Context(), nil)
+	if reqLogger == nil {
+		return r
+	}
+	return r.WithContext(logger.NewContext(r.Context(), reqLogger.WithField("user_id", userID)))
+}
+
+// Recover turns a panicking handler into a JSON 500 response and logs the
+// panic with its stack trace instead of dropping the connection
+func Recover(fallback *logger.ServiceLogger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rvr := recover()
+				if rvr == nil {
+					return
+				}
+				// The server uses this panic to abort a response on purpose
+				if err, ok := rvr.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rvr)
+				}
+
+				logger.FromContext(r.Context(), fallback).
+					WithField("stack", string(debug.Stack())).
+					Errorf("Panic serving %s %s: %v", r.Method, r.URL.Path, rvr)
+
+				if rec, ok := w.(*responseRecorder); ok && rec.wroteHeader {
+					// Too late for an error response, make the client see the failure
+					panic(http.ErrAbortHandler)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"message": "Internal server error",
+				})
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// responseRecorder remembers the status and size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
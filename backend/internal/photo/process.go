@@ -0,0 +1,193 @@
+package photo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const (
+	// ThumbnailSize is the maximum width and height of generated thumbnails
+	ThumbnailSize = 320
+	// MaxPixels bounds decoded image size to guard against decompression bombs.
+	// A decoded image takes up to 4 bytes per pixel, twice that while rotating.
+	MaxPixels = 24_000_000
+	// MaxConcurrent bounds how many images are decoded at once, which bounds
+	// the memory taken by uploads regardless of how many arrive together
+	MaxConcurrent = 2
+
+	jpegQuality = 90
+)
+
+// ErrUnsupportedType is returned for uploads that are not JPEG, PNG or WebP images
+var ErrUnsupportedType = errors.New("unsupported image type, expected JPEG, PNG or WebP")
+
+// slots holds a token for each image being processed
+var slots = make(chan struct{}, MaxConcurrent)
+
+// Processed is a sanitized photo and its thumbnail
+type Processed struct {
+	Data        []byte
+	ContentType string
+	Thumbnail   []byte // always JPEG
+	Width       int
+	Height      int
+}
+
+// Process sniffs the content type of an uploaded image, applies its EXIF
+// orientation and re-encodes it, which drops EXIF and all other metadata
+// such as GPS coordinates. PNG stays PNG, JPEG and WebP become JPEG.
+//
+// At most MaxConcurrent images are decoded at once, others wait for their turn
+// until ctx is done.
+func Process(ctx context.Context, data []byte) (*Processed, error) {
+	contentType := http.DetectContentType(data)
+
+	var decode func([]byte) (image.Image, error)
+	var decodeConfig func([]byte) (image.Config, error)
+	switch contentType {
+	case "image/jpeg":
+		decode = func(b []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return jpeg.DecodeConfig(bytes.NewReader(b)) }
+	case "image/png":
+		decode = func(b []byte) (image.Image, error) { return png.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return png.DecodeConfig(bytes.NewReader(b)) }
+	case "image/webp":
+		decode = func(b []byte) (image.Image, error) { return webp.Decode(bytes.NewReader(b)) }
+		decodeConfig = func(b []byte) (image.Config, error) { return webp.DecodeConfig(bytes.NewReader(b)) }
+	default:
+		return nil, ErrUnsupportedType
+	}
+
+	cfg, err := decodeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, fmt.Errorf("image dimensions %dx%d are not allowed", cfg.Width, cfg.Height)
+	}
+
+	select {
+	case slots <- struct{}{}:
+		defer func() { <-slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	img, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	if contentType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	var out bytes.Buffer
+	outType := "image/jpeg"
+	if contentType == "image/png" {
+		outType = "image/png"
+		err = png.Encode(&out, img)
+	} else {
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, thumbnail(img, ThumbnailSize), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	bounds := img.Bounds()
+	return &Processed{
+		Data:        out.Bytes(),
+		ContentType: outType,
+		Thumbnail:   thumb.Bytes(),
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}, nil
+}
+
+// thumbnail scales img to fit within size x size, keeping its aspect ratio.
+// Transparent areas are flattened onto white since thumbnails are JPEG.
+func thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			h = max(1, h*size/w)
+			w = size
+		} else {
+			w = max(1, w*size/h)
+			h = size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
+
+// applyOrientation rotates and flips img so it displays upright once the EXIF
+// orientation tag is gone
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	src := toRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+w*4]
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			i := dy*dst.Stride + dx*4
+			copy(dst.Pix[i:i+4], row[x*4:x*4+4])
+		}
+	}
+	return dst
+}
+
+// toRGBA returns img as an RGBA image with its origin at 0,0, converting it
+// with the fast paths of draw for the types the decoders return
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, b.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,197 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	// DefaultFCMEndpoint is the base URL of the FCM HTTP v1 API
+	DefaultFCMEndpoint = "https://fcm.googleapis.com"
+	// fcmScope is the OAuth scope needed to send messages
+	fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+	// maxErrorBody bounds how much of an error response is read
+	maxErrorBody = 64 * 1024
+)
+
+// ErrInvalidToken is returned when the provider reports that a device token is
+// no longer valid, e.g. because the app was uninstalled. Such tokens should be removed.
+var ErrInvalidToken = errors.New("push token is no longer valid")
+
+// Config represents FCM configuration
+type Config struct {
+	// Endpoint is the base URL of the FCM API, overridable to test against a fake server
+	Endpoint string
+	// ProjectID is the Firebase project, defaults to the project of the service account
+	ProjectID string
+	// CredentialsFile is the path of a Google service account JSON key. When it is
+	// empty requests are sent without authorization, which only a fake server accepts.
+	CredentialsFile string
+}
+
+// Message is a notification shown on the device together with data for the app
+type Message struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// FCMClient sends push notifications through the FCM HTTP v1 API.
+// FCM delivers to both Android and iOS (via APNs) tokens obtained from the Firebase SDK.
+type FCMClient struct {
+	sendURL string
+	client  *http.Client
+}
+
+// serviceAccount holds the fields of a service account key needed for the token exchange
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewFCMClient creates an FCM client from cfg
+func NewFCMClient(cfg Config) (*FCMClient, error) {
+	endpoint := strings.TrimRight(cfg.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = DefaultFCMEndpoint
+	}
+	projectID := cfg.ProjectID
+	client := &http.Client{Timeout: 15 * time.Second}
+
+	if cfg.CredentialsFile != "" {
+		data, err := os.ReadFile(cfg.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read FCM credentials: %w", err)
+		}
+		var account serviceAccount
+		if err := json.Unmarshal(data, &account); err != nil {
+			return nil, fmt.Errorf("failed to parse FCM credentials: %w", err)
+		}
+		if account.ClientEmail == "" || account.PrivateKey == "" || account.TokenURI == "" {
+			return nil, errors.New("FCM credentials must contain client_email, private_key and token_uri")
+		}
+		if projectID == "" {
+			projectID = account.ProjectID
+		}
+
+		jwtConfig := &jwt.Config{
+			Email:      account.ClientEmail,
+			PrivateKey: []byte(account.PrivateKey),
+			Scopes:     []string{fcmScope},
+			TokenURL:   account.TokenURI,
+		}
+		client = jwtConfig.Client(context.Background())
+		client.Timeout = 15 * time.Second
+	}
+
+	if projectID == "" {
+		return nil, errors.New("FCM project ID is required")
+	}
+
+	return &FCMClient{
+		sendURL: fmt.Sprintf("%s/v1/projects/%s/messages:send", endpoint, url.PathEscape(projectID)),
+		client:  client,
+	}, nil
+}
+
+// fcmRequest is the body of a messages:send call
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      map[string]any    `json:"android,omitempty"`
+	APNS         map[string]any    `json:"apns,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// fcmErrorResponse is the error body returned by the FCM API
+type fcmErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			Type      string `json:"@type"`
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// Send delivers msg to a single device token. It returns ErrInvalidToken when
+// the token should be discarded and another error when the send may be retried.
+func (c *FCMClient) Send(ctx context.Context, token string, msg Message) error {
+	body, err := json.Marshal(fcmRequest{Message: fcmMessage{
+		Token:        token,
+		Notification: fcmNotification{Title: msg.Title, Body: msg.Body},
+		Data:         msg.Data,
+		Android:      map[string]any{"priority": "high"},
+		APNS:         map[string]any{"headers": map[string]string{"apns-priority": "10"}},
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to encode FCM message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.sendURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create FCM request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("FCM request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	var fcmErr fcmErrorResponse
+	_ = json.Unmarshal(raw, &fcmErr)
+
+	if isInvalidToken(resp.StatusCode, fcmErr) {
+		return ErrInvalidToken
+	}
+	if fcmErr.Error.Message != "" {
+		return fmt.Errorf("FCM returned %d %s: %s", resp.StatusCode, fcmErr.Error.Status, fcmErr.Error.Message)
+	}
+	return fmt.Errorf("FCM returned %d", resp.StatusCode)
+}
+
+// isInvalidToken reports whether an FCM error means the token will never work again
+func isInvalidToken(status int, resp fcmErrorResponse) bool {
+	for _, detail := range resp.Error.Details {
+		switch detail.ErrorCode {
+		case "UNREGISTERED", "SENDER_ID_MISMATCH":
+			return true
+		case "INVALID_ARGUMENT":
+			// Also used for malformed payloads, which are not the token's fault
+			return strings.Contains(strings.ToLower(resp.Error.Message), "registration token")
+		}
+	}
+	return status == http.StatusNotFound && resp.Error.Status == "NOT_FOUND"
+}
@@ -0,0 +1,195 @@
+package registration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/auth"
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// Mode decides who can sign up for a new account
+type Mode string
+
+const (
+	ModeOpen    Mode = "open"    // Anyone can sign up
+	ModeClosed  Mode = "closed"  // Nobody can sign up, admins create accounts with the admin command
+	ModeInvite  Mode = "invite"  // Sign up requires an invite code minted by an admin
+	ModeDomains Mode = "domains" // Sign up requires an address of an allowed domain or an invite code
+)
+
+const (
+	// InviteCodePrefix starts every invite code so it can be told apart from other tokens
+	InviteCodePrefix = "invite_"
+	// inviteVisibleChars is how much of the random part is kept as the visible prefix
+	inviteVisibleChars = 6
+)
+
+// Errors returned when a sign up is refused. Their messages are shown to the client.
+var (
+	ErrClosed           = errors.New("registration is closed on this instance")
+	ErrInviteRequired   = errors.New("an invite code is required to sign up")
+	ErrInvalidInvite    = errors.New("invite code is invalid, expired or used up")
+	ErrDomainNotAllowed = errors.New("sign up is limited to email addresses of approved domains, ask an administrator for an invite code")
+)
+
+// Refused reports whether err is a refusal of the registration policy rather
+// than a failure to check it
+func Refused(err error) bool {
+	return errors.Is(err, ErrClosed) || errors.Is(err, ErrInviteRequired) ||
+		errors.Is(err, ErrInvalidInvite) || errors.Is(err, ErrDomainNotAllowed)
+}
+
+// Config holds registration configuration
+type Config struct {
+	Mode           Mode
+	AllowedDomains []string // Email domains that can sign up in ModeDomains
+}
+
+// Validate checks that the configuration can be used
+func (c Config) Validate() error {
+	switch c.Mode {
+	case ModeOpen, ModeClosed, ModeInvite:
+	case ModeDomains:
+		if len(c.AllowedDomains) == 0 {
+			return errors.New("allowed email domains are required in domains mode")
+		}
+		for _, domain := range c.AllowedDomains {
+			if domain == "" || strings.ContainsAny(domain, "@ ") {
+				return fmt.Errorf("invalid allowed email domain %q", domain)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown registration mode %q", c.Mode)
+	}
+	return nil
+}
+
+// Policy decides whether a new account can be created. On top of the
+// configured mode, admins can close registration at runtime through the
+// instance settings; they cannot open it when the mode is closed.
+type Policy struct {
+	db     db.Store
+	config Config
+	logger *logger.ServiceLogger
+}
+
+// NewPolicy creates a Policy for the configured registration mode
+func NewPolicy(database db.Store, config Config, logger *logger.ServiceLogger) (*Policy, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	domains := make([]string, len(config.AllowedDomains))
+	for i, domain := range config.AllowedDomains {
+		domains[i] = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	}
+	config.AllowedDomains = domains
+
+	return &Policy{
+		db:     database,
+		config: config,
+		logger: logger.WithField("component", "registration"),
+	}, nil
+}
+
+// Mode returns the configured registration mode
+func (p *Policy) Mode() Mode {
+	return p.config.Mode
+}
+
+// Admit checks whether an account for email can be created, redeeming
+// inviteCode where one is needed. The returned release function gives the use
+// of the code back and must be called if the account is not created after
+// all. Refusals are one of the Err values of this package.
+//
+// The domain of email only admits it if emailTrusted is set, i.e. the address
+// is known to belong to the user or has to be verified before the account can
+// be used. Anyone could claim an address of an allowed domain otherwise.
+func (p *Policy) Admit(ctx context.Context, email, inviteCode string, emailTrusted bool) (release func(), err error) {
+	release = func() {}
+
+	settings, err := p.db.GetInstanceSettings(ctx)
+	if err != nil {
+		return release, err
+	}
+	if p.config.Mode == ModeClosed || !settings.RegistrationEnabled {
+		return release, ErrClosed
+	}
+
+	switch p.config.Mode {
+	case ModeOpen:
+		return release, nil
+	case ModeDomains:
+		if emailTrusted && p.allowsDomain(email) {
+			return release, nil
+		}
+		// An invite code lets admins make exceptions for other addresses
+		if strings.TrimSpace(inviteCode) == "" {
+			return release, ErrDomainNotAllowed
+		}
+	}
+
+	inviteCode = strings.TrimSpace(inviteCode)
+	if inviteCode == "" {
+		return release, ErrInviteRequired
+	}
+	invite, err := p.db.RedeemInviteCode(ctx, auth.HashToken(inviteCode), time.Now().UTC())
+	if err != nil {
+		return release, err
+	}
+	if invite == nil {
+		return release, ErrInvalidInvite
+	}
+
+	p.logger.InfofContext(ctx, "Invite code %s redeemed for %s", invite.ID, email)
+	return func() {
+		// The request context may be done by now
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := p.db.ReleaseInviteCode(releaseCtx, invite.ID); err != nil {
+			p.logger.ErrorfContext(ctx, "Failed to release invite code %s: %v", invite.ID, err)
+		}
+	}, nil
+}
+
+// allowsDomain reports whether email belongs to one of the allowed domains
+func (p *Policy) allowsDomain(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range p.config.AllowedDomains {
+		if domain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// NewInviteCode returns an invite code and the record to store for it, minted
+// by the admin createdBy. maxUses and expiresAt are unlimited when nil.
+func NewInviteCode(createdBy uuid.UUID, note *string, maxUses *int, expiresAt *time.Time) (string, *model.InviteCode, error) {
+	secret, _, err := auth.GenerateToken()
+	if err != nil {
+		return "", nil, err
+	}
+	code := InviteCodePrefix + secret
+
+	return code, &model.InviteCode{
+		ID:        uuid.New(),
+		Prefix:    code[:len(InviteCodePrefix)+inviteVisibleChars],
+		CodeHash:  auth.HashToken(code),
+		Note:      note,
+		MaxUses:   maxUses,
+		ExpiresAt: expiresAt,
+		CreatedBy: &createdBy,
+	}, nil
+}
@@ -0,0 +1,285 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/metrics"
+	"github.com/anish-chanda/ferna/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records dispatch runs as traces, with a span per delivery
+var tracer = otel.Tracer("github.com/anish-chanda/ferna/internal/reminders")
+
+const (
+	// claimLease hides a claimed delivery from other dispatchers; it must outlast sendTimeout
+	claimLease = 5 * time.Minute
+	// sendTimeout bounds a single Notify call
+	sendTimeout = 30 * time.Second
+	// baseRetryDelay is the delay after the first failed attempt, doubled on every further failure
+	baseRetryDelay = 30 * time.Second
+	// maxRetryDelay caps the delay between attempts
+	maxRetryDelay = time.Hour
+)
+
+// Config holds reminder dispatcher configuration
+type Config struct {
+	Enabled     bool          // Whether the dispatcher runs at all
+	Interval    time.Duration // How often due care tasks are checked
+	BatchSize   int           // Deliveries claimed per query
+	MaxAttempts int           // Attempts before a delivery is given up on
+	Lookback    time.Duration // Tasks that became due longer ago than this are not reminded
+}
+
+// Dispatcher periodically queues reminders for due care tasks and hands them
+// to the notifiers. Deliveries are recorded in the database, so every reminder
+// is sent at least once per channel even across restarts, and at most one
+// delivery exists per occurrence and channel.
+type Dispatcher struct {
+	db        db.Store
+	config    Config
+	logger    *logger.ServiceLogger
+	notifiers map[string]Notifier
+	channels  []string
+
+	heartbeat atomic.Int64 // Unix nanoseconds of the last sign of life of the worker
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+// NewDispatcher creates a dispatcher delivering through the given notifiers
+func NewDispatcher(database db.Store, config Config, logger *logger.ServiceLogger, notifiers ...Notifier) (*Dispatcher, error) {
+	if config.Interval <= 0 {
+		return nil, errors.New("reminder interval must be positive")
+	}
+	if config.BatchSize <= 0 {
+		return nil, errors.New("reminder batch size must be positive")
+	}
+	if config.MaxAttempts <= 0 {
+		return nil, errors.New("reminder max attempts must be positive")
+	}
+
+	d := &Dispatcher{
+		db:        database,
+		config:    config,
+		logger:    logger.WithField("component", "reminders"),
+		notifiers: make(map[string]Notifier, len(notifiers)),
+	}
+	for _, n := range notifiers {
+		if _, exists := d.notifiers[n.Channel()]; exists {
+			return nil, fmt.Errorf("duplicate notifier for channel %q", n.Channel())
+		}
+		d.notifiers[n.Channel()] = n
+		d.channels = append(d.channels, n.Channel())
+	}
+	return d, nil
+}
+
+// Start runs the dispatcher in a background goroutine until Stop is called
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+
+	go func() {
+		defer close(d.done)
+		d.run(ctx)
+	}()
+}
+
+// Stop signals the dispatcher to stop and waits for the reminder being sent to
+// finish, or for ctx to expire. Unfinished deliveries are retried on the next start.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	cancel, done := d.cancel, d.done
+	d.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+		d.logger.Info("Reminder dispatcher stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("reminder dispatcher did not stop in time: %w", ctx.Err())
+	}
+}
+
+// run dispatches once immediately and then on every tick until ctx is cancelled
+func (d *Dispatcher) run(ctx context.Context) {
+	d.logger.InfofContext(ctx, "Reminder dispatcher started - Interval: %s, Channels: %v", d.config.Interval, d.channels)
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		d.beat()
+		if err := d.Dispatch(ctx); err != nil && ctx.Err() == nil {
+			metrics.ReminderDispatchErrors.Inc()
+			d.logger.ErrorfContext(ctx, "Reminder dispatch failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch queues reminders for care tasks that are due now and delivers all
+// pending reminders, stopping early when ctx is cancelled
+func (d *Dispatcher) Dispatch(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "reminders.dispatch")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	now := time.Now().UTC()
+
+	queued, err := d.db.EnqueueDueReminders(ctx, d.channels, now, d.config.Lookback)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.Int64("reminders.queued", queued))
+	if queued > 0 {
+		metrics.RemindersQueued.Add(float64(queued))
+		d.logger.DebugfContext(ctx, "Queued %d reminder deliveries", queued)
+	}
+
+	for ctx.Err() == nil {
+		reminders, err := d.db.ClaimReminders(ctx, time.Now().UTC(), claimLease, d.config.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, reminder := range reminders {
+			d.beat()
+			// Finish a claimed delivery even during shutdown so it is not left leased
+			d.deliver(context.WithoutCancel(ctx), reminder)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		if len(reminders) < d.config.BatchSize {
+			break
+		}
+	}
+	return nil
+}
+
+// deliver sends a claimed reminder through its channel and records the outcome
+func (d *Dispatcher) deliver(ctx context.Context, reminder *model.Reminder) {
+	ctx, span := tracer.Start(ctx, "reminders.deliver", trace.WithAttributes(
+		attribute.String("reminders.delivery_id", reminder.DeliveryID.String()),
+		attribute.String("reminders.channel", reminder.Channel),
+		attribute.Int("reminders.attempt", reminder.Attempts),
+	))
+	defer span.End()
+
+	log := d.logger.WithContext(ctx).WithFields(map[string]interface{}{
+		"delivery_id": reminder.DeliveryID.String(),
+		"channel":     reminder.Channel,
+		"attempt":     reminder.Attempts,
+	})
+
+	if !reminder.Current {
+		metrics.ReminderDeliveries.WithLabelValues(reminder.Channel, metrics.OutcomeCancelled).Inc()
+		if err := d.db.MarkReminderCancelled(ctx, reminder.DeliveryID); err != nil {
+			log.Errorf("Failed to cancel stale reminder: %v", err)
+		}
+		return
+	}
+
+	notifier, ok := d.notifiers[reminder.Channel]
+	if !ok {
+		// The channel was disabled after the delivery was queued
+		metrics.ReminderDeliveries.WithLabelValues(reminder.Channel, metrics.OutcomeFailed).Inc()
+		if err := d.db.MarkReminderFailed(ctx, reminder.DeliveryID, "no notifier for channel", nil); err != nil {
+			log.Errorf("Failed to record reminder failure: %v", err)
+		}
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	err := notifier.Notify(sendCtx, reminder)
+	cancel()
+
+	if err == nil {
+		metrics.ReminderDeliveries.WithLabelValues(reminder.Channel, metrics.OutcomeSent).Inc()
+		if err := d.db.MarkReminderSent(ctx, reminder.DeliveryID); err != nil {
+			// The lease will expire and the reminder is sent again, which at-least-once allows
+			log.Errorf("Failed to mark reminder as sent: %v", err)
+		}
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+
+	var retryAt *time.Time
+	if reminder.Attempts < d.config.MaxAttempts {
+		next := time.Now().UTC().Add(retryDelay(reminder.Attempts))
+		retryAt = &next
+		metrics.ReminderDeliveries.WithLabelValues(reminder.Channel, metrics.OutcomeRetry).Inc()
+		log.Warnf("Reminder delivery failed, retrying at %s: %v", next.Format(time.RFC3339), err)
+	} else {
+		metrics.ReminderDeliveries.WithLabelValues(reminder.Channel, metrics.OutcomeFailed).Inc()
+		log.Errorf("Reminder delivery failed, giving up: %v", err)
+	}
+	if err := d.db.MarkReminderFailed(ctx, reminder.DeliveryID, err.Error(), retryAt); err != nil {
+		log.Errorf("Failed to record reminder failure: %v", err)
+	}
+}
+
+// Heartbeat returns when the worker last showed it is alive, the zero time if
+// it never ran. A running worker beats at least once per interval and per delivery.
+func (d *Dispatcher) Heartbeat() time.Time {
+	nanos := d.heartbeat.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// beat records a sign of life of the worker
+func (d *Dispatcher) beat() {
+	d.heartbeat.Store(time.Now().UnixNano())
+}
+
+// retryDelay returns the exponential backoff after the given number of attempts
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,47 @@
+package reminders
+
+import (
+	"context"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/mail"
+	"github.com/anish-chanda/ferna/internal/schedule"
+	"github.com/anish-chanda/ferna/model"
+)
+
+// EmailNotifier queues reminders in the mail outbox, which takes care of retries
+type EmailNotifier struct {
+	db     db.Store
+	mailer *mail.Mailer
+}
+
+// NewEmailNotifier creates a notifier delivering through mailer
+func NewEmailNotifier(database db.Store, mailer *mail.Mailer) *EmailNotifier {
+	return &EmailNotifier{db: database, mailer: mailer}
+}
+
+func (n *EmailNotifier) Channel() string {
+	return "email"
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, reminder *model.Reminder) error {
+	user, err := n.db.GetUserByID(ctx, reminder.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	// Show the due time on the user's own clock
+	loc, _ := schedule.LoadLocation(user.Timezone)
+	title, body := reminderText(reminder)
+
+	return n.mailer.Enqueue(ctx, user.Email, mail.TemplateReminder, map[string]any{
+		"Name":      user.FullName,
+		"Title":     title,
+		"Body":      body,
+		"PlantName": reminder.PlantName,
+		"DueAt":     reminder.DueAt.In(loc).Format("Mon, 2 Jan 2006 15:04 MST"),
+	})
+}
@@ -0,0 +1,43 @@
+package reminders
+
+import (
+	"context"
+
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/model"
+)
+
+// Notifier delivers reminders over a single channel such as push or email.
+// Notify may be called more than once for the same reminder, so it should be
+// safe to repeat. A notifier with nothing to deliver to, for example a user
+// without registered devices, should return nil.
+type Notifier interface {
+	// Channel is the stable name stored with each delivery
+	Channel() string
+	Notify(ctx context.Context, reminder *model.Reminder) error
+}
+
+// LogNotifier writes reminders to the service log, useful in development
+type LogNotifier struct {
+	logger *logger.ServiceLogger
+}
+
+// NewLogNotifier creates a notifier that only logs reminders
+func NewLogNotifier(logger *logger.ServiceLogger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Channel() string {
+	return "log"
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, reminder *model.Reminder) error {
+	n.logger.WithContext(ctx).WithFields(map[string]interface{}{
+		"user_id":     reminder.UserID.String(),
+		"schedule_id": reminder.ScheduleID.String(),
+		"plant":       reminder.PlantName,
+		"task_type":   string(reminder.TaskType),
+		"due_at":      reminder.DueAt,
+	}).Info("Care task reminder")
+	return nil
+}
@@ -0,0 +1,87 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/push"
+	"github.com/anish-chanda/ferna/model"
+)
+
+// PushNotifier sends reminders to every device the user registered.
+// Tokens the provider rejects as invalid are removed.
+type PushNotifier struct {
+	db     db.Store
+	client *push.FCMClient
+	logger *logger.ServiceLogger
+}
+
+// NewPushNotifier creates a notifier delivering through FCM
+func NewPushNotifier(database db.Store, client *push.FCMClient, logger *logger.ServiceLogger) *PushNotifier {
+	return &PushNotifier{db: database, client: client, logger: logger}
+}
+
+func (n *PushNotifier) Channel() string {
+	return "push"
+}
+
+func (n *PushNotifier) Notify(ctx context.Context, reminder *model.Reminder) error {
+	devices, err := n.db.ListDevicesByUser(ctx, reminder.UserID)
+	if err != nil {
+		return err
+	}
+
+	title, body := reminderText(reminder)
+	msg := push.Message{
+		Title: title,
+		Body:  body,
+		Data: map[string]string{
+			"type":        "care_reminder",
+			"schedule_id": reminder.ScheduleID.String(),
+			"plant_id":    reminder.PlantID.String(),
+			"task_type":   string(reminder.TaskType),
+			"due_at":      reminder.DueAt.UTC().Format(time.RFC3339),
+		},
+	}
+
+	var errs []error
+	for _, device := range devices {
+		err := n.client.Send(ctx, device.Token, msg)
+		switch {
+		case err == nil:
+		case errors.Is(err, push.ErrInvalidToken):
+			n.logger.InfofContext(ctx, "Pruning invalid push token of device %s", device.ID)
+			if err := n.db.DeleteDeviceByToken(ctx, device.Token); err != nil {
+				n.logger.ErrorfContext(ctx, "Failed to prune device %s: %v", device.ID, err)
+			}
+		default:
+			errs = append(errs, fmt.Errorf("device %s: %w", device.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// careActions holds the verb and past participle used to phrase each care task type
+var careActions = map[model.CareTaskType][2]string{
+	model.CareTaskWater:     {"water", "watered"},
+	model.CareTaskFertilize: {"fertilize", "fertilized"},
+	model.CareTaskMist:      {"mist", "misted"},
+	model.CareTaskRepot:     {"repot", "repotted"},
+	model.CareTaskPrune:     {"prune", "pruned"},
+	model.CareTaskRotate:    {"rotate", "rotated"},
+	model.CareTaskClean:     {"clean", "cleaned"},
+}
+
+// reminderText returns the title and body of a reminder shown to the user
+func reminderText(reminder *model.Reminder) (string, string) {
+	action, ok := careActions[reminder.TaskType]
+	if !ok {
+		return "Time to care for " + reminder.PlantName, reminder.PlantName + " has a care task due."
+	}
+	return fmt.Sprintf("Time to %s %s", action[0], reminder.PlantName),
+		fmt.Sprintf("%s is due to be %s.", reminder.PlantName, action[1])
+}
@@ -0,0 +1,179 @@
+package reminders
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db/dbtest"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/push"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/google/uuid"
+)
+
+// fakeFCM is a stand-in for the FCM HTTP v1 API. Tokens are delivered to
+// unless an error response was set for them.
+type fakeFCM struct {
+	t *testing.T
+
+	mu        sync.Mutex
+	errors    map[string]string // FCM error body by token
+	delivered []string          // Tokens in delivery order
+}
+
+func (f *fakeFCM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/projects/ferna-test/messages:send" {
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		http.NotFound(w, r)
+		return
+	}
+	var req struct {
+		Message struct {
+			Token        string `json:"token"`
+			Notification struct {
+				Title string `json:"title"`
+			} `json:"notification"`
+			Data map[string]string `json:"data"`
+		} `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		f.t.Errorf("failed to decode FCM request: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Message.Notification.Title != "Time to water Fern" || req.Message.Data["type"] != "care_reminder" {
+		f.t.Errorf("unexpected message %+v", req.Message)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if body, ok := f.errors[req.Message.Token]; ok {
+		var status struct {
+			Error struct {
+				Code int `json:"code"`
+			} `json:"error"`
+		}
+		json.Unmarshal([]byte(body), &status)
+		w.WriteHeader(status.Error.Code)
+		fmt.Fprint(w, body)
+		return
+	}
+	f.delivered = append(f.delivered, req.Message.Token)
+	fmt.Fprintf(w, `{"name": "projects/ferna-test/messages/%d"}`, len(f.delivered))
+}
+
+// fail makes the server answer messages to token with the FCM error body
+func (f *fakeFCM) fail(token, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors[token] = body
+}
+
+// takeDelivered returns the sorted tokens delivered to since the last call
+func (f *fakeFCM) takeDelivered() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delivered := f.delivered
+	f.delivered = nil
+	sort.Strings(delivered)
+	return delivered
+}
+
+const (
+	unregisteredError = `{"error": {"code": 404, "message": "Requested entity was not found.", "status": "NOT_FOUND",
+		"details": [{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": "UNREGISTERED"}]}}`
+	invalidTokenError = `{"error": {"code": 400, "message": "The registration token is not a valid FCM registration token", "status": "INVALID_ARGUMENT",
+		"details": [{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": "INVALID_ARGUMENT"}]}}`
+	invalidPayloadError = `{"error": {"code": 400, "message": "Invalid value at 'message.data'", "status": "INVALID_ARGUMENT",
+		"details": [{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": "INVALID_ARGUMENT"}]}}`
+)
+
+// TestPushNotifierPrunesInvalidTokens checks that devices whose token FCM
+// reports as unregistered or invalid are deleted, and only those
+func TestPushNotifierPrunesInvalidTokens(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New(logger.Config{Level: "error"})
+	database := dbtest.NewSQLite(t)
+
+	fake := &fakeFCM{t: t, errors: map[string]string{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client, err := push.NewFCMClient(push.Config{Endpoint: server.URL, ProjectID: "ferna-test"})
+	if err != nil {
+		t.Fatalf("NewFCMClient failed: %v", err)
+	}
+	notifier := NewPushNotifier(database, client, log)
+
+	hash := "unused"
+	userID, err := database.CreateUser(ctx, &model.User{
+		ID:           uuid.New(),
+		AuthProvider: model.AuthProviderLocal,
+		Email:        "user@example.com",
+		FullName:     "User",
+		PasswordHash: &hash,
+		Timezone:     "UTC",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	tokens := []string{"token-invalid", "token-ok", "token-payload", "token-unregistered"}
+	for _, token := range tokens {
+		if err := database.UpsertDevice(ctx, &model.Device{ID: uuid.New(), UserID: userID, Token: token, Platform: model.DeviceAndroid}); err != nil {
+			t.Fatalf("UpsertDevice failed: %v", err)
+		}
+	}
+
+	reminder := &model.Reminder{
+		DeliveryID:   uuid.New(),
+		Channel:      "push",
+		ScheduleID:   uuid.New(),
+		OccurrenceAt: time.Now().UTC(),
+		DueAt:        time.Now().UTC(),
+		UserID:       userID,
+		PlantID:      uuid.New(),
+		PlantName:    "Fern",
+		TaskType:     model.CareTaskWater,
+		Current:      true,
+	}
+
+	// Every device receives the first reminder
+	if err := notifier.Notify(ctx, reminder); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if got := fake.takeDelivered(); fmt.Sprint(got) != fmt.Sprint(tokens) {
+		t.Fatalf("delivered to %v, want %v", got, tokens)
+	}
+
+	// Then the app is uninstalled from one device and another token turns invalid.
+	// A payload error is not the fault of the token and must not prune it.
+	fake.fail("token-unregistered", unregisteredError)
+	fake.fail("token-invalid", invalidTokenError)
+	fake.fail("token-payload", invalidPayloadError)
+	if err := notifier.Notify(ctx, reminder); err == nil {
+		t.Errorf("Notify ignored the payload error")
+	}
+	if got := fake.takeDelivered(); fmt.Sprint(got) != "[token-ok]" {
+		t.Errorf("delivered to %v, want [token-ok]", got)
+	}
+
+	devices, err := database.ListDevicesByUser(ctx, userID)
+	if err != nil {
+		t.Fatalf("ListDevicesByUser failed: %v", err)
+	}
+	var remaining []string
+	for _, device := range devices {
+		remaining = append(remaining, device.Token)
+	}
+	sort.Strings(remaining)
+	if fmt.Sprint(remaining) != "[token-ok token-payload]" {
+		t.Errorf("devices left %v, want [token-ok token-payload]", remaining)
+	}
+}
@@ -0,0 +1,94 @@
+package schedule
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidSnooze is returned when a snooze target is not after now or would
+// move the task past the following occurrence
+var ErrInvalidSnooze = errors.New("snooze must end after now and no later than the following occurrence")
+
+// ErrCompletedInFuture is returned when a completion time lies in the future
+var ErrCompletedInFuture = errors.New("completion time cannot be in the future")
+
+// State is the pending occurrence of a series. DueAt equals OccurrenceAt unless
+// the occurrence has been snoozed, in which case DueAt is later.
+//
+// The transitions below are pure functions of the series, the state and the
+// given times, so every client that applies them gets the same result.
+type State struct {
+	OccurrenceAt time.Time
+	DueAt        time.Time
+}
+
+// Snoozed reports whether the pending occurrence has been postponed
+func (st State) Snoozed() bool {
+	return st.DueAt.After(st.OccurrenceAt)
+}
+
+// Start returns the state of a series that starts being tracked at now:
+// the first occurrence at or after now.
+func Start(series Series, now time.Time) State {
+	occ := series.NextOnOrAfter(now)
+	return State{OccurrenceAt: occ, DueAt: occ}
+}
+
+// Complete marks the pending occurrence done at the given time. The next
+// occurrence is the first one strictly after both the pending occurrence and
+// the completion time, so completing late never leaves a backlog of missed
+// occurrences and completing early moves on to the following one.
+func Complete(series Series, st State, at, now time.Time) (State, error) {
+	if at.After(now) {
+		return State{}, ErrCompletedInFuture
+	}
+	after := st.OccurrenceAt
+	if at.After(after) {
+		after = at
+	}
+	occ := series.Next(after)
+	return State{OccurrenceAt: occ, DueAt: occ}, nil
+}
+
+// Skip drops exactly one occurrence, moving to the one right after it even if
+// that one is already overdue
+func Skip(series Series, st State) State {
+	occ := series.Next(st.OccurrenceAt)
+	return State{OccurrenceAt: occ, DueAt: occ}
+}
+
+// Snooze postpones the pending occurrence to until. The series itself is not
+// changed, and until may not pass the following occurrence.
+func Snooze(series Series, st State, until, now time.Time) (State, error) {
+	if !until.After(now) || until.After(series.Next(st.OccurrenceAt)) {
+		return State{}, ErrInvalidSnooze
+	}
+	return State{OccurrenceAt: st.OccurrenceAt, DueAt: until}, nil
+}
+
+// Reschedule restarts a series from its (new) anchor: the pending occurrence
+// becomes the first occurrence at or after now, and any snooze is dropped
+func Reschedule(series Series, now time.Time) State {
+	return Start(series, now)
+}
+
+// relocateSlack is subtracted before searching the new series so that a DST
+// shift cannot push the search past the occurrence it is looking for
+const relocateSlack = 3 * time.Hour
+
+// Relocate moves the pending occurrence to the same wall-clock time in the
+// location of to, for when the owner changes their timezone. A snooze is kept
+// as long as it is still valid for the moved occurrence.
+func Relocate(from, to Series, st State) State {
+	wall := st.OccurrenceAt.In(from.Loc)
+	y, m, d := wall.Date()
+	hour, min, sec := wall.Clock()
+	moved := time.Date(y, m, d, hour, min, sec, 0, to.Loc)
+
+	occ := to.NextOnOrAfter(moved.Add(-relocateSlack))
+	next := State{OccurrenceAt: occ, DueAt: occ}
+	if st.Snoozed() && st.DueAt.After(occ) && !st.DueAt.After(to.Next(occ)) {
+		next.DueAt = st.DueAt
+	}
+	return next
+}
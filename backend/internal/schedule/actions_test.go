@@ -0,0 +1,157 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// state builds a State from RFC 3339 instants
+func state(t *testing.T, occurrence, due string) State {
+	t.Helper()
+	return State{OccurrenceAt: utc(t, occurrence), DueAt: utc(t, due)}
+}
+
+// checkState compares a state with the expected occurrence and due time
+func checkState(t *testing.T, got State, occurrence, due string) {
+	t.Helper()
+	if !got.OccurrenceAt.Equal(utc(t, occurrence)) || !got.DueAt.Equal(utc(t, due)) {
+		t.Errorf("state = {%s %s}, want {%s %s}",
+			got.OccurrenceAt.UTC().Format(time.RFC3339), got.DueAt.UTC().Format(time.RFC3339), occurrence, due)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	series := mustSeries(t, "FREQ=DAILY", "2024-01-01 09:00", time.UTC)
+	pending := state(t, "2024-01-03T09:00:00Z", "2024-01-03T09:00:00Z")
+
+	tests := []struct {
+		name string
+		st   State
+		at   string
+		want string
+	}{
+		{"on time", pending, "2024-01-03T09:00:00Z", "2024-01-04T09:00:00Z"},
+		{"late skips the missed occurrences", pending, "2024-01-05T12:00:00Z", "2024-01-06T09:00:00Z"},
+		{"late on an occurrence", pending, "2024-01-05T09:00:00Z", "2024-01-06T09:00:00Z"},
+		{"early moves to the following occurrence", pending, "2024-01-02T20:00:00Z", "2024-01-04T09:00:00Z"},
+		{"snoozed", state(t, "2024-01-03T09:00:00Z", "2024-01-03T18:00:00Z"), "2024-01-03T18:30:00Z", "2024-01-04T09:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := utc(t, tt.at)
+			got, err := Complete(series, tt.st, at, at)
+			if err != nil {
+				t.Fatalf("Complete failed: %v", err)
+			}
+			checkState(t, got, tt.want, tt.want)
+		})
+	}
+}
+
+func TestCompleteInFuture(t *testing.T) {
+	series := mustSeries(t, "FREQ=DAILY", "2024-01-01 09:00", time.UTC)
+	pending := state(t, "2024-01-03T09:00:00Z", "2024-01-03T09:00:00Z")
+
+	_, err := Complete(series, pending, utc(t, "2024-01-03T09:01:00Z"), utc(t, "2024-01-03T09:00:00Z"))
+	if !errors.Is(err, ErrCompletedInFuture) {
+		t.Errorf("Complete in the future returned %v, want ErrCompletedInFuture", err)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	series := mustSeries(t, "FREQ=WEEKLY;BYDAY=MO,TH", "2024-01-01 09:00", time.UTC)
+
+	tests := []struct {
+		name string
+		st   State
+		want string
+	}{
+		{"pending", state(t, "2024-01-01T09:00:00Z", "2024-01-01T09:00:00Z"), "2024-01-04T09:00:00Z"},
+		// The following occurrence is the next one in the series, not after the snooze
+		{"past a snooze", state(t, "2024-01-01T09:00:00Z", "2024-01-04T09:00:00Z"), "2024-01-04T09:00:00Z"},
+		{"past a short snooze", state(t, "2024-01-04T09:00:00Z", "2024-01-05T09:00:00Z"), "2024-01-08T09:00:00Z"},
+		// Skipping an overdue occurrence drops exactly that one
+		{"overdue", state(t, "2024-01-04T09:00:00Z", "2024-01-04T09:00:00Z"), "2024-01-08T09:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Skip(series, tt.st)
+			checkState(t, got, tt.want, tt.want)
+			if got.Snoozed() {
+				t.Errorf("Skip kept the snooze")
+			}
+		})
+	}
+}
+
+func TestSnooze(t *testing.T) {
+	series := mustSeries(t, "FREQ=DAILY", "2024-01-01 09:00", time.UTC)
+	pending := state(t, "2024-01-03T09:00:00Z", "2024-01-03T09:00:00Z")
+	now := utc(t, "2024-01-03T10:00:00Z")
+
+	tests := []struct {
+		name    string
+		st      State
+		until   string
+		wantErr bool
+	}{
+		{"later today", pending, "2024-01-03T15:00:00Z", false},
+		{"until the following occurrence", pending, "2024-01-04T09:00:00Z", false},
+		{"past the following occurrence", pending, "2024-01-04T09:00:01Z", true},
+		{"until now", pending, "2024-01-03T10:00:00Z", true},
+		{"until the past", pending, "2024-01-03T09:30:00Z", true},
+		{"again", state(t, "2024-01-03T09:00:00Z", "2024-01-03T15:00:00Z"), "2024-01-03T20:00:00Z", false},
+		// A snooze may end before the current due time, keeping the occurrence
+		{"shorter than before", state(t, "2024-01-03T09:00:00Z", "2024-01-03T20:00:00Z"), "2024-01-03T12:00:00Z", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Snooze(series, tt.st, utc(t, tt.until), now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSnooze) {
+					t.Errorf("Snooze returned %v, want ErrInvalidSnooze", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Snooze failed: %v", err)
+			}
+			checkState(t, got, "2024-01-03T09:00:00Z", tt.until)
+			if !got.Snoozed() {
+				t.Errorf("Snoozed() = false after snoozing")
+			}
+		})
+	}
+}
+
+func TestReschedule(t *testing.T) {
+	tests := []struct {
+		name   string
+		series Series
+		now    string
+		want   string
+	}{
+		{"future anchor", mustSeries(t, "FREQ=WEEKLY", "2024-02-01 07:00", time.UTC), "2024-01-10T08:00:00Z", "2024-02-01T07:00:00Z"},
+		{"past anchor", mustSeries(t, "FREQ=DAILY", "2024-01-01 07:00", time.UTC), "2024-01-10T08:00:00Z", "2024-01-11T07:00:00Z"},
+		{"now on an occurrence", mustSeries(t, "FREQ=DAILY", "2024-01-01 07:00", time.UTC), "2024-01-10T07:00:00Z", "2024-01-10T07:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Reschedule(tt.series, utc(t, tt.now))
+			checkState(t, got, tt.want, tt.want)
+		})
+	}
+}
+
+func TestRelocate(t *testing.T) {
+	from := mustSeries(t, "FREQ=DAILY", "2024-01-01 09:00", time.UTC)
+	to := mustSeries(t, "FREQ=DAILY", "2024-01-01 09:00", mustLocation(t, "Europe/Berlin"))
+
+	got := Relocate(from, to, state(t, "2024-01-03T09:00:00Z", "2024-01-03T09:00:00Z"))
+	checkState(t, got, "2024-01-03T08:00:00Z", "2024-01-03T08:00:00Z")
+
+	// A snooze that still ends before the following occurrence is kept
+	got = Relocate(from, to, state(t, "2024-01-03T09:00:00Z", "2024-01-03T15:00:00Z"))
+	checkState(t, got, "2024-01-03T08:00:00Z", "2024-01-03T15:00:00Z")
+}
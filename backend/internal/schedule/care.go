@@ -0,0 +1,32 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anish-chanda/ferna/model"
+)
+
+// LoadLocation loads an IANA timezone. Empty or unknown names fall back to UTC,
+// in which case the error describes why.
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.UTC, fmt.Errorf("timezone is empty")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC, fmt.Errorf("unknown timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// SeriesFor builds the recurrence series of a care schedule in the owner's location
+func SeriesFor(s *model.CareSchedule, loc *time.Location) (Series, error) {
+	rule, err := ParseRule(s.RRule)
+	if err != nil {
+		return Series{}, err
+	}
+	return NewSeries(rule, s.Anchor.Time(), loc), nil
+}
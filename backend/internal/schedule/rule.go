@@ -0,0 +1,160 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the RRULE FREQ part supported by the schedule engine
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// MaxInterval bounds INTERVAL to keep occurrences within a sane range
+const MaxInterval = 1000
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Rule is the subset of RFC 5545 RRULE used for care schedules:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL and, for WEEKLY, BYDAY.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []time.Weekday
+}
+
+// IntervalRule returns a rule repeating every n days
+func IntervalRule(days int) Rule {
+	return Rule{Freq: Daily, Interval: days}
+}
+
+// ParseRule parses an RRULE string such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH".
+// An optional "RRULE:" prefix is accepted.
+func ParseRule(s string) (Rule, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.ToUpper(s), "RRULE:")
+	if s == "" {
+		return Rule{}, fmt.Errorf("rule is empty")
+	}
+
+	rule := Rule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[key] {
+			return Rule{}, fmt.Errorf("duplicate rule part %s", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			rule.Freq = Frequency(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return Rule{}, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return Rule{}, fmt.Errorf("invalid BYDAY value %q", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		default:
+			return Rule{}, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+	rule.ByDay = normalizeWeekdays(rule.ByDay)
+	return rule, nil
+}
+
+// Validate checks that the rule can be evaluated
+func (r Rule) Validate() error {
+	switch r.Freq {
+	case Daily, Weekly, Monthly, Yearly:
+	case "":
+		return fmt.Errorf("FREQ is required")
+	default:
+		return fmt.Errorf("unsupported FREQ %s", r.Freq)
+	}
+	if r.Interval < 1 || r.Interval > MaxInterval {
+		return fmt.Errorf("INTERVAL must be between 1 and %d", MaxInterval)
+	}
+	if len(r.ByDay) > 0 && r.Freq != Weekly {
+		return fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	return nil
+}
+
+// String returns the canonical RRULE representation of the rule
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range normalizeWeekdays(r.ByDay) {
+			for code, d := range weekdayCodes {
+				if d == day {
+					codes = append(codes, code)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// normalizeWeekdays sorts weekdays Monday first and removes duplicates
+func normalizeWeekdays(days []time.Weekday) []time.Weekday {
+	if len(days) == 0 {
+		return nil
+	}
+	seen := map[time.Weekday]bool{}
+	out := make([]time.Weekday, 0, len(days))
+	for _, d := range days {
+		if !seen[d] {
+			seen[d] = true
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return mondayOffset(out[i]) < mondayOffset(out[j])
+	})
+	return out
+}
+
+// mondayOffset returns the number of days since Monday for a weekday
+func mondayOffset(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
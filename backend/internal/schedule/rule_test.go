@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"FREQ=DAILY;INTERVAL=1", "FREQ=DAILY"},
+		{"rrule:freq=weekly;interval=2;byday=th,mo,mo", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{" FREQ=MONTHLY;INTERVAL=3; ", "FREQ=MONTHLY;INTERVAL=3"},
+		{"FREQ=YEARLY;INTERVAL=1000", "FREQ=YEARLY;INTERVAL=1000"},
+		{"FREQ=WEEKLY;BYDAY=SU,SA", "FREQ=WEEKLY;BYDAY=SA,SU"},
+	}
+	for _, tt := range tests {
+		rule, err := ParseRule(tt.in)
+		if err != nil {
+			t.Errorf("ParseRule(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("ParseRule(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"RRULE:",
+		"INTERVAL=2",
+		"FREQ",
+		"FREQ=",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;INTERVAL=1001",
+		"FREQ=DAILY;INTERVAL=two",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=MO,",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;COUNT=3",
+		"FREQ=DAILY;UNTIL=20250101T000000Z",
+	}
+	for _, in := range tests {
+		if rule, err := ParseRule(in); err == nil {
+			t.Errorf("ParseRule(%q) = %q, want error", in, rule)
+		}
+	}
+}
+
+func TestIntervalRule(t *testing.T) {
+	if got := IntervalRule(3).String(); got != "FREQ=DAILY;INTERVAL=3" {
+		t.Errorf("IntervalRule(3) = %q", got)
+	}
+	if err := IntervalRule(0).Validate(); err == nil {
+		t.Errorf("IntervalRule(0) is valid, want error")
+	}
+}
@@ -0,0 +1,184 @@
+package schedule
+
+import (
+	"time"
+)
+
+// Series is a recurrence rule anchored at a local wall-clock time in a location.
+//
+// Occurrences keep the anchor's wall-clock time across DST transitions. A time
+// that falls into a DST gap is moved forward by the length of the gap and an
+// ambiguous time during a DST overlap resolves to the earlier instant, as
+// described in RFC 5545. Monthly and yearly rules clamp the anchor's day of
+// month to the last day of shorter months.
+type Series struct {
+	Rule   Rule
+	Anchor time.Time // wall clock only, the zone of the value is ignored
+	Loc    *time.Location
+}
+
+// NewSeries creates a series, defaulting to UTC when loc is nil
+func NewSeries(rule Rule, anchor time.Time, loc *time.Location) Series {
+	if loc == nil {
+		loc = time.UTC
+	}
+	y, m, d := anchor.Date()
+	hour, min, sec := anchor.Clock()
+	return Series{Rule: rule, Anchor: time.Date(y, m, d, hour, min, sec, 0, time.UTC), Loc: loc}
+}
+
+// First returns the first occurrence of the series
+func (s Series) First() time.Time {
+	return s.occurrences(0)[0]
+}
+
+// Next returns the first occurrence strictly after t
+func (s Series) Next(t time.Time) time.Time {
+	for k := s.periodBefore(t); ; k++ {
+		for _, occ := range s.occurrences(k) {
+			if occ.After(t) {
+				return occ
+			}
+		}
+	}
+}
+
+// NextOnOrAfter returns the first occurrence at or after t
+func (s Series) NextOnOrAfter(t time.Time) time.Time {
+	return s.Next(t.Add(-time.Nanosecond))
+}
+
+// Between returns up to limit occurrences in the inclusive range [from, until]
+func (s Series) Between(from, until time.Time, limit int) []time.Time {
+	var out []time.Time
+	for occ := s.NextOnOrAfter(from); !occ.After(until) && len(out) < limit; occ = s.Next(occ) {
+		out = append(out, occ)
+	}
+	return out
+}
+
+// periodBefore returns a period index whose occurrences are not after t,
+// so iteration can start there instead of at the anchor
+func (s Series) periodBefore(t time.Time) int {
+	local := t.In(s.Loc)
+	ay, am, _ := s.Anchor.Date()
+	ly, lm, _ := local.Date()
+
+	var k int
+	switch s.Rule.Freq {
+	case Daily:
+		k = daysBetween(s.Anchor, local) / s.Rule.Interval
+	case Weekly:
+		k = daysBetween(s.weekStart(), local) / 7 / s.Rule.Interval
+	case Monthly:
+		k = ((ly-ay)*12 + int(lm-am)) / s.Rule.Interval
+	case Yearly:
+		k = (ly - ay) / s.Rule.Interval
+	}
+
+	// Step back one period to absorb DST shifts and partial periods
+	k--
+	if k < 0 {
+		return 0
+	}
+	return k
+}
+
+// occurrences returns the occurrences of period k in chronological order
+func (s Series) occurrences(k int) []time.Time {
+	y, m, d := s.Anchor.Date()
+	hour, min, sec := s.Anchor.Clock()
+
+	switch s.Rule.Freq {
+	case Weekly:
+		if len(s.Rule.ByDay) == 0 {
+			return []time.Time{s.resolve(y, m, d+7*k*s.Rule.Interval, hour, min, sec)}
+		}
+		wy, wm, wd := s.weekStart().Date()
+		wd += 7 * k * s.Rule.Interval
+		out := make([]time.Time, 0, len(s.Rule.ByDay))
+		for _, day := range s.Rule.ByDay {
+			date := time.Date(wy, wm, wd+mondayOffset(day), 0, 0, 0, 0, time.UTC)
+			if k == 0 && daysBetween(s.Anchor, date) < 0 {
+				continue // days of the anchor week before the anchor itself
+			}
+			out = append(out, s.resolve(date.Year(), date.Month(), date.Day(), hour, min, sec))
+		}
+		if len(out) == 0 {
+			// All BYDAY days of the first week precede the anchor
+			return s.occurrences(k + 1)
+		}
+		return out
+	case Monthly:
+		return []time.Time{s.resolveClamped(y, m+time.Month(k*s.Rule.Interval), d, hour, min, sec)}
+	case Yearly:
+		return []time.Time{s.resolveClamped(y+k*s.Rule.Interval, m, d, hour, min, sec)}
+	default:
+		return []time.Time{s.resolve(y, m, d+k*s.Rule.Interval, hour, min, sec)}
+	}
+}
+
+// weekStart returns the Monday of the anchor's week as a UTC date
+func (s Series) weekStart() time.Time {
+	y, m, d := s.Anchor.Date()
+	return time.Date(y, m, d-mondayOffset(s.Anchor.Weekday()), 0, 0, 0, 0, time.UTC)
+}
+
+// resolveClamped resolves a date whose day is clamped to the length of its month
+func (s Series) resolveClamped(y int, m time.Month, d, hour, min, sec int) time.Time {
+	first := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if d > lastDay {
+		d = lastDay
+	}
+	return s.resolve(first.Year(), first.Month(), d, hour, min, sec)
+}
+
+// resolve converts a local wall-clock time to an instant in a deterministic way.
+// time.Date does not guarantee which offset it picks around transitions, so the
+// offsets in effect before and after the date are tried explicitly.
+func (s Series) resolve(y int, m time.Month, d, hour, min, sec int) time.Time {
+	wall := time.Date(y, m, d, hour, min, sec, 0, time.UTC)
+
+	_, offsetBefore := wall.Add(-26 * time.Hour).In(s.Loc).Zone()
+	_, offsetAfter := wall.Add(26 * time.Hour).In(s.Loc).Zone()
+
+	before := wall.Add(-time.Duration(offsetBefore) * time.Second)
+	after := wall.Add(-time.Duration(offsetAfter) * time.Second)
+
+	beforeValid := sameWallClock(before.In(s.Loc), wall)
+	afterValid := sameWallClock(after.In(s.Loc), wall)
+
+	switch {
+	case beforeValid && afterValid:
+		if after.Before(before) {
+			return after
+		}
+		return before
+	case afterValid:
+		return after
+	default:
+		// Either only the earlier offset matches or the time is in a DST gap,
+		// in which case the earlier offset shifts it forward past the gap
+		return before
+	}
+}
+
+// sameWallClock reports whether t shows the same date and clock as wall
+func sameWallClock(t, wall time.Time) bool {
+	ty, tm, td := t.Date()
+	wy, wm, wd := wall.Date()
+	th, tmin, ts := t.Clock()
+	wh, wmin, ws := wall.Clock()
+	return ty == wy && tm == wm && td == wd && th == wh && tmin == wmin && ts == ws
+}
+
+// daysBetween returns the number of calendar days from a's date to b's date,
+// each taken in its own location
+func daysBetween(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	da := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	db := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
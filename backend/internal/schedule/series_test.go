@@ -0,0 +1,244 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// mustLocation loads an IANA timezone or fails the test
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", name, err)
+	}
+	return loc
+}
+
+// mustSeries builds a series from an RRULE and a wall-clock anchor
+func mustSeries(t *testing.T, rrule string, anchor string, loc *time.Location) Series {
+	t.Helper()
+	rule, err := ParseRule(rrule)
+	if err != nil {
+		t.Fatalf("ParseRule(%q) failed: %v", rrule, err)
+	}
+	at, err := time.Parse("2006-01-02 15:04", anchor)
+	if err != nil {
+		t.Fatalf("invalid anchor %q: %v", anchor, err)
+	}
+	return NewSeries(rule, at, loc)
+}
+
+// utc parses an RFC 3339 instant
+func utc(t *testing.T, s string) time.Time {
+	t.Helper()
+	at, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatalf("invalid time %q: %v", s, err)
+	}
+	return at
+}
+
+// checkOccurrences compares the first occurrences of a series with want
+func checkOccurrences(t *testing.T, series Series, want []string) {
+	t.Helper()
+	occ := series.First()
+	for i, w := range want {
+		if i > 0 {
+			occ = series.Next(occ)
+		}
+		if expected := utc(t, w); !occ.Equal(expected) {
+			t.Errorf("occurrence %d = %s, want %s", i, occ.UTC().Format(time.RFC3339), w)
+		}
+	}
+}
+
+func TestSeriesDST(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+	berlin := mustLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name   string
+		series Series
+		want   []string
+	}{
+		{
+			// Clocks go from 02:00 EST to 03:00 EDT on 2024-03-10
+			name:   "new york gap moves forward",
+			series: mustSeries(t, "FREQ=DAILY", "2024-03-09 02:30", newYork),
+			want:   []string{"2024-03-09T07:30:00Z", "2024-03-10T07:30:00Z", "2024-03-11T06:30:00Z"},
+		},
+		{
+			name:   "new york keeps the wall clock",
+			series: mustSeries(t, "FREQ=DAILY", "2024-03-09 09:00", newYork),
+			want:   []string{"2024-03-09T14:00:00Z", "2024-03-10T13:00:00Z", "2024-03-11T13:00:00Z"},
+		},
+		{
+			// Clocks go from 02:00 EDT back to 01:00 EST on 2024-11-03
+			name:   "new york overlap takes the earlier instant",
+			series: mustSeries(t, "FREQ=DAILY", "2024-11-02 01:30", newYork),
+			want:   []string{"2024-11-02T05:30:00Z", "2024-11-03T05:30:00Z", "2024-11-04T06:30:00Z"},
+		},
+		{
+			// Clocks go from 02:00 CET to 03:00 CEST on 2024-03-31
+			name:   "berlin gap moves forward",
+			series: mustSeries(t, "FREQ=WEEKLY", "2024-03-24 02:30", berlin),
+			want:   []string{"2024-03-24T01:30:00Z", "2024-03-31T01:30:00Z", "2024-04-07T00:30:00Z"},
+		},
+		{
+			// Clocks go from 03:00 CEST back to 02:00 CET on 2024-10-27
+			name:   "berlin overlap takes the earlier instant",
+			series: mustSeries(t, "FREQ=DAILY", "2024-10-26 02:30", berlin),
+			want:   []string{"2024-10-26T00:30:00Z", "2024-10-27T00:30:00Z", "2024-10-28T01:30:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkOccurrences(t, tt.series, tt.want)
+		})
+	}
+}
+
+func TestSeriesMonthlyClamping(t *testing.T) {
+	tests := []struct {
+		name   string
+		series Series
+		want   []string
+	}{
+		{
+			name:   "end of month",
+			series: mustSeries(t, "FREQ=MONTHLY", "2024-01-31 09:00", time.UTC),
+			want:   []string{"2024-01-31T09:00:00Z", "2024-02-29T09:00:00Z", "2024-03-31T09:00:00Z", "2024-04-30T09:00:00Z"},
+		},
+		{
+			name:   "every other month",
+			series: mustSeries(t, "FREQ=MONTHLY;INTERVAL=2", "2023-12-30 09:00", time.UTC),
+			want:   []string{"2023-12-30T09:00:00Z", "2024-02-29T09:00:00Z", "2024-04-30T09:00:00Z", "2024-06-30T09:00:00Z"},
+		},
+		{
+			name:   "leap day",
+			series: mustSeries(t, "FREQ=YEARLY", "2024-02-29 09:00", time.UTC),
+			want:   []string{"2024-02-29T09:00:00Z", "2025-02-28T09:00:00Z", "2026-02-28T09:00:00Z", "2027-02-28T09:00:00Z", "2028-02-29T09:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkOccurrences(t, tt.series, tt.want)
+		})
+	}
+}
+
+func TestSeriesByDay(t *testing.T) {
+	tests := []struct {
+		name   string
+		series Series
+		want   []string
+	}{
+		{
+			// 2024-01-03 is a Wednesday, the Monday of its week is skipped
+			name:   "anchor mid week",
+			series: mustSeries(t, "FREQ=WEEKLY;BYDAY=MO,TH", "2024-01-03 08:00", time.UTC),
+			want:   []string{"2024-01-04T08:00:00Z", "2024-01-08T08:00:00Z", "2024-01-11T08:00:00Z", "2024-01-15T08:00:00Z"},
+		},
+		{
+			name:   "every other week",
+			series: mustSeries(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "2024-01-03 08:00", time.UTC),
+			want:   []string{"2024-01-04T08:00:00Z", "2024-01-15T08:00:00Z", "2024-01-18T08:00:00Z", "2024-01-29T08:00:00Z"},
+		},
+		{
+			// 2024-01-05 is a Friday, after every day of its week
+			name:   "anchor after all days",
+			series: mustSeries(t, "FREQ=WEEKLY;BYDAY=MO,TH", "2024-01-05 08:00", time.UTC),
+			want:   []string{"2024-01-08T08:00:00Z", "2024-01-11T08:00:00Z", "2024-01-15T08:00:00Z"},
+		},
+		{
+			name:   "anchor on a listed day",
+			series: mustSeries(t, "FREQ=WEEKLY;BYDAY=MO,SU", "2024-01-01 08:00", time.UTC),
+			want:   []string{"2024-01-01T08:00:00Z", "2024-01-07T08:00:00Z", "2024-01-08T08:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkOccurrences(t, tt.series, tt.want)
+		})
+	}
+}
+
+func TestSeriesNext(t *testing.T) {
+	series := mustSeries(t, "FREQ=DAILY;INTERVAL=3", "2024-01-01 08:00", time.UTC)
+
+	tests := []struct {
+		name  string
+		after string
+		want  string
+	}{
+		{"long before the anchor", "2020-06-01T00:00:00Z", "2024-01-01T08:00:00Z"},
+		{"just before the anchor", "2024-01-01T07:59:59Z", "2024-01-01T08:00:00Z"},
+		{"at the anchor", "2024-01-01T08:00:00Z", "2024-01-04T08:00:00Z"},
+		{"between occurrences", "2024-06-15T12:00:00Z", "2024-06-17T08:00:00Z"},
+		{"at an occurrence", "2024-06-17T08:00:00Z", "2024-06-20T08:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := series.Next(utc(t, tt.after)); !got.Equal(utc(t, tt.want)) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got.UTC().Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+// TestSeriesNextMatchesIteration checks that starting from periodBefore finds
+// the same occurrence as walking the series from its first occurrence
+func TestSeriesNextMatchesIteration(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+	berlin := mustLocation(t, "Europe/Berlin")
+
+	series := []Series{
+		mustSeries(t, "FREQ=DAILY;INTERVAL=2", "2024-01-31 02:30", newYork),
+		mustSeries(t, "FREQ=WEEKLY;INTERVAL=3;BYDAY=TU,SA,SU", "2024-02-01 01:30", newYork),
+		mustSeries(t, "FREQ=MONTHLY", "2024-01-31 02:30", berlin),
+		mustSeries(t, "FREQ=YEARLY", "2024-02-29 23:59", berlin),
+	}
+	start := utc(t, "2023-12-01T00:00:00Z")
+	end := utc(t, "2026-06-01T00:00:00Z")
+
+	for _, s := range series {
+		// Walk the occurrences one by one from the first
+		occs := []time.Time{s.First()}
+		for occs[len(occs)-1].Before(end) {
+			last := occs[len(occs)-1]
+			next := s.occurrences(0)[0]
+			for k := 0; !next.After(last); k++ {
+				for _, occ := range s.occurrences(k) {
+					if occ.After(last) {
+						next = occ
+						break
+					}
+				}
+			}
+			occs = append(occs, next)
+		}
+
+		i := 0
+		for at := start; at.Before(end); at = at.Add(7 * time.Hour) {
+			for i < len(occs) && !occs[i].After(at) {
+				i++
+			}
+			if got := s.Next(at); !got.Equal(occs[i]) {
+				t.Fatalf("%s: Next(%s) = %s, want %s", s.Rule, at.Format(time.RFC3339), got.Format(time.RFC3339), occs[i].Format(time.RFC3339))
+			}
+		}
+	}
+}
+
+func TestSeriesBetween(t *testing.T) {
+	series := mustSeries(t, "FREQ=DAILY", "2024-01-01 08:00", time.UTC)
+
+	got := series.Between(utc(t, "2024-01-02T08:00:00Z"), utc(t, "2024-01-04T08:00:00Z"), 10)
+	if len(got) != 3 || !got[0].Equal(utc(t, "2024-01-02T08:00:00Z")) || !got[2].Equal(utc(t, "2024-01-04T08:00:00Z")) {
+		t.Errorf("Between returned %v, want the 2nd to the 4th inclusive", got)
+	}
+	if got := series.Between(utc(t, "2024-01-01T00:00:00Z"), utc(t, "2024-12-31T00:00:00Z"), 5); len(got) != 5 {
+		t.Errorf("Between returned %d occurrences, want the limit of 5", len(got))
+	}
+}
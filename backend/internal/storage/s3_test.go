@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is an in-memory stand-in for an S3 compatible server such as MinIO.
+// It serves path-style object requests for a single bucket.
+type fakeS3 struct {
+	t      *testing.T
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	body        []byte
+	contentType string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Authorization"), "Credential=access/") {
+		f.t.Errorf("%s %s is not signed with the configured access key", r.Method, r.URL.Path)
+		writeS3Error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket || key == "" {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err == nil && strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			body, err = decodeAWSChunked(body)
+		}
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = fakeObject{body: body, contentType: r.Header.Get("Content-Type")}
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", fmt.Sprint(len(obj.body)))
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(obj.body)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// object returns the object stored under key
+func (f *fakeS3) object(key string) (fakeObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[key]
+	return obj, ok
+}
+
+// decodeAWSChunked strips the chunk headers of a streaming signed upload, in
+// which every chunk is "<hex size>;chunk-signature=<signature>\r\n<data>\r\n"
+// and a chunk of size 0 ends the body
+func decodeAWSChunked(body []byte) ([]byte, error) {
+	var data []byte
+	for {
+		header, rest, ok := bytes.Cut(body, []byte("\r\n"))
+		if !ok {
+			return nil, errors.New("chunk header is not terminated")
+		}
+		sizeHex, _, _ := bytes.Cut(header, []byte(";"))
+		var size int
+		if _, err := fmt.Sscanf(string(sizeHex), "%x", &size); err != nil {
+			return nil, fmt.Errorf("invalid chunk size %q", sizeHex)
+		}
+		if size == 0 {
+			return data, nil
+		}
+		if len(rest) < size+2 {
+			return nil, errors.New("chunk is truncated")
+		}
+		data = append(data, rest[:size]...)
+		body = rest[size+2:]
+	}
+}
+
+// writeS3Error writes an error response in the XML format of S3
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+// newFakeS3Store starts a fake S3 server and returns a store pointed at its endpoint
+func newFakeS3Store(t *testing.T) (*S3Store, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{t: t, bucket: "photos", objects: map[string]fakeObject{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	endpoint, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse server URL: %v", err)
+	}
+	store, err := NewS3Store(S3Config{
+		Endpoint:  endpoint.Host,
+		AccessKey: "access",
+		SecretKey: "secret",
+		Bucket:    "photos",
+		Region:    "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store failed: %v", err)
+	}
+	return store, fake
+}
+
+func TestS3Store(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newFakeS3Store(t)
+	const key = "users/1/plants/2/photo.jpg"
+	content := []byte("jpeg data")
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "image/jpeg"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	stored, ok := fake.object(key)
+	if !ok || !bytes.Equal(stored.body, content) || stored.contentType != "image/jpeg" {
+		t.Fatalf("server holds %q (%s) under %s, want %q (image/jpeg)", stored.body, stored.contentType, key, content)
+	}
+
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("failed to read blob: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Get returned %q, want %q", got, content)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok := fake.object(key); ok {
+		t.Errorf("Delete left the object on the server")
+	}
+	// Deleting a missing blob is not an error
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing blob failed: %v", err)
+	}
+
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing blob = %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3StoreRejectsInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newFakeS3Store(t)
+	for _, key := range []string{"", "/abs", "../escape", "a/../b", `a\b`} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("Put accepted key %q", key)
+		}
+		if _, err := store.Get(ctx, key); err == nil {
+			t.Errorf("Get accepted key %q", key)
+		}
+	}
+}
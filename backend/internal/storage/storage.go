@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned when a blob does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores opaque binary objects such as photos under slash separated keys
+type BlobStore interface {
+	// Put stores the content of r under key, replacing any existing blob
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under key, returning ErrNotFound if it does not exist
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Driver selects the BlobStore implementation
+type Driver string
+
+const (
+	DriverLocal Driver = "local"
+	DriverS3    Driver = "s3"
+)
+
+// Config represents blob storage configuration
+type Config struct {
+	Driver    Driver
+	LocalPath string
+	S3        S3Config
+}
+
+// New creates the BlobStore selected by cfg.Driver
+func New(cfg Config) (BlobStore, error) {
+	switch cfg.Driver {
+	case DriverLocal:
+		return NewLocalStore(cfg.LocalPath)
+	case DriverS3:
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown blob store driver %q", cfg.Driver)
+	}
+}
+
+// validateKey rejects keys that are empty, absolute or escape the store root
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	if path.Clean(key) != key || key == "." || strings.HasPrefix(key, "../") || key == ".." {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// maxLoginBodyBytes bounds the login request body read to find the account
+const maxLoginBodyBytes = 64 << 10
+
+// attemptKey is the context key of the login attempt of a request
+type attemptKey struct{}
+
+// FromContext returns the login attempt begun by Middleware, nil if there is none
+func FromContext(ctx context.Context) *Attempt {
+	attempt, _ := ctx.Value(attemptKey{}).(*Attempt)
+	return attempt
+}
+
+// Middleware throttles a login endpoint of the go-pkgz direct provider. The
+// account is read from the same fields the provider reads: user in the query,
+// the form or the JSON body. The attempt is put in the request context for the
+// credential checker, which knows whether a refusal was a wrong password; an
+// attempt it does not end is released, counting neither way.
+func (t *Throttler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !t.config.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		email, err := loginUser(r)
+		if err != nil {
+			writeError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		ip := t.ClientIP(r)
+
+		attempt, wait, err := t.Begin(r.Context(), ip, email)
+		if err != nil {
+			t.logger.ErrorfContext(r.Context(), "Failed to check login throttle: %v", err)
+		}
+		if wait > 0 {
+			WriteTooManyRequests(w, wait)
+			return
+		}
+
+		defer func() {
+			// Release even if the client went away in the meantime
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+			defer cancel()
+			if err := attempt.Release(ctx); err != nil {
+				t.logger.ErrorfContext(ctx, "Failed to release login attempt: %v", err)
+			}
+		}()
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), attemptKey{}, attempt)))
+	})
+}
+
+// loginUser returns the user name of a direct provider login request and
+// leaves the body in place for the provider to read
+func loginUser(r *http.Request) (string, error) {
+	if r.Method == http.MethodGet {
+		return r.URL.Query().Get("user"), nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxLoginBodyBytes))
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		var creds struct {
+			User string `json:"user"`
+		}
+		// A malformed body is rejected by the provider, which counts as neither outcome
+		json.Unmarshal(body, &creds)
+		return creds.User, nil
+	}
+
+	form, _ := url.ParseQuery(string(body))
+	if user := form.Get("user"); user != "" {
+		return user, nil
+	}
+	return r.URL.Query().Get("user"), nil
+}
+
+// writeError writes a JSON error response in the shape of the API handlers
+func writeError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"message": message,
+	})
+}
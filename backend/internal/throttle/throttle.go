@@ -0,0 +1,330 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/mail"
+	"github.com/anish-chanda/ferna/model"
+)
+
+const (
+	// FreeFailures is how many failures in a row go without any delay, enough for typos
+	FreeFailures = 3
+	// cleanupInterval is how often throttles of long gone failures are removed
+	cleanupInterval = 10 * time.Minute
+	// ipv6PrefixBits groups IPv6 clients by network, as a single host usually owns a whole /64
+	ipv6PrefixBits = 64
+)
+
+// Config holds login throttling configuration
+type Config struct {
+	Enabled            bool
+	MaxAccountFailures int           // Failures in a row that lock an account
+	MaxIPFailures      int           // Failures in a row that block a client address
+	LockoutDuration    time.Duration // How long a lockout lasts
+	BaseDelay          time.Duration // Delay after the first failure beyond the free ones, doubled after each further failure
+	MaxDelay           time.Duration // Cap of the delay before the lockout
+	FailureWindow      time.Duration // Failures further apart than this start the count over
+	TrustForwardedFor  bool          // Whether the client address is taken from X-Forwarded-For, only behind a trusted proxy
+}
+
+// Validate checks that the configuration can be used
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxAccountFailures <= FreeFailures || c.MaxIPFailures <= FreeFailures {
+		return errors.New("login failure limits must be larger than " + strconv.Itoa(FreeFailures))
+	}
+	if c.LockoutDuration <= 0 || c.BaseDelay <= 0 || c.MaxDelay < c.BaseDelay || c.FailureWindow <= 0 {
+		return errors.New("login throttle durations must be positive and the maximum delay at least the base delay")
+	}
+	return nil
+}
+
+// Throttler slows down password guessing. Failed logins are counted per
+// client address and per account; after a few failures every further attempt
+// has to wait exponentially longer, and once a limit is reached the address
+// or account is locked out for a while. Blocked attempts are refused before
+// the password is hashed, so they cost the server next to nothing.
+//
+// Every attempt is counted when it begins and taken back if it succeeds, so no
+// more attempts than the limit get to check a password however many arrive at
+// once.
+type Throttler struct {
+	db     db.Store
+	mailer *mail.Mailer
+	config Config
+	logger *logger.ServiceLogger
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+// New creates a Throttler that notifies locked out account owners through mailer
+func New(database db.Store, mailer *mail.Mailer, config Config, logger *logger.ServiceLogger) (*Throttler, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Throttler{
+		db:     database,
+		mailer: mailer,
+		config: config,
+		logger: logger.WithField("component", "login-throttle"),
+	}, nil
+}
+
+// Attempt is a login attempt that has been counted as a failure of its client
+// address and account before the password is checked, so concurrent guesses
+// cannot slip past the limits. It ends with exactly one of Fail, Succeed or
+// Release; the first one wins and later calls do nothing. A nil Attempt, as
+// returned when throttling is disabled, ignores all of them.
+type Attempt struct {
+	t           *Throttler
+	ip          string
+	email       string
+	ipFailures  int
+	accFailures int
+	lockedUntil time.Time // Set as the lockout of the keys whose limit this attempt reached
+
+	mu   sync.Mutex
+	done bool
+}
+
+// Begin reserves a login attempt from ip for email. If either is blocked the
+// attempt is refused with how long to wait, and nothing is counted.
+func (t *Throttler) Begin(ctx context.Context, ip, email string) (*Attempt, time.Duration, error) {
+	if !t.config.Enabled {
+		return nil, 0, nil
+	}
+
+	now := time.Now().UTC()
+	t.cleanup(ctx, now)
+
+	a := &Attempt{
+		t:           t,
+		ip:          ip,
+		email:       accountKey(email),
+		lockedUntil: now.Add(t.config.LockoutDuration).Truncate(time.Microsecond),
+	}
+	windowStart := now.Add(-t.config.FailureWindow)
+
+	var err error
+	a.ipFailures, err = t.db.ReserveLoginAttempt(ctx, model.ThrottleScopeIP, a.ip, now, windowStart, t.config.MaxIPFailures, a.lockedUntil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if a.ipFailures > 0 {
+		a.accFailures, err = t.db.ReserveLoginAttempt(ctx, model.ThrottleScopeAccount, a.email, now, windowStart, t.config.MaxAccountFailures, a.lockedUntil)
+		if err != nil {
+			a.Release(ctx)
+			return nil, 0, err
+		}
+	}
+	if a.ipFailures > 0 && a.accFailures > 0 {
+		return a, 0, nil
+	}
+
+	// Refused, give back the key that was counted
+	if a.ipFailures > 0 {
+		if err := t.db.ReleaseLoginAttempt(ctx, model.ThrottleScopeIP, a.ip, a.lockedUntil); err != nil {
+			t.logger.ErrorfContext(ctx, "Failed to release login attempt: %v", err)
+		}
+	}
+	until, err := t.db.LoginBlockedUntil(ctx, ip, a.email, now)
+	if err != nil {
+		return nil, 0, err
+	}
+	if until == nil {
+		// The block ran out in the meantime, a retry goes through
+		return nil, time.Second, nil
+	}
+	return nil, until.Sub(now), nil
+}
+
+// Fail records that the password was wrong and blocks the next attempt for the
+// backoff delay. It returns how long the next attempt has to wait.
+func (a *Attempt) Fail(ctx context.Context) (time.Duration, error) {
+	if !a.finish() {
+		return 0, nil
+	}
+	t := a.t
+	now := time.Now().UTC()
+
+	ipWait, err := t.block(ctx, model.ThrottleScopeIP, a.ip, a.ipFailures, t.config.MaxIPFailures, now)
+	if err != nil {
+		return 0, err
+	}
+	accountWait, err := t.block(ctx, model.ThrottleScopeAccount, a.email, a.accFailures, t.config.MaxAccountFailures, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if a.ipFailures == t.config.MaxIPFailures {
+		t.logger.WarnfContext(ctx, "Client %s blocked for %s after %d failed logins", a.ip, t.config.LockoutDuration, t.config.MaxIPFailures)
+	}
+	if a.accFailures == t.config.MaxAccountFailures {
+		t.logger.WarnfContext(ctx, "Account %s locked for %s after %d failed logins", a.email, t.config.LockoutDuration, t.config.MaxAccountFailures)
+		t.notifyLockout(ctx, a.email, a.ip)
+	}
+	return max(ipWait, accountWait), nil
+}
+
+// Succeed forgets the failed logins of the account. Failures of the client
+// address are kept, so one valid account does not reset a guessing spree.
+func (a *Attempt) Succeed(ctx context.Context) error {
+	if !a.finish() {
+		return nil
+	}
+	if err := a.t.db.ClearLoginFailures(ctx, model.ThrottleScopeAccount, a.email); err != nil {
+		return err
+	}
+	return a.t.db.ReleaseLoginAttempt(ctx, model.ThrottleScopeIP, a.ip, a.lockedUntil)
+}
+
+// Release takes the attempt back without counting it either way, for a
+// correct password that is refused for another reason or a request that
+// failed before the password was decided
+func (a *Attempt) Release(ctx context.Context) error {
+	if !a.finish() {
+		return nil
+	}
+	if a.ipFailures > 0 {
+		if err := a.t.db.ReleaseLoginAttempt(ctx, model.ThrottleScopeIP, a.ip, a.lockedUntil); err != nil {
+			return err
+		}
+	}
+	if a.accFailures > 0 {
+		return a.t.db.ReleaseLoginAttempt(ctx, model.ThrottleScopeAccount, a.email, a.lockedUntil)
+	}
+	return nil
+}
+
+// finish marks the attempt as ended and reports whether it was still open
+func (a *Attempt) finish() bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.done {
+		return false
+	}
+	a.done = true
+	return true
+}
+
+// block blocks a key for the backoff delay after the given number of failures
+// and returns the delay. A key at its limit was locked out when the attempt was
+// reserved, so the lockout duration is returned without blocking again.
+func (t *Throttler) block(ctx context.Context, scope model.ThrottleScope, key string, failures, limit int, now time.Time) (time.Duration, error) {
+	if failures >= limit {
+		return t.config.LockoutDuration, nil
+	}
+	wait := t.backoff(failures)
+	if wait == 0 {
+		return 0, nil
+	}
+	if err := t.db.BlockLogin(ctx, scope, key, now.Add(wait)); err != nil {
+		return 0, err
+	}
+	return wait, nil
+}
+
+// backoff returns how long to block after the given number of failures in a row
+func (t *Throttler) backoff(failures int) time.Duration {
+	if failures <= FreeFailures {
+		return 0
+	}
+	delay := t.config.BaseDelay
+	for i := FreeFailures + 1; i < failures && delay < t.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, t.config.MaxDelay)
+}
+
+// notifyLockout emails the owner of a locked account, if the account exists
+func (t *Throttler) notifyLockout(ctx context.Context, email, ip string) {
+	user, err := t.db.GetUserByEmail(ctx, email)
+	if err != nil {
+		t.logger.ErrorfContext(ctx, "Failed to look up locked account: %v", err)
+		return
+	}
+	if user == nil {
+		return
+	}
+
+	err = t.mailer.Enqueue(ctx, user.Email, mail.TemplateAccountLocked, map[string]any{
+		"Name":     user.FullName,
+		"Duration": mail.HumanDuration(t.config.LockoutDuration),
+		"IP":       ip,
+	})
+	if err != nil {
+		t.logger.ErrorfContext(ctx, "Failed to queue lockout email for user %s: %v", user.ID, err)
+	}
+}
+
+// cleanup removes throttles of failures outside the window, at most once per cleanupInterval
+func (t *Throttler) cleanup(ctx context.Context, now time.Time) {
+	t.mu.Lock()
+	if now.Sub(t.lastCleanup) < cleanupInterval {
+		t.mu.Unlock()
+		return
+	}
+	t.lastCleanup = now
+	t.mu.Unlock()
+
+	deleted, err := t.db.DeleteStaleLoginThrottles(ctx, now.Add(-t.config.FailureWindow), now)
+	if err != nil {
+		t.logger.WarnfContext(ctx, "Failed to clean up login throttles: %v", err)
+		return
+	}
+	if deleted > 0 {
+		t.logger.DebugfContext(ctx, "Removed %d stale login throttles", deleted)
+	}
+}
+
+// ClientIP returns the address failures of r are counted against
+func (t *Throttler) ClientIP(r *http.Request) string {
+	raw := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		raw = host
+	}
+	if t.config.TrustForwardedFor {
+		// The proxy appends the address it saw, so the last entry is the one it vouches for
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			parts := strings.Split(forwarded, ",")
+			raw = strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+
+	ip := net.ParseIP(raw)
+	if ip == nil {
+		return raw
+	}
+	if ip.To4() == nil {
+		return ip.Mask(net.CIDRMask(ipv6PrefixBits, 128)).String() + "/" + strconv.Itoa(ipv6PrefixBits)
+	}
+	return ip.String()
+}
+
+// WriteTooManyRequests writes a 429 response telling the client when to retry
+func WriteTooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeError(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+}
+
+// accountKey normalizes the email an attempt was made for, whether or not an account uses it
+func accountKey(email string) string {
+	return strings.TrimSpace(strings.ToLower(email))
+}
@@ -0,0 +1,276 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anish-chanda/ferna/internal/db/dbtest"
+	"github.com/anish-chanda/ferna/internal/logger"
+)
+
+// testConfig locks an account after 5 failures and a client after 20, with
+// backoff delays short enough to wait out in a test
+var testConfig = Config{
+	Enabled:            true,
+	MaxAccountFailures: 5,
+	MaxIPFailures:      20,
+	LockoutDuration:    time.Hour,
+	BaseDelay:          time.Millisecond,
+	MaxDelay:           2 * time.Millisecond,
+	FailureWindow:      time.Hour,
+}
+
+// newTestThrottler creates a Throttler on a migrated SQLite database
+func newTestThrottler(t *testing.T, config Config) *Throttler {
+	t.Helper()
+	database := dbtest.NewSQLite(t)
+
+	throttler, err := New(database, nil, config, logger.New(logger.Config{Level: "error"}))
+	if err != nil {
+		t.Fatalf("failed to create throttler: %v", err)
+	}
+	return throttler
+}
+
+// failUntilBlocked fails attempts one after another, waiting out the backoff,
+// until an attempt is refused for longer than the backoff. It returns the
+// number of attempts that got through and the final wait.
+func failUntilBlocked(t *testing.T, throttler *Throttler, ip, email string) (int, time.Duration) {
+	t.Helper()
+	ctx := context.Background()
+	for n := 0; n < 100; n++ {
+		attempt, wait, err := throttler.Begin(ctx, ip, email)
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		if wait > time.Minute {
+			return n, wait
+		}
+		if wait > 0 {
+			time.Sleep(wait)
+			n--
+			continue
+		}
+		if _, err := attempt.Fail(ctx); err != nil {
+			t.Fatalf("Fail failed: %v", err)
+		}
+	}
+	t.Fatalf("%s was never locked out", email)
+	return 0, 0
+}
+
+func TestLockoutAfterFailures(t *testing.T) {
+	throttler := newTestThrottler(t, testConfig)
+
+	n, wait := failUntilBlocked(t, throttler, "192.0.2.1", "user@example.com")
+	if n != testConfig.MaxAccountFailures {
+		t.Errorf("%d attempts got through, want %d", n, testConfig.MaxAccountFailures)
+	}
+	if wait < testConfig.LockoutDuration-time.Minute {
+		t.Errorf("locked out for %s, want about %s", wait, testConfig.LockoutDuration)
+	}
+
+	// Attempts of the same account from elsewhere are locked out as well
+	if _, wait, _ := throttler.Begin(context.Background(), "192.0.2.2", "USER@example.com"); wait < time.Minute {
+		t.Errorf("account lockout was not applied to another client, wait %s", wait)
+	}
+}
+
+// TestLockoutUnderConcurrentFailures checks that attempts which begin together
+// cannot get past the limit, and that those still in flight once the lockout
+// is set do not undo it
+func TestLockoutUnderConcurrentFailures(t *testing.T) {
+	throttler := newTestThrottler(t, testConfig)
+	ctx := context.Background()
+
+	const guesses = 50
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		started = make(chan struct{})
+		checked int
+	)
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-started
+			// Every guess comes from its own address so only the account limit applies
+			attempt, wait, err := throttler.Begin(ctx, fmt.Sprintf("198.51.100.%d", i), "user@example.com")
+			if err != nil {
+				t.Errorf("Begin failed: %v", err)
+				return
+			}
+			if wait > 0 {
+				return
+			}
+			mu.Lock()
+			checked++
+			mu.Unlock()
+			// The password check happens here
+			time.Sleep(5 * time.Millisecond)
+			if _, err := attempt.Fail(ctx); err != nil {
+				t.Errorf("Fail failed: %v", err)
+			}
+		}(i)
+	}
+	close(started)
+	wg.Wait()
+
+	if checked == 0 || checked > testConfig.MaxAccountFailures {
+		t.Errorf("%d of %d concurrent guesses got to check a password, want 1 to %d", checked, guesses, testConfig.MaxAccountFailures)
+	}
+
+	// Wait out any backoff, the account has to end up locked out
+	n, wait := failUntilBlocked(t, throttler, "203.0.113.1", "user@example.com")
+	if checked+n != testConfig.MaxAccountFailures {
+		t.Errorf("%d attempts got through in total, want %d", checked+n, testConfig.MaxAccountFailures)
+	}
+	if wait < testConfig.LockoutDuration-time.Minute {
+		t.Errorf("locked out for %s, want about %s", wait, testConfig.LockoutDuration)
+	}
+}
+
+func TestClientLockoutUnderConcurrentFailures(t *testing.T) {
+	throttler := newTestThrottler(t, testConfig)
+	ctx := context.Background()
+
+	const guesses = 100
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		checked int
+	)
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Every guess is for another account so only the client limit applies
+			attempt, wait, err := throttler.Begin(ctx, "192.0.2.1", fmt.Sprintf("user%d@example.com", i))
+			if err != nil || wait > 0 {
+				return
+			}
+			mu.Lock()
+			checked++
+			mu.Unlock()
+			attempt.Fail(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	if checked > testConfig.MaxIPFailures {
+		t.Errorf("%d of %d concurrent guesses got to check a password, want at most %d", checked, guesses, testConfig.MaxIPFailures)
+	}
+}
+
+func TestReleasedAttemptsDoNotCount(t *testing.T) {
+	throttler := newTestThrottler(t, testConfig)
+	ctx := context.Background()
+
+	// A correct password refused for another reason, e.g. a pending second
+	// factor, must not lock the owner out however often it happens
+	for i := 0; i < 3*testConfig.MaxAccountFailures; i++ {
+		attempt, wait, err := throttler.Begin(ctx, "192.0.2.1", "user@example.com")
+		if err != nil || wait > 0 {
+			t.Fatalf("attempt %d refused: wait %s, err %v", i, wait, err)
+		}
+		if err := attempt.Release(ctx); err != nil {
+			t.Fatalf("Release failed: %v", err)
+		}
+		// Ending an attempt twice does nothing
+		if _, err := attempt.Fail(ctx); err != nil {
+			t.Fatalf("Fail after Release failed: %v", err)
+		}
+	}
+}
+
+func TestReleaseLiftsItsOwnLockout(t *testing.T) {
+	throttler := newTestThrottler(t, testConfig)
+	ctx := context.Background()
+
+	// Fail all but the last attempt before the lockout
+	for i := 0; i < testConfig.MaxAccountFailures-1; i++ {
+		attempt, wait, err := throttler.Begin(ctx, "192.0.2.1", "user@example.com")
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		time.Sleep(wait)
+		if wait > 0 {
+			i--
+			continue
+		}
+		attempt.Fail(ctx)
+	}
+	time.Sleep(testConfig.MaxDelay)
+
+	// The last attempt reaches the limit but turns out not to be a failure
+	attempt, wait, err := throttler.Begin(ctx, "192.0.2.1", "user@example.com")
+	if err != nil || wait > 0 {
+		t.Fatalf("last attempt refused: wait %s, err %v", wait, err)
+	}
+	if _, wait, _ := throttler.Begin(ctx, "192.0.2.2", "user@example.com"); wait < time.Minute {
+		t.Fatalf("attempt at the limit did not lock out the account, wait %s", wait)
+	}
+	if err := attempt.Release(ctx); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	next, wait, err := throttler.Begin(ctx, "192.0.2.1", "user@example.com")
+	if err != nil || wait > 0 {
+		t.Fatalf("attempt after release refused: wait %s, err %v", wait, err)
+	}
+	next.Fail(ctx)
+	if _, wait, _ := throttler.Begin(ctx, "192.0.2.1", "user@example.com"); wait < time.Minute {
+		t.Errorf("failing the attempt at the limit did not lock out the account, wait %s", wait)
+	}
+}
+
+func TestSuccessClearsAccountFailures(t *testing.T) {
+	throttler := newTestThrottler(t, testConfig)
+	ctx := context.Background()
+
+	for round := 0; round < 3; round++ {
+		for i := 0; i < testConfig.MaxAccountFailures-1; i++ {
+			attempt, wait, err := throttler.Begin(ctx, fmt.Sprintf("192.0.2.%d", round), "user@example.com")
+			if err != nil {
+				t.Fatalf("Begin failed: %v", err)
+			}
+			if wait > time.Minute {
+				t.Fatalf("locked out in round %d", round)
+			}
+			time.Sleep(wait)
+			if wait > 0 {
+				i--
+				continue
+			}
+			attempt.Fail(ctx)
+		}
+		time.Sleep(testConfig.MaxDelay)
+
+		attempt, wait, err := throttler.Begin(ctx, fmt.Sprintf("192.0.2.%d", round), "user@example.com")
+		if err != nil || wait > 0 {
+			t.Fatalf("login refused in round %d: wait %s, err %v", round, wait, err)
+		}
+		if err := attempt.Succeed(ctx); err != nil {
+			t.Fatalf("Succeed failed: %v", err)
+		}
+	}
+}
+
+func TestDisabledThrottler(t *testing.T) {
+	throttler, err := New(nil, nil, Config{}, logger.New(logger.Config{Level: "error"}))
+	if err != nil {
+		t.Fatalf("failed to create throttler: %v", err)
+	}
+	attempt, wait, err := throttler.Begin(context.Background(), "192.0.2.1", "user@example.com")
+	if attempt != nil || wait != 0 || err != nil {
+		t.Fatalf("Begin = %v, %s, %v, want nothing", attempt, wait, err)
+	}
+	// A nil attempt ignores its outcome
+	attempt.Fail(context.Background())
+	attempt.Succeed(context.Background())
+	attempt.Release(context.Background())
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span for every request, continuing the trace of
+// the caller if it sent one. Probes and metric scrapes are left out as they
+// would drown the traces worth looking at.
+func Middleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http.request",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			// Renamed after the route once the mux has matched one
+			return r.Method
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/metrics" && r.URL.Path != "/health" && !strings.HasPrefix(r.URL.Path, "/health/")
+		}),
+	)
+}
+
+// Route names the span of a request after the route pattern it matched. It has
+// to wrap the ServeMux directly, as the mux stores the matched pattern on the
+// request it is given.
+func Route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			route := r.Pattern
+			if _, path, found := strings.Cut(route, " "); found {
+				route = path
+			}
+			if route == "" {
+				return
+			}
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/anish-chanda/ferna/internal/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// Supported span exporters
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Config holds tracing configuration
+type Config struct {
+	Exporter    string  // none, otlp or stdout
+	Endpoint    string  // OTLP/HTTP collector URL, e.g. http://localhost:4318
+	SampleRatio float64 // Share of new traces that are recorded, traces started by callers follow their decision
+}
+
+// Validate checks that the configuration can be used
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case ExporterNone, ExporterStdout:
+	case ExporterOTLP:
+		if !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://") {
+			return errors.New("OTLP endpoint must be an http:// or https:// URL")
+		}
+	default:
+		return fmt.Errorf("unsupported tracing exporter %q", c.Exporter)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return errors.New("trace sample ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// ShutdownFunc flushes pending spans and stops the exporter
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup installs the global tracer provider and W3C trace context propagation.
+// Without an exporter tracing stays disabled and spans cost next to nothing.
+func Setup(ctx context.Context, config Config, service string, logger *logger.ServiceLogger) (ShutdownFunc, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(config.Endpoint, "/")+"/v1/traces"))
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s span exporter: %w", config.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(service)),
+		resource.WithFromEnv(),
+		resource.WithHost(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	errLogger := logger.WithField("component", "tracing")
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errLogger.Warnf("OpenTelemetry error: %v", err)
+	}))
+
+	logger.Infof("Tracing enabled - Exporter: %s, Sample ratio: %g", config.Exporter, config.SampleRatio)
+	return provider.Shutdown, nil
+}
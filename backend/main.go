@@ -2,34 +2,77 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // embed the timezone database for minimal container images
 
+	"github.com/anish-chanda/ferna/internal/account"
 	"github.com/anish-chanda/ferna/internal/auth"
 	"github.com/anish-chanda/ferna/internal/db"
 	"github.com/anish-chanda/ferna/internal/handlers"
+	"github.com/anish-chanda/ferna/internal/health"
 	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/mail"
+	"github.com/anish-chanda/ferna/internal/metrics"
+	"github.com/anish-chanda/ferna/internal/middleware"
+	"github.com/anish-chanda/ferna/internal/push"
+	"github.com/anish-chanda/ferna/internal/registration"
+	"github.com/anish-chanda/ferna/internal/reminders"
+	"github.com/anish-chanda/ferna/internal/storage"
+	"github.com/anish-chanda/ferna/internal/throttle"
+	"github.com/anish-chanda/ferna/internal/tracing"
 	"github.com/anish-chanda/ferna/migrations"
-	"github.com/go-pkgz/auth/avatar"
-	"github.com/go-pkgz/auth/provider"
-	"github.com/go-pkgz/auth/token"
+	"github.com/anish-chanda/ferna/model"
 	authpkg "github.com/go-pkgz/auth/v2"
+	"github.com/go-pkgz/auth/v2/avatar"
+	"github.com/go-pkgz/auth/v2/provider"
+	"github.com/go-pkgz/auth/v2/token"
+	"github.com/google/uuid"
 )
 
+// tokenAudience is the audience of the JWTs issued to the mobile app
+const tokenAudience = "ferna-mobile"
+
 // App holds the application dependencies
 type App struct {
-	config *Config
-	logger *logger.ServiceLogger
-	db     *db.PostgresDB
-	server *http.Server
-	auth   *authpkg.Service
+	config    *Config
+	logger    *logger.ServiceLogger
+	db        db.Store
+	blobs     storage.BlobStore
+	avatars   avatar.Store
+	mailer    *mail.Mailer
+	reminders *reminders.Dispatcher
+	eraser    *account.Eraser
+	throttler *throttle.Throttler
+	signups   *registration.Policy
+	server    *http.Server
+	admin     *http.Server // Serves metrics on a separate port, nil if they share the API port
+	tracing   tracing.ShutdownFunc
+	auth      *authpkg.Service
+	// localProvider is the direct provider registered for local accounts
+	localProvider provider.DirectHandler
 }
 
 func main() {
+	// Subcommands load the same configuration as the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(runMigrateCommand(os.Args[2:]))
+		case "admin":
+			os.Exit(runAdminCommand(os.Args[2:]))
+		}
+	}
+
+	noMigrate := flag.Bool("no-migrate", false, "start without applying pending database migrations")
+	flag.Parse()
+
 	// Load configuration
 	config, err := LoadConfig()
 	if err != nil {
@@ -43,30 +86,79 @@ func main() {
 
 	appLogger.Infof("Configuration loaded - Port: %d", config.APIPort)
 
-	// Initialize database
 	ctx := context.Background()
 
-	database, err := db.NewPostgresDB(ctx, config.Database, appLogger)
+	// Initialize tracing before anything that creates spans
+	shutdownTracing, err := tracing.Setup(ctx, config.Tracing, config.Logger.Service, appLogger)
+	if err != nil {
+		appLogger.Fatalf("Failed to setup tracing: %v", err)
+	}
+
+	// Initialize database
+	database, err := openDatabase(ctx, config.Database, appLogger)
 	if err != nil {
 		appLogger.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer database.Close()
 
-	// Run database migrations
-	if err := migrations.RunMigrations(ctx, database.Pool, appLogger); err != nil {
+	// Run database migrations, unless the operator manages them with the migrate command
+	if *noMigrate {
+		appLogger.Warn("Skipping database migrations (--no-migrate)")
+	} else if err := runMigrations(ctx, database, appLogger); err != nil {
 		appLogger.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Initialize blob storage for photos
+	blobs, err := storage.New(config.Storage)
+	if err != nil {
+		appLogger.Fatalf("Failed to initialize blob storage: %v", err)
+	}
+	appLogger.Infof("Blob storage initialized - Driver: %s", config.Storage.Driver)
+
 	// Create application instance
 	app := &App{
-		config: config,
-		logger: appLogger,
-		db:     database,
+		config:  config,
+		logger:  appLogger,
+		db:      database,
+		blobs:   blobs,
+		tracing: shutdownTracing,
+	}
+
+	// Setup registration policy, which the auth service consults for OAuth sign up
+	app.signups, err = registration.NewPolicy(database, config.Registration, appLogger)
+	if err != nil {
+		appLogger.Fatalf("Failed to setup registration: %v", err)
 	}
+	appLogger.Infof("Registration mode: %s", config.Registration.Mode)
 
 	// Setup auth service
 	app.setupAuthService()
 
+	// Setup outbound mail
+	if err := app.setupMail(); err != nil {
+		appLogger.Fatalf("Failed to setup mail: %v", err)
+	}
+
+	// Setup login throttling
+	app.throttler, err = throttle.New(database, app.mailer, config.Throttle, appLogger)
+	if err != nil {
+		appLogger.Fatalf("Failed to setup login throttling: %v", err)
+	}
+
+	// Setup reminder dispatcher
+	if err := app.setupReminders(); err != nil {
+		appLogger.Fatalf("Failed to setup reminders: %v", err)
+	}
+
+	// Setup account deletion
+	app.eraser, err = account.NewEraser(database, blobs, app.avatars, config.Deletion, appLogger)
+	if err != nil {
+		appLogger.Fatalf("Failed to setup account deletion: %v", err)
+	}
+
+	// Setup metrics collected from the database
+	app.setupMetrics()
+
 	// Setup HTTP server
 	if err := app.setupServer(); err != nil {
 		appLogger.Fatalf("Failed to setup server: %v", err)
@@ -83,21 +175,129 @@ func (app *App) setupServer() error {
 	// Create HTTP mux for routing
 	mux := http.NewServeMux()
 
-	// Health check endpoint
-	mux.HandleFunc("GET /health", app.healthCheckHandler)
+	// Health check endpoints. /health is kept for existing checks and reports readiness.
+	checker := app.setupHealthChecks()
+	mux.HandleFunc("GET /health/live", checker.LiveHandler)
+	mux.HandleFunc("GET /health/ready", checker.ReadyHandler)
+	mux.HandleFunc("GET /health", checker.ReadyHandler)
 
 	// Auth endpoints
-	mux.HandleFunc("POST /api/auth/signup", handlers.SignupHandler(app.db, app.logger))
+	sessions := auth.NewSessions(app.auth.TokenService(), tokenAudience)
+	mux.HandleFunc("POST /api/auth/login", handlers.LoginHandler(app.db, sessions, app.throttler, app.config.Auth.RequireVerifiedEmail, app.logger))
+	mux.HandleFunc("POST /api/auth/login/2fa", handlers.CompleteTwoFactorLoginHandler(app.db, sessions, app.throttler, app.logger))
+	mux.HandleFunc("POST /api/auth/signup", handlers.SignupHandler(app.db, app.signups, app.mailer, app.config.Auth.EmailVerificationURL, app.config.Auth.EmailVerificationTTL, app.logger))
+	mux.HandleFunc("POST /api/auth/verify-email", handlers.VerifyEmailHandler(app.db, app.logger))
+	mux.HandleFunc("POST /api/auth/verify-email/resend", handlers.ResendVerificationHandler(app.db, app.mailer, app.config.Auth.EmailVerificationURL, app.config.Auth.EmailVerificationTTL, app.logger))
+	mux.HandleFunc("POST /api/auth/password/forgot", handlers.ForgotPasswordHandler(app.db, app.mailer, app.config.Auth.PasswordResetURL, app.config.Auth.PasswordResetTTL, app.logger))
+	mux.HandleFunc("POST /api/auth/password/reset", handlers.ResetPasswordHandler(app.db, app.logger))
 
 	// Mount auth service routes (auth handler and avatar handler)
 	authHandler, avatarHandler := app.auth.Handlers()
 	mux.Handle("/auth/", http.StripPrefix("/auth", authHandler))
+	mux.Handle("/auth/local/login", app.throttler.Middleware(http.HandlerFunc(app.localLogin)))
 	mux.Handle("/avatar/", http.StripPrefix("/avatar", avatarHandler))
 
+	// Authenticated API endpoints. Routes wrapped with Scoped also accept API keys.
+	authMiddleware := auth.NewAuthenticator(app.auth.Middleware(), app.db, app.logger)
+
+	// Link an OAuth identity to an existing local account
+	mux.Handle("POST /api/auth/link", authMiddleware.Auth(handlers.LinkIdentityHandler(app.db, app.throttler, app.logger)))
+	// Exchange the token of an OAuth login to an account with 2FA for a login challenge
+	mux.Handle("POST /api/auth/oauth/2fa", authMiddleware.Auth(handlers.OAuthTwoFactorChallengeHandler(app.db, app.logger)))
+
+	// Plant endpoints
+	mux.Handle("GET /api/plants", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.ListPlantsHandler(app.db, app.logger)))
+	mux.Handle("POST /api/plants", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.CreatePlantHandler(app.db, app.logger)))
+	mux.Handle("GET /api/plants/{id}", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.GetPlantHandler(app.db, app.logger)))
+	mux.Handle("PATCH /api/plants/{id}", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.UpdatePlantHandler(app.db, app.logger)))
+	mux.Handle("DELETE /api/plants/{id}", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.DeletePlantHandler(app.db, app.blobs, app.logger)))
+
+	// Photo endpoints
+	mux.Handle("GET /api/plants/{id}/photos", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.ListPhotosHandler(app.db, app.logger)))
+	mux.Handle("POST /api/plants/{id}/photos", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.UploadPhotoHandler(app.db, app.blobs, app.config.Photos.MaxUploadBytes, app.logger)))
+	mux.Handle("GET /api/plants/{id}/photos/{photoID}", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.PhotoContentHandler(app.db, app.blobs, false, app.logger)))
+	mux.Handle("GET /api/plants/{id}/photos/{photoID}/thumbnail", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.PhotoContentHandler(app.db, app.blobs, true, app.logger)))
+	mux.Handle("DELETE /api/plants/{id}/photos/{photoID}", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.DeletePhotoHandler(app.db, app.blobs, app.logger)))
+
+	// Care event endpoints
+	mux.Handle("GET /api/plants/{id}/timeline", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.PlantTimelineHandler(app.db, app.logger)))
+	mux.Handle("POST /api/plants/{id}/events", authMiddleware.Scoped(model.APIKeyScopePlants, handlers.CreateCareEventHandler(app.db, app.logger)))
+
+	// Care schedule endpoints
+	mux.Handle("GET /api/plants/{id}/schedules", authMiddleware.Scoped(model.APIKeyScopeTasks, handlers.ListCareSchedulesHandler(app.db, app.logger)))
+	mux.Handle("POST /api/plants/{id}/schedules", authMiddleware.Scoped(model.APIKeyScopeTasks, handlers.CreateCareScheduleHandler(app.db, app.logger)))
+	mux.Handle("GET /api/plants/{id}/schedules/{scheduleID}", authMiddleware.Scoped(model.APIKeyScopeTasks, handlers.GetCareScheduleHandler(app.db, app.logger)))
+	mux.Handle("PATCH /api/plants/{id}/schedules/{scheduleID}", authMiddleware.Scoped(model.APIKeyScopeTasks, handlers.UpdateCareScheduleHandler(app.db, app.logger)))
+	mux.Handle("DELETE /api/plants/{id}/schedules/{scheduleID}", authMiddleware.Scoped(model.APIKeyScopeTasks, handlers.DeleteCareScheduleHandler(app.db, app.logger)))
+	mux.Handle("GET /api/plants/{id}/schedules/{scheduleID}/history", authMiddleware.Scoped(model.APIKeyScopeTasks, handlers.ListCareTaskRecordsHandler(app.db, app.logger)))
+	mux.Handle("POST /api/plants/{id}/schedules/{scheduleID}/complete", authMiddleware.Scoped(model.APIKeyScopeTasks, handlers.CareTaskActionHandler(app.db, app.logger, model.CareTaskActionComplete)))
+	mux.Handle("POST /api/plants/{id}/schedules/{scheduleID}/snooze", authMiddleware.Scoped(model.APIKeyScopeTasks, handlers.CareTaskActionHandler(app.db, app.logger, model.CareTaskActionSnooze)))
+	mux.Handle("POST /api/plants/{id}/schedules/{scheduleID}/skip", authMiddleware.Scoped(model.APIKeyScopeTasks, handlers.CareTaskActionHandler(app.db, app.logger, model.CareTaskActionSkip)))
+	mux.Handle("POST /api/plants/{id}/schedules/{scheduleID}/reschedule", authMiddleware.Scoped(model.APIKeyScopeTasks, handlers.CareTaskActionHandler(app.db, app.logger, model.CareTaskActionReschedule)))
+
+	// Care task endpoints
+	mux.Handle("GET /api/tasks/due", authMiddleware.Scoped(model.APIKeyScopeTasks, handlers.ListDueTasksHandler(app.db, app.logger)))
+
+	// Push device endpoints
+	mux.Handle("POST /api/devices", authMiddleware.Auth(handlers.RegisterDeviceHandler(app.db, app.logger)))
+	mux.Handle("DELETE /api/devices", authMiddleware.Auth(handlers.UnregisterDeviceHandler(app.db, app.logger)))
+
+	// Profile endpoints
+	mux.Handle("GET /api/me", authMiddleware.Auth(handlers.GetProfileHandler(app.db, app.logger)))
+	mux.Handle("PATCH /api/me", authMiddleware.Auth(handlers.UpdateProfileHandler(app.db, app.logger)))
+	mux.Handle("POST /api/me/password", authMiddleware.Auth(handlers.ChangePasswordHandler(app.db, app.throttler, app.logger)))
+	mux.Handle("DELETE /api/me", authMiddleware.Auth(handlers.DeleteAccountHandler(app.db, app.eraser, app.throttler, app.logger)))
+	mux.Handle("POST /api/me/deletion/cancel", authMiddleware.Auth(handlers.CancelAccountDeletionHandler(app.eraser, app.logger)))
+	mux.Handle("POST /api/me/email", authMiddleware.Auth(handlers.ChangeEmailHandler(app.db, app.mailer, app.config.Auth.EmailVerificationURL, app.config.Auth.EmailVerificationTTL, app.throttler, app.logger)))
+
+	// Two-factor authentication endpoints
+	mux.Handle("GET /api/me/2fa", authMiddleware.Auth(handlers.TwoFactorStatusHandler(app.db, app.logger)))
+	mux.Handle("POST /api/me/2fa/setup", authMiddleware.Auth(handlers.SetupTwoFactorHandler(app.db, app.logger)))
+	mux.Handle("POST /api/me/2fa/confirm", authMiddleware.Auth(handlers.ConfirmTwoFactorHandler(app.db, app.logger)))
+	mux.Handle("POST /api/me/2fa/disable", authMiddleware.Auth(handlers.DisableTwoFactorHandler(app.db, app.throttler, app.logger)))
+	mux.Handle("POST /api/me/2fa/recovery-codes", authMiddleware.Auth(handlers.RegenerateRecoveryCodesHandler(app.db, app.logger)))
+
+	// API key endpoints
+	mux.Handle("GET /api/keys", authMiddleware.Auth(handlers.ListAPIKeysHandler(app.db, app.logger)))
+	mux.Handle("POST /api/keys", authMiddleware.Auth(handlers.CreateAPIKeyHandler(app.db, app.logger)))
+	mux.Handle("DELETE /api/keys/{id}", authMiddleware.Auth(handlers.DeleteAPIKeyHandler(app.db, app.logger)))
+
+	// Instance admin endpoints, the Admin middleware checks the role of the user
+	mux.Handle("GET /api/admin/users", authMiddleware.Admin(handlers.AdminListUsersHandler(app.db, app.logger)))
+	mux.Handle("DELETE /api/admin/users/{id}", authMiddleware.Admin(handlers.AdminDeleteUserHandler(app.eraser, app.logger)))
+	mux.Handle("GET /api/admin/stats", authMiddleware.Admin(handlers.InstanceStatsHandler(app.db, app.logger)))
+	mux.Handle("GET /api/admin/registration", authMiddleware.Admin(handlers.GetRegistrationHandler(app.db, app.signups, app.logger)))
+	mux.Handle("PUT /api/admin/registration", authMiddleware.Admin(handlers.UpdateRegistrationHandler(app.db, app.signups, app.logger)))
+	mux.Handle("GET /api/admin/invites", authMiddleware.Admin(handlers.ListInviteCodesHandler(app.db, app.logger)))
+	mux.Handle("POST /api/admin/invites", authMiddleware.Admin(handlers.CreateInviteCodeHandler(app.db, app.logger)))
+	mux.Handle("DELETE /api/admin/invites/{id}", authMiddleware.Admin(handlers.DeleteInviteCodeHandler(app.db, app.logger)))
+
+	// Metrics endpoint, optionally on its own port to keep it off the public API
+	// The span is started before the access log so its lines carry the trace ID
+	middlewares := []middleware.Middleware{middleware.RequestID, tracing.Middleware, middleware.AccessLog(app.logger), middleware.Recover(app.logger)}
+	if app.config.Metrics.Enabled {
+		if app.config.Metrics.Port == 0 {
+			mux.Handle("GET /metrics", metrics.Handler())
+		} else {
+			adminMux := http.NewServeMux()
+			adminMux.Handle("GET /metrics", metrics.Handler())
+			app.admin = &http.Server{
+				Addr:         fmt.Sprintf("%s:%d", app.config.Host, app.config.Metrics.Port),
+				Handler:      adminMux,
+				ReadTimeout:  15 * time.Second,
+				WriteTimeout: 15 * time.Second,
+				IdleTimeout:  60 * time.Second,
+			}
+		}
+		// Instrument sits right around the mux, which records the matched route pattern
+		middlewares = append(middlewares, metrics.Instrument)
+	}
+	middlewares = append(middlewares, tracing.Route)
+
 	// Configure server
 	app.server = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", app.config.Host, app.config.APIPort),
-		Handler:      mux,
+		Handler:      middleware.Chain(mux, middlewares...),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -108,6 +308,8 @@ func (app *App) setupServer() error {
 
 // setupAuthService configures the authentication service
 func (app *App) setupAuthService() {
+	app.avatars = avatar.NewLocalFS(app.config.Auth.AvatarPath)
+
 	// Setup auth options
 	authOptions := authpkg.Opts{
 		SecretReader: token.SecretFunc(func(id string) (string, error) {
@@ -117,52 +319,421 @@ func (app *App) setupAuthService() {
 		CookieDuration: time.Duration(app.config.Auth.CookieDuration) * time.Hour,
 		Issuer:         "ferna",
 		AudienceReader: token.AudienceFunc(func() ([]string, error) {
-			return []string{"ferna-mobile"}, nil
+			return []string{tokenAudience}, nil
 		}),
 		URL:         app.config.Auth.BaseURL,
 		DisableXSRF: app.config.Auth.DisableXSRF,
-		AvatarStore: avatar.NewLocalFS(app.config.Auth.AvatarPath),
+		AvatarStore: app.avatars,
+		ClaimsUpd:   token.ClaimsUpdFunc(app.updateClaims),
+		Validator:   token.ValidatorFunc(app.validateToken),
 	}
 
 	app.auth = authpkg.NewService(authOptions)
 
-	// Add local provider for credential checking
-	app.auth.AddDirectProvider("local", provider.CredCheckerFunc(func(user, passwd string) (ok bool, err error) {
+	// Add OAuth providers that have client credentials configured
+	if app.config.Auth.Google.Enabled() {
+		app.auth.AddCustomProvider("google", authpkg.Client{
+			Cid:     app.config.Auth.Google.ClientID,
+			Csecret: app.config.Auth.Google.ClientSecret,
+		}, auth.GoogleProvider(app.config.Auth.Google))
+		app.logger.Info("Google login enabled")
+	}
+	if app.config.Auth.Facebook.Enabled() {
+		app.auth.AddCustomProvider("facebook", authpkg.Client{
+			Cid:     app.config.Auth.Facebook.ClientID,
+			Csecret: app.config.Auth.Facebook.ClientSecret,
+		}, auth.FacebookProvider(app.config.Auth.Facebook))
+		app.logger.Info("Facebook login enabled")
+	}
+
+	// Add local provider for credential checking. Its login route is served by
+	// localLogin, which binds the checker to the throttled login attempt.
+	app.auth.AddDirectProviderWithUserIDFunc("local", app.localCredChecker(nil, app.logger), app.localUserID)
+	local, err := app.auth.Provider("local")
+	if err == nil {
+		app.localProvider, _ = local.Provider.(provider.DirectHandler)
+	}
+}
+
+// localLogin serves /auth/local/login of the direct provider with a credential
+// checker that records the outcome of the request's login attempt
+func (app *App) localLogin(w http.ResponseWriter, r *http.Request) {
+	direct := app.localProvider
+	direct.CredChecker = app.localCredChecker(throttle.FromContext(r.Context()), logger.FromContext(r.Context(), app.logger))
+	direct.LoginHandler(w, r)
+}
+
+// localCredChecker checks the password of a local account for the direct
+// provider. Wrong credentials fail the login attempt and accepted ones succeed
+// it; a correct password refused for another reason ends it neither way.
+func (app *App) localCredChecker(attempt *throttle.Attempt, log *logger.ServiceLogger) provider.CredCheckerFunc {
+	return func(user, passwd string) (ok bool, err error) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
 		// Get user from database
 		dbUser, err := app.db.GetUserByEmail(ctx, user)
 		if err != nil {
-			app.logger.Debugf("Error getting user for auth: %v", err)
+			log.Debugf("Error getting user for auth: %v", err)
 			return false, err
 		}
 		if dbUser == nil {
-			app.logger.Debugf("User not found for auth: %s", user)
+			log.Debugf("User not found for auth: %s", user)
+			app.failLogin(ctx, attempt, log)
 			return false, nil
 		}
 
 		// Check if user is local auth provider and has password
 		if dbUser.AuthProvider != "local" || dbUser.PasswordHash == nil {
-			app.logger.Debugf("User is not local auth provider: %s", user)
+			log.Debugf("User is not local auth provider: %s", user)
+			app.failLogin(ctx, attempt, log)
 			return false, nil
 		}
 
 		// Verify password using internal auth package
-		valid, err := auth.VerifyPassword(passwd, *dbUser.PasswordHash)
+		valid, err := auth.VerifyPassword(ctx, passwd, *dbUser.PasswordHash)
 		if err != nil {
-			app.logger.Debugf("Password verification error: %v", err)
+			log.Debugf("Password verification error: %v", err)
 			return false, err
 		}
+		if !valid {
+			log.Debugf("Invalid password for user: %s", user)
+			app.failLogin(ctx, attempt, log)
+			return false, nil
+		}
 
-		if valid {
-			app.logger.Infof("User authenticated successfully: %s", user)
-		} else {
-			app.logger.Debugf("Invalid password for user: %s", user)
+		if dbUser.DisabledAt != nil {
+			log.Debugf("Login attempt for disabled account: %s", user)
+			return false, nil
 		}
 
-		return valid, nil
-	}))
+		// Optionally keep unverified accounts out until they confirm their email
+		if app.config.Auth.RequireVerifiedEmail && dbUser.EmailVerifiedAt == nil {
+			log.Debugf("Login attempt with unverified email: %s", user)
+			return false, nil
+		}
+
+		// The password alone is not enough with two-factor authentication,
+		// such logins go through /api/auth/login and /api/auth/login/2fa
+		totp, err := app.db.GetTOTP(ctx, dbUser.ID)
+		if err != nil {
+			return false, err
+		}
+		if totp.Enabled() {
+			log.Debugf("Direct login refused, two-factor authentication required: %s", user)
+			return false, nil
+		}
+
+		if err := attempt.Succeed(ctx); err != nil {
+			log.Errorf("Failed to clear login failures: %v", err)
+		}
+		log.Infof("User authenticated successfully: %s", user)
+		return true, nil
+	}
+}
+
+// failLogin records a wrong password for a login attempt
+func (app *App) failLogin(ctx context.Context, attempt *throttle.Attempt, log *logger.ServiceLogger) {
+	if _, err := attempt.Fail(ctx); err != nil {
+		log.Errorf("Failed to record login failure: %v", err)
+	}
+}
+
+// localUserID keys local sign-ins by the ferna user ID rather than the email,
+// so the ID and the avatar stored for it survive email changes
+func (app *App) localUserID(user string, _ *http.Request) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbUser, err := app.db.GetUserByEmail(ctx, user)
+	if err != nil || dbUser == nil {
+		return user
+	}
+	return dbUser.ID.String()
+}
+
+// updateClaims resolves the ferna user behind a token and stores its ID in the claims,
+// so handlers can scope queries without another lookup
+func (app *App) updateClaims(claims token.Claims) token.Claims {
+	if claims.User == nil || claims.User.StrAttr(auth.UserIDAttr) != "" {
+		return claims
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var dbUser *model.User
+	var err error
+	provider := model.AuthProvider(auth.ProviderFromUserID(claims.User.ID))
+	switch provider {
+	case model.AuthProviderGoogle, model.AuthProviderFacebook:
+		dbUser, err = app.resolveOAuthUser(ctx, provider, claims.User)
+	default:
+		// For the local provider the token user name is the email used to log in
+		email := strings.TrimSpace(strings.ToLower(claims.User.Name))
+		dbUser, err = app.db.GetUserByEmail(ctx, email)
+	}
+	if err != nil {
+		app.logger.Errorf("Failed to resolve user for token claims: %v", err)
+		return claims
+	}
+	if dbUser == nil {
+		app.logger.Debugf("No user found for token claims: %s", claims.User.ID)
+		return claims
+	}
+
+	// Only the local provider asks for the second factor itself, OAuth logins
+	// of such accounts go through /api/auth/oauth/2fa and /api/auth/login/2fa
+	if provider == model.AuthProviderGoogle || provider == model.AuthProviderFacebook {
+		totp, err := app.db.GetTOTP(ctx, dbUser.ID)
+		if err != nil {
+			app.logger.Errorf("Failed to load TOTP for token claims: %v", err)
+			return claims
+		}
+		if totp.Enabled() {
+			app.logger.Debugf("%s login of user %s requires a second factor", provider, dbUser.ID)
+			claims.User.SetBoolAttr(auth.TwoFactorRequiredAttr, true)
+			return claims
+		}
+	}
+
+	auth.SetUserID(claims.User, dbUser.ID)
+	claims.User.Email = dbUser.Email
+	return claims
+}
+
+// openDatabase connects to the database selected by the DATABASE_URL scheme
+func openDatabase(ctx context.Context, config db.Config, logger *logger.ServiceLogger) (db.Store, error) {
+	if config.Driver() == db.DriverSQLite {
+		return db.NewSQLiteDB(ctx, config, logger)
+	}
+	return db.NewPostgresDB(ctx, config, logger)
+}
+
+// runMigrations applies the pending migrations of the database
+func runMigrations(ctx context.Context, database db.Store, logger *logger.ServiceLogger) error {
+	switch database := database.(type) {
+	case *db.PostgresDB:
+		return migrations.RunMigrations(ctx, database.Pool, logger)
+	case *db.SQLiteDB:
+		return migrations.RunSQLiteMigrations(ctx, database.DB, logger)
+	default:
+		return fmt.Errorf("unsupported database %T", database)
+	}
+}
+
+// newMigrator creates a migrator for the embedded migrations of the database
+func newMigrator(database db.Store, logger *logger.ServiceLogger) (*migrations.Migrator, error) {
+	switch database := database.(type) {
+	case *db.PostgresDB:
+		return migrations.NewPostgresMigrator(database.Pool, logger)
+	case *db.SQLiteDB:
+		return migrations.NewSQLiteMigrator(database.DB, logger)
+	default:
+		return nil, fmt.Errorf("unsupported database %T", database)
+	}
+}
+
+// schemaVersion returns the migration version of the database the app runs on
+func (app *App) schemaVersion(ctx context.Context) (uint, bool, error) {
+	switch database := app.db.(type) {
+	case *db.PostgresDB:
+		return migrations.Version(ctx, database.Pool)
+	case *db.SQLiteDB:
+		return migrations.SQLiteVersion(ctx, database.DB)
+	default:
+		return 0, false, fmt.Errorf("unsupported database %T", app.db)
+	}
+}
+
+// setupHealthChecks registers the dependencies that have to work for the
+// instance to receive traffic
+func (app *App) setupHealthChecks() *health.Checker {
+	checker := health.NewChecker(app.config.Logger.Service, app.logger)
+	checker.Add("database", health.Database(app.db.Ping))
+	checker.Add("migrations", health.Migrations(app.schemaVersion))
+	checker.Add("blob_store", health.BlobStore(app.blobs))
+	if app.reminders != nil {
+		// A delivery may take up to a minute, on top of the wait for the next tick
+		checker.Add("reminders", health.Heartbeat(app.reminders.Heartbeat, 2*app.config.Reminders.Interval+time.Minute))
+	}
+	return checker
+}
+
+// setupMetrics registers the collectors that read connection pool statistics
+// and the migration version on every scrape
+func (app *App) setupMetrics() {
+	if !app.config.Metrics.Enabled {
+		return
+	}
+	metrics.Registry.MustRegister(metrics.NewMigrationCollector(app.schemaVersion))
+	// SQLite runs on a single connection, so only PostgreSQL has pool statistics
+	if postgres, ok := app.db.(*db.PostgresDB); ok {
+		metrics.Registry.MustRegister(metrics.NewPoolCollector(postgres.Stats))
+	}
+}
+
+// setupMail creates the mailer and its outbox worker
+func (app *App) setupMail() error {
+	sender, err := mail.NewSender(app.config.Mail, app.logger)
+	if err != nil {
+		return err
+	}
+	app.mailer, err = mail.NewMailer(app.db, app.config.Mail, sender, app.logger)
+	return err
+}
+
+// setupReminders creates the background dispatcher that notifies users of due care tasks
+func (app *App) setupReminders() error {
+	if !app.config.Reminders.Enabled {
+		app.logger.Info("Reminder dispatcher disabled")
+		return nil
+	}
+
+	var notifiers []reminders.Notifier
+	if app.config.Push.Enabled {
+		if app.config.Push.FCM.CredentialsFile == "" {
+			app.logger.Warn("FCM_CREDENTIALS_FILE is not set, push requests are sent without authorization")
+		}
+		client, err := push.NewFCMClient(app.config.Push.FCM)
+		if err != nil {
+			return err
+		}
+		notifiers = append(notifiers, reminders.NewPushNotifier(app.db, client, app.logger))
+	}
+	if app.config.EmailReminders {
+		notifiers = append(notifiers, reminders.NewEmailNotifier(app.db, app.mailer))
+	}
+	if len(notifiers) == 0 {
+		// Without a real channel reminders are only logged, which is handy in development
+		notifiers = append(notifiers, reminders.NewLogNotifier(app.logger))
+	}
+
+	dispatcher, err := reminders.NewDispatcher(app.db, app.config.Reminders, app.logger, notifiers...)
+	if err != nil {
+		return err
+	}
+	app.reminders = dispatcher
+	return nil
+}
+
+// resolveOAuthUser finds the user an OAuth identity is linked to, creating an
+// account with no password on first login. If the email already belongs to
+// another account the identity is not attached silently; the token is marked
+// with auth.LinkRequiredAttr instead and the user has to link it explicitly.
+func (app *App) resolveOAuthUser(ctx context.Context, provider model.AuthProvider, user *token.User) (*model.User, error) {
+	subject := user.StrAttr(auth.OAuthSubjectAttr)
+	if subject == "" {
+		return nil, fmt.Errorf("%s token has no subject", provider)
+	}
+
+	dbUser, err := app.db.GetUserByIdentity(ctx, provider, subject)
+	if err != nil || dbUser != nil {
+		return dbUser, err
+	}
+
+	if user.Email == "" {
+		return nil, fmt.Errorf("%s account did not share an email address", provider)
+	}
+	existing, err := app.db.GetUserByEmail(ctx, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		app.logger.Infof("%s login for existing account %s requires linking", provider, existing.ID)
+		user.SetBoolAttr(auth.LinkRequiredAttr, true)
+		return nil, nil
+	}
+
+	// A first OAuth login signs up, so the registration mode applies. OAuth
+	// logins cannot carry an invite code, and an email the provider did not
+	// verify is not admitted by its domain. A refused token carries no user and is rejected.
+	release, err := app.signups.Admit(ctx, user.Email, "", user.BoolAttr(auth.OAuthEmailVerifiedAttr))
+	if err != nil {
+		if registration.Refused(err) {
+			app.logger.Infof("%s sign up refused for %s: %v", provider, user.Email, err)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	fullName := strings.TrimSpace(user.Name)
+	if fullName == "" {
+		fullName = user.Email
+	}
+	newUser := &model.User{
+		ID:           uuid.New(),
+		AuthProvider: provider,
+		Email:        user.Email,
+		FullName:     fullName,
+		Timezone:     "UTC", // providers do not share one, users can change it later
+	}
+	if user.Picture != "" {
+		newUser.AvatarURL = &user.Picture
+	}
+	if user.BoolAttr(auth.OAuthEmailVerifiedAttr) {
+		now := time.Now().UTC()
+		newUser.EmailVerifiedAt = &now
+	}
+	identity := &model.UserIdentity{
+		ID:       uuid.New(),
+		UserID:   newUser.ID,
+		Provider: provider,
+		Subject:  subject,
+		Email:    &newUser.Email,
+	}
+
+	if err := app.db.CreateOAuthUser(ctx, newUser, identity); err != nil {
+		release()
+		return nil, err
+	}
+	return newUser, nil
+}
+
+// validateToken rejects tokens of users that no longer exist, were disabled or
+// whose sessions were revoked after the token was issued, e.g. by a password reset
+func (app *App) validateToken(_ string, claims token.Claims) bool {
+	raw := claims.User.StrAttr(auth.UserIDAttr)
+	if raw == "" {
+		// Not a ferna user, handlers reject the request when they need one
+		return true
+	}
+	userID, err := uuid.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbUser, err := app.db.GetUserByID(ctx, userID)
+	if err != nil {
+		app.logger.Errorf("Failed to load user for token validation: %v", err)
+		return false
+	}
+	if dbUser == nil {
+		return false
+	}
+
+	if dbUser.DisabledAt != nil {
+		app.logger.Debugf("Rejecting token of disabled user %s", userID)
+		return false
+	}
+
+	// JWT timestamps have second precision, so the revocation time is rounded
+	// up: a token from the same second may predate it. A sign-in within that
+	// second after the revocation is rejected too and has to be repeated.
+	if dbUser.SessionsRevokedAt != nil {
+		cutoff := dbUser.SessionsRevokedAt.Truncate(time.Second)
+		if cutoff.Before(*dbUser.SessionsRevokedAt) {
+			cutoff = cutoff.Add(time.Second)
+		}
+		if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(cutoff) {
+			app.logger.Debugf("Rejecting revoked token of user %s", userID)
+			return false
+		}
+	}
+	return true
 }
 
 // start starts the server with graceful shutdown
@@ -171,6 +742,13 @@ func (app *App) start() error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	// Start background workers
+	app.mailer.Start()
+	if app.reminders != nil {
+		app.reminders.Start()
+	}
+	app.eraser.Start()
+
 	// Start server in a goroutine
 	go func() {
 		app.logger.Infof("Server starting on %s", app.server.Addr)
@@ -180,6 +758,16 @@ func (app *App) start() error {
 		}
 	}()
 
+	if app.admin != nil {
+		go func() {
+			app.logger.Infof("Metrics server starting on %s", app.admin.Addr)
+			if err := app.admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				app.logger.Errorf("Metrics server failed to start: %v", err)
+				quit <- syscall.SIGTERM
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	<-quit
 	app.logger.Info("Server shutdown signal received...")
@@ -188,32 +776,35 @@ func (app *App) start() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// handle other cleanup tasks here
+	// Stop background workers before the server so in-flight reminders and mail are recorded
+	if app.reminders != nil {
+		if err := app.reminders.Stop(ctx); err != nil {
+			app.logger.Errorf("Reminder dispatcher shutdown: %v", err)
+		}
+	}
+	if err := app.mailer.Stop(ctx); err != nil {
+		app.logger.Errorf("Mail worker shutdown: %v", err)
+	}
+	if err := app.eraser.Stop(ctx); err != nil {
+		app.logger.Errorf("Account eraser shutdown: %v", err)
+	}
 
 	// Attempt graceful shutdown
+	if app.admin != nil {
+		if err := app.admin.Shutdown(ctx); err != nil {
+			app.logger.Errorf("Metrics server shutdown: %v", err)
+		}
+	}
 	if err := app.server.Shutdown(ctx); err != nil {
 		app.logger.Errorf("Server forced to shutdown: %v", err)
 		return err
 	}
 
+	// Flush the spans of the last requests
+	if err := app.tracing(ctx); err != nil {
+		app.logger.Errorf("Tracing shutdown: %v", err)
+	}
+
 	app.logger.Info("Server exited")
 	return nil
 }
-
-// HTTP Handlers
-
-// healthCheckHandler provides a simple health check endpoint
-func (app *App) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	response := fmt.Sprintf(`{
-		"status": "healthy",
-		"service": "%s",
-		"timestamp": "%s"
-	}`, app.config.Logger.Service, time.Now().UTC().Format(time.RFC3339))
-
-	w.Write([]byte(response))
-
-	app.logger.Debug("Health check accessed")
-}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/anish-chanda/ferna/internal/account"
+	"github.com/anish-chanda/ferna/internal/auth"
+	"github.com/anish-chanda/ferna/internal/db/dbtest"
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/internal/registration"
+	"github.com/anish-chanda/ferna/internal/storage"
+	"github.com/anish-chanda/ferna/internal/throttle"
+	"github.com/anish-chanda/ferna/model"
+	"github.com/go-pkgz/auth/v2/token"
+	"github.com/google/uuid"
+)
+
+// fakeOIDC is a stand-in for the authorization, token and user info endpoints
+// of an OpenID Connect provider. The authorization code chosen by the test
+// selects the profile returned for the login.
+type fakeOIDC struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	profiles map[string]map[string]any // User info by authorization code
+}
+
+func (f *fakeOIDC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/token":
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+			f.t.Errorf("invalid token request: %v %v", err, r.PostForm)
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		// The code doubles as the access token
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": %q, "token_type": "Bearer", "expires_in": 3600}`, r.PostForm.Get("code"))
+	case "/userinfo":
+		f.mu.Lock()
+		profile, ok := f.profiles[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		f.mu.Unlock()
+		if !ok {
+			http.Error(w, "invalid access token", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(profile)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// addProfile registers the user info returned for logins with code
+func (f *fakeOIDC) addProfile(code string, profile map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.profiles[code] = profile
+}
+
+// newTestApp sets up the server like main does, on a SQLite database with
+// Google sign-in pointed at a fake provider. env overrides configuration.
+func newTestApp(t *testing.T, env map[string]string) (*App, *fakeOIDC) {
+	t.Helper()
+	fake := &fakeOIDC{t: t, profiles: map[string]map[string]any{}}
+	provider := httptest.NewServer(fake)
+	t.Cleanup(provider.Close)
+
+	defaults := map[string]string{
+		"DATABASE_URL":         "sqlite://" + t.TempDir() + "/ferna.db",
+		"LOG_LEVEL":            "error",
+		"JWT_SECRET":           "test-secret",
+		"BLOB_STORE_PATH":      t.TempDir(),
+		"REMINDERS_ENABLED":    "false",
+		"METRICS_ENABLED":      "false",
+		"GOOGLE_CLIENT_ID":     "client",
+		"GOOGLE_CLIENT_SECRET": "secret",
+		"GOOGLE_AUTH_URL":      provider.URL + "/authorize",
+		"GOOGLE_TOKEN_URL":     provider.URL + "/token",
+		"GOOGLE_USERINFO_URL":  provider.URL + "/userinfo",
+	}
+	for key, value := range env {
+		defaults[key] = value
+	}
+	for key, value := range defaults {
+		t.Setenv(key, value)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	config.Auth.AvatarPath = t.TempDir()
+
+	log := logger.New(config.Logger)
+	database := dbtest.NewSQLite(t)
+	blobs, err := storage.New(config.Storage)
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+	app := &App{config: config, logger: log, db: database, blobs: blobs}
+
+	if app.signups, err = registration.NewPolicy(database, config.Registration, log); err != nil {
+		t.Fatalf("failed to setup registration: %v", err)
+	}
+	app.setupAuthService()
+	if err := app.setupMail(); err != nil {
+		t.Fatalf("failed to setup mail: %v", err)
+	}
+	if app.throttler, err = throttle.New(database, app.mailer, config.Throttle, log); err != nil {
+		t.Fatalf("failed to setup login throttling: %v", err)
+	}
+	if app.eraser, err = account.NewEraser(database, blobs, app.avatars, config.Deletion, log); err != nil {
+		t.Fatalf("failed to setup account deletion: %v", err)
+	}
+	if err := app.setupServer(); err != nil {
+		t.Fatalf("failed to setup server: %v", err)
+	}
+	return app, fake
+}
+
+// googleLogin runs the OAuth flow of a Google login answered by the fake
+// provider with the profile of code. It returns the issued JWT and its claims.
+func googleLogin(t *testing.T, app *App, code string) (string, token.Claims) {
+	t.Helper()
+	handler := app.server.Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/google/login?aud="+tokenAudience, nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login returned %d: %s", rec.Code, rec.Body)
+	}
+	redirect, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || redirect.Path != "/authorize" || redirect.Query().Get("client_id") != "client" {
+		t.Fatalf("login redirected to %s", rec.Header().Get("Location"))
+	}
+
+	// The provider sends the browser back with the code and the state
+	callback := fmt.Sprintf("/auth/google/callback?code=%s&state=%s", url.QueryEscape(code), url.QueryEscape(redirect.Query().Get("state")))
+	req := httptest.NewRequest(http.MethodGet, callback, nil)
+	for _, cookie := range rec.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback returned %d: %s", rec.Code, rec.Body)
+	}
+
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == "JWT" {
+			claims, err := app.auth.TokenService().Parse(cookie.Value)
+			if err != nil {
+				t.Fatalf("failed to parse token: %v", err)
+			}
+			return cookie.Value, claims
+		}
+	}
+	t.Fatalf("callback issued no token")
+	return "", token.Claims{}
+}
+
+// createLocalUser stores a local account with password
+func createLocalUser(t *testing.T, app *App, email, password string) *model.User {
+	t.Helper()
+	ctx := context.Background()
+	hash, err := auth.HashPassword(ctx, password)
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	user := &model.User{
+		ID:           uuid.New(),
+		AuthProvider: model.AuthProviderLocal,
+		Email:        email,
+		FullName:     "Local User",
+		PasswordHash: &hash,
+		Timezone:     "UTC",
+	}
+	if _, err := app.db.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	return user
+}
+
+// linkIdentity posts the password of the local account to /api/auth/link with the token of an OAuth login
+func linkIdentity(t *testing.T, app *App, jwt, password string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/link", strings.NewReader(fmt.Sprintf(`{"password": %q}`, password)))
+	req.Header.Set("X-JWT", jwt)
+	rec := httptest.NewRecorder()
+	app.server.Handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// TestOAuthFirstLoginCreatesUser checks that the first login of a provider
+// account creates a user without a password, and later logins find it again
+func TestOAuthFirstLoginCreatesUser(t *testing.T) {
+	ctx := context.Background()
+	app, fake := newTestApp(t, nil)
+	fake.addProfile("new", map[string]any{"sub": "1001", "email": "New@Example.com", "email_verified": true, "name": "New User"})
+
+	_, claims := googleLogin(t, app, "new")
+	userID := claims.User.StrAttr(auth.UserIDAttr)
+	if userID == "" || claims.User.BoolAttr(auth.LinkRequiredAttr) {
+		t.Fatalf("first login got user %q, link required %v", userID, claims.User.BoolAttr(auth.LinkRequiredAttr))
+	}
+
+	user, err := app.db.GetUserByEmail(ctx, "new@example.com")
+	if err != nil || user == nil {
+		t.Fatalf("GetUserByEmail = %v, %v", user, err)
+	}
+	if user.ID.String() != userID || user.AuthProvider != model.AuthProviderGoogle || user.FullName != "New User" {
+		t.Errorf("created user %+v for token user %s", user, userID)
+	}
+	if user.PasswordHash != nil {
+		t.Errorf("OAuth user was created with a password")
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Errorf("verified provider email was not marked verified")
+	}
+
+	_, claims = googleLogin(t, app, "new")
+	if got := claims.User.StrAttr(auth.UserIDAttr); got != userID {
+		t.Errorf("second login got user %q, want %s", got, userID)
+	}
+	users, err := app.db.ListUsers(ctx)
+	if err != nil || len(users) != 1 {
+		t.Errorf("ListUsers = %d users, %v, want 1", len(users), err)
+	}
+}
+
+// TestOAuthLoginRequiresLinkForLocalAccount checks that a provider account
+// with the email of a local account does not sign into it until the owner
+// links it with the account password
+func TestOAuthLoginRequiresLinkForLocalAccount(t *testing.T) {
+	ctx := context.Background()
+	app, fake := newTestApp(t, nil)
+	local := createLocalUser(t, app, "owner@example.com", "correct horse")
+	fake.addProfile("owner", map[string]any{"sub": "2002", "email": "owner@example.com", "email_verified": true})
+
+	jwt, claims := googleLogin(t, app, "owner")
+	if got := claims.User.StrAttr(auth.UserIDAttr); got != "" {
+		t.Fatalf("login signed into user %s without linking", got)
+	}
+	if !claims.User.BoolAttr(auth.LinkRequiredAttr) {
+		t.Fatalf("token is not marked as requiring a link")
+	}
+	if user, err := app.db.GetUserByIdentity(ctx, model.AuthProviderGoogle, "2002"); err != nil || user != nil {
+		t.Fatalf("identity was linked before the password was given: %v, %v", user, err)
+	}
+
+	if status := linkIdentity(t, app, jwt, "wrong password"); status != http.StatusUnauthorized {
+		t.Errorf("link with a wrong password returned %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := linkIdentity(t, app, jwt, "correct horse"); status != http.StatusOK {
+		t.Fatalf("link with the password returned %d, want %d", status, http.StatusOK)
+	}
+
+	_, claims = googleLogin(t, app, "owner")
+	if got := claims.User.StrAttr(auth.UserIDAttr); got != local.ID.String() {
+		t.Errorf("login after linking got user %q, want %s", got, local.ID)
+	}
+}
+
+// TestOAuthUnverifiedEmail checks that an email the provider did not verify
+// can neither be linked to the local account using it nor claim an allowed domain
+func TestOAuthUnverifiedEmail(t *testing.T) {
+	t.Run("link", func(t *testing.T) {
+		app, fake := newTestApp(t, nil)
+		createLocalUser(t, app, "owner@example.com", "correct horse")
+		fake.addProfile("unverified", map[string]any{"sub": "3003", "email": "owner@example.com", "email_verified": false})
+
+		jwt, claims := googleLogin(t, app, "unverified")
+		if got := claims.User.StrAttr(auth.UserIDAttr); got != "" {
+			t.Fatalf("login signed into user %s", got)
+		}
+		// Even the right password does not link an identity whose email may belong to someone else
+		if status := linkIdentity(t, app, jwt, "correct horse"); status != http.StatusForbidden {
+			t.Errorf("link of an unverified email returned %d, want %d", status, http.StatusForbidden)
+		}
+		if user, err := app.db.GetUserByIdentity(context.Background(), model.AuthProviderGoogle, "3003"); err != nil || user != nil {
+			t.Errorf("unverified identity was linked: %v, %v", user, err)
+		}
+	})
+
+	t.Run("domain", func(t *testing.T) {
+		app, fake := newTestApp(t, map[string]string{
+			"REGISTRATION_MODE":            "domains",
+			"REGISTRATION_ALLOWED_DOMAINS": "example.com",
+			"REQUIRE_VERIFIED_EMAIL":       "true",
+		})
+		fake.addProfile("unverified", map[string]any{"sub": "4004", "email": "someone@example.com", "email_verified": false})
+		fake.addProfile("verified", map[string]any{"sub": "5005", "email": "other@example.com", "email_verified": true})
+
+		_, claims := googleLogin(t, app, "unverified")
+		if got := claims.User.StrAttr(auth.UserIDAttr); got != "" {
+			t.Errorf("unverified email of an allowed domain signed up as %s", got)
+		}
+		if user, err := app.db.GetUserByEmail(context.Background(), "someone@example.com"); err != nil || user != nil {
+			t.Errorf("user was created for an unverified email: %v, %v", user, err)
+		}
+
+		_, claims = googleLogin(t, app, "verified")
+		if claims.User.StrAttr(auth.UserIDAttr) == "" {
+			t.Errorf("verified email of an allowed domain was not signed up")
+		}
+	})
+}
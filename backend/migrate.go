@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/anish-chanda/ferna/internal/logger"
+	"github.com/anish-chanda/ferna/migrations"
+)
+
+// migrateUsage documents the migrate subcommands
+const migrateUsage = `Usage: ferna-api migrate <command> [argument]
+
+Commands:
+  up         apply all pending migrations
+  down N     roll back the last N migrations
+  goto V     migrate up or down to version V
+  status     show the schema version and the pending migrations
+  force V    record version V and clear the dirty flag without running any migration,
+             after a failed migration was repaired by hand (0 means never migrated)
+`
+
+// runMigrateCommand runs a migrate subcommand against the configured database
+// and returns the process exit code
+func runMigrateCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	command := args[0]
+	var number uint64
+	switch command {
+	case "up", "status":
+		if len(args) != 1 {
+			fmt.Fprintf(os.Stderr, "migrate %s takes no arguments\n\n%s", command, migrateUsage)
+			return 2
+		}
+	case "down", "goto", "force":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "migrate %s takes exactly one number\n\n%s", command, migrateUsage)
+			return 2
+		}
+		var err error
+		if number, err = strconv.ParseUint(args[1], 10, 32); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid number %q for migrate %s\n", args[1], command)
+			return 2
+		}
+	case "help", "-h", "--help":
+		fmt.Print(migrateUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown migrate command %q\n\n%s", command, migrateUsage)
+		return 2
+	}
+
+	// Load configuration
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	appLogger := logger.New(config.Logger)
+	logger.SetGlobalLogger(config.Logger)
+
+	ctx := context.Background()
+	database, err := openDatabase(ctx, config.Database, appLogger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize database: %v\n", err)
+		return 1
+	}
+	defer database.Close()
+
+	migrator, err := newMigrator(database, appLogger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to setup migrations: %v\n", err)
+		return 1
+	}
+	defer migrator.Close()
+
+	switch command {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down(int(number))
+	case "goto":
+		err = migrator.Goto(uint(number))
+	case "force":
+		err = migrator.Force(uint(number))
+	case "status":
+		var status *migrations.Status
+		if status, err = migrator.Status(); err == nil {
+			printMigrationStatus(status)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Migrate %s failed: %v\n", command, err)
+		return 1
+	}
+	return 0
+}
+
+// printMigrationStatus writes a migration status to stdout
+func printMigrationStatus(status *migrations.Status) {
+	pending := "none"
+	if len(status.Pending) > 0 {
+		versions := make([]string, len(status.Pending))
+		for i, version := range status.Pending {
+			versions[i] = strconv.FormatUint(uint64(version), 10)
+		}
+		pending = strings.Join(versions, ", ")
+	}
+
+	fmt.Printf("Version: %d\n", status.Version)
+	fmt.Printf("Dirty:   %t\n", status.Dirty)
+	fmt.Printf("Latest:  %d\n", status.Latest)
+	fmt.Printf("Pending: %s\n", pending)
+	if status.Dirty {
+		fmt.Println("\nThe last migration failed halfway. Repair the schema, then run 'ferna-api migrate force V' with the last version that applied cleanly.")
+	}
+}
@@ -2,13 +2,21 @@ package migrations
 
 import (
 	"context"
+	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 
 	"github.com/anish-chanda/ferna/internal/logger"
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
 )
@@ -16,66 +24,263 @@ import (
 //go:embed postgresql/*.sql
 var PostgresMigrations embed.FS
 
-// RunMigrations executes database migrations using the embedded PostgreSQL files
-func RunMigrations(ctx context.Context, pool *pgxpool.Pool, logger *logger.ServiceLogger) error {
-	logger.Info("Starting PostgreSQL database migrations")
+//go:embed sqlite/*.sql
+var SQLiteMigrations embed.FS
 
+// Migrator applies the embedded migrations to a database
+type Migrator struct {
+	m      *migrate.Migrate
+	source source.Driver
+	logger *logger.ServiceLogger
+	close  func()
+}
+
+// Status describes the schema of a database relative to the embedded migrations
+type Status struct {
+	Version uint // 0 if the database was never migrated
+	Dirty   bool
+	Latest  uint
+	Pending []uint
+}
+
+// NewPostgresMigrator creates a migrator for a PostgreSQL pool using the embedded PostgreSQL files
+func NewPostgresMigrator(pool *pgxpool.Pool, logger *logger.ServiceLogger) (*Migrator, error) {
 	// Create source driver from embedded filesystem
 	source, err := iofs.New(PostgresMigrations, "postgresql")
 	if err != nil {
-		return fmt.Errorf("failed to create migration source: %w", err)
+		return nil, fmt.Errorf("failed to create migration source: %w", err)
 	}
-	defer source.Close()
 
 	// Convert pgxpool to sql.DB for migrate
 	sqlDB := stdlib.OpenDBFromPool(pool)
-	defer sqlDB.Close()
 
 	// Create database driver for postgres
 	driver, err := postgres.WithInstance(sqlDB, &postgres.Config{})
 	if err != nil {
-		return fmt.Errorf("failed to create postgres driver: %w", err)
+		source.Close()
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to create postgres driver: %w", err)
 	}
 
 	// Create migrate instance
 	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
 	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
+		source.Close()
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+
+	migrator := &Migrator{m: m, source: source, logger: logger}
+	migrator.close = func() { m.Close() }
+	return migrator, nil
+}
+
+// NewSQLiteMigrator creates a migrator for a SQLite database using the embedded SQLite files
+func NewSQLiteMigrator(sqlDB *sql.DB, logger *logger.ServiceLogger) (*Migrator, error) {
+	// Create source driver from embedded filesystem
+	source, err := iofs.New(SQLiteMigrations, "sqlite")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration source: %w", err)
+	}
+
+	// Create database driver for sqlite
+	driver, err := sqlite.WithInstance(sqlDB, &sqlite.Config{})
+	if err != nil {
+		source.Close()
+		return nil, fmt.Errorf("failed to create sqlite driver: %w", err)
 	}
-	defer m.Close()
 
+	// Create migrate instance
+	m, err := migrate.NewWithInstance("iofs", source, "sqlite", driver)
+	if err != nil {
+		source.Close()
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+
+	// Closing m would close the database handle, which is owned by the caller
+	migrator := &Migrator{m: m, source: source, logger: logger}
+	migrator.close = func() { source.Close() }
+	return migrator, nil
+}
+
+// Close releases the resources held by the migrator
+func (mg *Migrator) Close() {
+	mg.close()
+}
+
+// RunMigrations executes database migrations using the embedded PostgreSQL files
+func RunMigrations(ctx context.Context, pool *pgxpool.Pool, logger *logger.ServiceLogger) error {
+	logger.Info("Starting PostgreSQL database migrations")
+
+	migrator, err := NewPostgresMigrator(pool, logger)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	return migrator.Up()
+}
+
+// RunSQLiteMigrations executes database migrations using the embedded SQLite files
+func RunSQLiteMigrations(ctx context.Context, sqlDB *sql.DB, logger *logger.ServiceLogger) error {
+	logger.Info("Starting SQLite database migrations")
+
+	migrator, err := NewSQLiteMigrator(sqlDB, logger)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	return migrator.Up()
+}
+
+// Up applies all pending migrations
+func (mg *Migrator) Up() error {
 	// Get current migration version
-	currentVersion, dirty, err := m.Version()
+	currentVersion, dirty, err := mg.m.Version()
 	if err != nil && err != migrate.ErrNilVersion {
 		return fmt.Errorf("failed to get current migration version: %w", err)
 	}
 
 	if dirty {
-		return fmt.Errorf("database is in a dirty state at version %d", currentVersion)
+		return fmt.Errorf("database is in a dirty state at version %d, fix the schema and run 'migrate force' with the last good version", currentVersion)
 	}
 
 	if err == migrate.ErrNilVersion {
-		logger.Debug("No existing migrations found, starting fresh")
+		mg.logger.Debug("No existing migrations found, starting fresh")
 	} else {
-		logger.Debugf("Current database version: %d", currentVersion)
+		mg.logger.Debugf("Current database version: %d", currentVersion)
 	}
 
 	// Run migrations
-	err = m.Up()
+	err = mg.m.Up()
 	if err != nil && err != migrate.ErrNoChange {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
 	if err == migrate.ErrNoChange {
-		logger.Debug("No new migrations to apply")
-	} else {
-		// Get new version after migration
-		newVersion, _, err := m.Version()
-		if err != nil {
-			return fmt.Errorf("failed to get new migration version: %w", err)
+		mg.logger.Debug("No new migrations to apply")
+		return nil
+	}
+	return mg.logVersion("Migrations completed successfully")
+}
+
+// Down rolls back the given number of applied migrations
+func (mg *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return errors.New("number of migrations to roll back must be at least 1")
+	}
+
+	err := mg.m.Steps(-steps)
+	var short migrate.ErrShortLimit
+	if errors.As(err, &short) {
+		// Steps rolls back what it can before reporting that it ran out of migrations
+		mg.logger.Warnf("Rolled back %d of %d migrations, no older migrations are applied", steps-int(short.Short), steps)
+	} else if err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+
+	if err == migrate.ErrNoChange {
+		mg.logger.Info("No migrations to roll back")
+		return nil
+	}
+	return mg.logVersion("Rollback completed successfully")
+}
+
+// Goto migrates up or down to the given version
+func (mg *Migrator) Goto(version uint) error {
+	err := mg.m.Migrate(version)
+	if err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("failed to migrate to version %d: %w", version, err)
+	}
+
+	if err == migrate.ErrNoChange {
+		mg.logger.Infof("Database is already at version %d", version)
+		return nil
+	}
+	return mg.logVersion("Migration completed successfully")
+}
+
+// Force records version as the current version and clears the dirty flag
+// without running any migration. It is used after repairing a failed migration
+// by hand. Version 0 marks the database as never migrated.
+func (mg *Migrator) Force(version uint) error {
+	target := int(version)
+	if version == 0 {
+		target = database.NilVersion
+	}
+	if err := mg.m.Force(target); err != nil {
+		return fmt.Errorf("failed to force version %d: %w", version, err)
+	}
+
+	mg.logger.Infof("Database version forced to %d", version)
+	return nil
+}
+
+// Status reports the current version of the database and the migrations that are not applied yet
+func (mg *Migrator) Status() (*Status, error) {
+	version, dirty, err := mg.m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return nil, fmt.Errorf("failed to get current migration version: %w", err)
+	}
+
+	status := &Status{Version: version, Dirty: dirty, Pending: []uint{}}
+
+	// Walk all embedded migrations to find the latest and the pending ones
+	next, err := mg.source.First()
+	for err == nil {
+		status.Latest = next
+		if next > status.Version {
+			status.Pending = append(status.Pending, next)
 		}
-		logger.Infof("Migrations completed successfully. Database version: %d", newVersion)
+		next, err = mg.source.Next(next)
+	}
+	if !errors.Is(err, os.ErrNotExist) && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
 	}
 
+	return status, nil
+}
+
+// logVersion logs msg with the version the database is at now
+func (mg *Migrator) logVersion(msg string) error {
+	version, _, err := mg.m.Version()
+	if err == migrate.ErrNilVersion {
+		mg.logger.Infof("%s. Database version: 0", msg)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get new migration version: %w", err)
+	}
+	mg.logger.Infof("%s. Database version: %d", msg, version)
 	return nil
 }
+
+// Version returns the schema version recorded by golang-migrate and whether the
+// last migration failed halfway. A database that was never migrated is at version 0.
+func Version(ctx context.Context, pool *pgxpool.Pool) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	return uint(version), dirty, nil
+}
+
+// SQLiteVersion returns the schema version of a SQLite database, like Version
+func SQLiteVersion(ctx context.Context, sqlDB *sql.DB) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := sqlDB.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	return uint(version), dirty, nil
+}
@@ -0,0 +1,15 @@
+-- Plants owned by a user
+CREATE TABLE plants (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    species text,
+    location text,
+    notes text,
+    acquired_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT plants_name_not_empty CHECK (length(trim(name)) > 0)
+);
+
+CREATE INDEX plants_user_id_idx ON plants (user_id, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE care_schedules;
+DROP TYPE care_task_type;
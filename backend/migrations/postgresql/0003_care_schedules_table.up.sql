@@ -0,0 +1,20 @@
+-- Enum for care task types
+CREATE TYPE care_task_type AS ENUM ('water', 'fertilize', 'mist', 'repot', 'prune', 'rotate', 'clean', 'other');
+
+-- Recurring care schedules. anchor_local is a wall-clock time interpreted in the
+-- owner's timezone, next_due_at is computed by the server from rrule and anchor_local.
+CREATE TABLE care_schedules (
+    id uuid PRIMARY KEY,
+    plant_id uuid NOT NULL REFERENCES plants (id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    task_type care_task_type NOT NULL,
+    rrule text NOT NULL,
+    anchor_local timestamp NOT NULL,
+    notes text,
+    next_due_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX care_schedules_plant_id_idx ON care_schedules (plant_id);
+CREATE INDEX care_schedules_user_due_idx ON care_schedules (user_id, next_due_at);
@@ -0,0 +1,4 @@
+DROP TABLE care_task_records;
+DROP TYPE care_task_action;
+
+ALTER TABLE care_schedules DROP COLUMN occurrence_at;
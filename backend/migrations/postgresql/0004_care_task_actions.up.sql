@@ -0,0 +1,24 @@
+-- occurrence_at is the series occurrence a schedule is waiting on. next_due_at
+-- equals it unless the occurrence has been snoozed.
+ALTER TABLE care_schedules ADD COLUMN occurrence_at timestamptz;
+UPDATE care_schedules SET occurrence_at = next_due_at;
+ALTER TABLE care_schedules ALTER COLUMN occurrence_at SET NOT NULL;
+
+-- Enum for actions taken on a care task
+CREATE TYPE care_task_action AS ENUM ('complete', 'snooze', 'skip', 'reschedule');
+
+-- Append-only record of actions taken on care schedules
+CREATE TABLE care_task_records (
+    id uuid PRIMARY KEY,
+    schedule_id uuid NOT NULL REFERENCES care_schedules (id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    action care_task_action NOT NULL,
+    occurrence_at timestamptz NOT NULL, -- occurrence the action applied to
+    acted_at timestamptz NOT NULL, -- completion time for 'complete', request time otherwise
+    previous_due_at timestamptz NOT NULL,
+    next_due_at timestamptz NOT NULL,
+    next_occurrence_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX care_task_records_schedule_id_idx ON care_task_records (schedule_id, created_at);
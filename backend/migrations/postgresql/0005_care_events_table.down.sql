@@ -0,0 +1,4 @@
+-- Dropping the table also drops its append-only trigger
+DROP TABLE care_events;
+DROP FUNCTION care_events_reject_update();
+DROP TYPE care_event_type;
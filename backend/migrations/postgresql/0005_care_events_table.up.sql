@@ -0,0 +1,28 @@
+-- Enum for care event types, the care task types plus notes and photos
+CREATE TYPE care_event_type AS ENUM ('water', 'fertilize', 'mist', 'repot', 'prune', 'rotate', 'clean', 'note', 'photo', 'other');
+
+-- Append-only log of everything that happened to a plant
+CREATE TABLE care_events (
+    id uuid PRIMARY KEY,
+    plant_id uuid NOT NULL REFERENCES plants (id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE, -- actor
+    type care_event_type NOT NULL,
+    occurred_at timestamptz NOT NULL,
+    payload jsonb NOT NULL DEFAULT '{}'::jsonb,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT care_events_payload_is_object CHECK (jsonb_typeof(payload) = 'object')
+);
+
+CREATE INDEX care_events_timeline_idx ON care_events (plant_id, occurred_at DESC, id DESC);
+
+-- Events are never edited. Deletes are still allowed so plant and account
+-- removal can cascade.
+CREATE FUNCTION care_events_reject_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'care_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER care_events_append_only
+    BEFORE UPDATE ON care_events
+    FOR EACH ROW EXECUTE FUNCTION care_events_reject_update();
@@ -0,0 +1,17 @@
+-- Photos attached to plants. The image data lives in the blob store under
+-- blob_key and thumbnail_key.
+CREATE TABLE plant_photos (
+    id uuid PRIMARY KEY,
+    plant_id uuid NOT NULL REFERENCES plants (id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    blob_key text NOT NULL,
+    thumbnail_key text NOT NULL,
+    content_type text NOT NULL,
+    width integer NOT NULL,
+    height integer NOT NULL,
+    size_bytes bigint NOT NULL,
+    caption text,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX plant_photos_plant_id_idx ON plant_photos (plant_id, created_at DESC);
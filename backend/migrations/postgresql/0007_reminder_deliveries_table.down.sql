@@ -0,0 +1,2 @@
+DROP TABLE reminder_deliveries;
+DROP TYPE reminder_delivery_status;
@@ -0,0 +1,24 @@
+-- Enum for reminder delivery states
+CREATE TYPE reminder_delivery_status AS ENUM ('pending', 'sent', 'failed', 'cancelled');
+
+-- One row per care task occurrence and notification channel. The unique key
+-- deduplicates reminders (a snoozed occurrence gets one more reminder when the
+-- snooze ends), and pending rows are retried until they are sent, which gives
+-- at-least-once delivery across restarts.
+CREATE TABLE reminder_deliveries (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    schedule_id uuid NOT NULL REFERENCES care_schedules (id) ON DELETE CASCADE,
+    channel text NOT NULL,
+    occurrence_at timestamptz NOT NULL,
+    due_at timestamptz NOT NULL,
+    status reminder_delivery_status NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error text,
+    sent_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT reminder_deliveries_dedup UNIQUE (schedule_id, occurrence_at, due_at, channel)
+);
+
+CREATE INDEX reminder_deliveries_pending_idx ON reminder_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,17 @@
+-- Enum for the platform a push token belongs to
+CREATE TYPE device_platform AS ENUM ('android', 'ios', 'web');
+
+-- Push notification tokens registered by the mobile app. A token identifies an
+-- app install, so it belongs to at most one user at a time.
+CREATE TABLE devices (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token text NOT NULL,
+    platform device_platform NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT devices_token_unique UNIQUE (token),
+    CONSTRAINT devices_token_not_empty CHECK (length(token) > 0)
+);
+
+CREATE INDEX devices_user_id_idx ON devices (user_id);
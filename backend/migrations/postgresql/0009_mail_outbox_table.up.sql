@@ -0,0 +1,21 @@
+-- Enum for outbound mail states
+CREATE TYPE mail_status AS ENUM ('pending', 'sent', 'failed');
+
+-- Rendered messages waiting to be sent. Pending rows are retried with backoff,
+-- so mail queued before a restart or an SMTP outage is still delivered.
+CREATE TABLE mail_outbox (
+    id uuid PRIMARY KEY,
+    to_address text NOT NULL,
+    subject text NOT NULL,
+    text_body text NOT NULL,
+    html_body text NOT NULL,
+    status mail_status NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error text,
+    sent_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX mail_outbox_pending_idx ON mail_outbox (next_attempt_at) WHERE status = 'pending';
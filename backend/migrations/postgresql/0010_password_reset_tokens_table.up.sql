@@ -0,0 +1,15 @@
+-- Tokens issued before this time are rejected, e.g. after a password reset
+ALTER TABLE users ADD COLUMN sessions_revoked_at timestamptz;
+
+-- Single-use password reset tokens. Only a SHA-256 hash of the token is stored.
+CREATE TABLE password_reset_tokens (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash bytea NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT password_reset_tokens_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
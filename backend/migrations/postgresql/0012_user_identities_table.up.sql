@@ -0,0 +1,14 @@
+-- External OAuth identities a user can sign in with. An identity is only ever
+-- attached to an existing account through explicit linking.
+CREATE TABLE user_identities (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider user_auth_provider NOT NULL,
+    subject text NOT NULL,
+    email varchar(254),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT user_identities_provider_subject_unique UNIQUE (provider, subject),
+    CONSTRAINT user_identities_not_local CHECK (provider <> 'local')
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
@@ -0,0 +1,18 @@
+-- Personal API keys for scripts and integrations. Only a hash of the key is
+-- stored; the prefix is kept in clear text so users can tell their keys apart.
+-- An empty scope list grants the same access as the user's session.
+CREATE TABLE api_keys (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    prefix text NOT NULL,
+    key_hash bytea NOT NULL,
+    scopes text[] NOT NULL DEFAULT '{}',
+    last_used_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT api_keys_key_hash_unique UNIQUE (key_hash),
+    CONSTRAINT api_keys_name_not_empty CHECK (length(name) > 0),
+    CONSTRAINT api_keys_scopes_valid CHECK (scopes <@ ARRAY['read', 'plants', 'tasks']::text[])
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
@@ -0,0 +1,5 @@
+DROP TABLE file_deletions;
+DROP TYPE file_store;
+
+DROP INDEX users_deletion_scheduled_idx;
+ALTER TABLE users DROP COLUMN deletion_scheduled_at;
@@ -0,0 +1,23 @@
+-- Accounts scheduled for erasure. The account keeps working until the grace
+-- period ends so the owner can still cancel the deletion.
+ALTER TABLE users ADD COLUMN deletion_scheduled_at timestamptz;
+
+CREATE INDEX users_deletion_scheduled_idx ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+-- Enum for the file stores an erased account can leave files in
+CREATE TYPE file_store AS ENUM ('blobs', 'avatars');
+
+-- Files left behind by erased accounts. Rows are written in the transaction
+-- that deletes the account and removed once the file is gone, so files are
+-- cleaned up even if the store is unavailable at the time of the erasure.
+CREATE TABLE file_deletions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    store file_store NOT NULL,
+    key text NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error text,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX file_deletions_next_attempt_idx ON file_deletions (next_attempt_at);
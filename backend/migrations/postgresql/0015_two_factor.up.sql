@@ -0,0 +1,36 @@
+-- TOTP secrets of local accounts. A row without enabled_at is an enrollment
+-- that has not been confirmed with a code yet. last_used_step stops a code
+-- from being used twice.
+CREATE TABLE user_totp (
+    user_id uuid PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret text NOT NULL,
+    enabled_at timestamptz,
+    last_used_step bigint,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Single-use recovery codes, hashed with Argon2id like passwords
+CREATE TABLE totp_recovery_codes (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash text NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX totp_recovery_codes_user_id_idx ON totp_recovery_codes (user_id);
+
+-- Logins whose password was correct and that wait for the second factor.
+-- Only a SHA-256 hash of the challenge token is stored.
+CREATE TABLE login_challenges (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash bytea NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT login_challenges_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX login_challenges_user_id_idx ON login_challenges (user_id);
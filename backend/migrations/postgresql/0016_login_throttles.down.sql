@@ -0,0 +1,2 @@
+DROP TABLE login_throttles;
+DROP TYPE throttle_scope;
@@ -0,0 +1,16 @@
+-- Enum for what a login throttle counts failures for
+CREATE TYPE throttle_scope AS ENUM ('ip', 'account');
+
+-- Failed logins per client address and per account email. A row is kept in
+-- the database rather than in memory so every API instance sees the same
+-- count. blocked_until is set once the failures call for a backoff or lockout.
+CREATE TABLE login_throttles (
+    scope throttle_scope NOT NULL,
+    key text NOT NULL,
+    failures integer NOT NULL DEFAULT 0,
+    last_failure_at timestamptz NOT NULL,
+    blocked_until timestamptz,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX login_throttles_last_failure_idx ON login_throttles (last_failure_at);
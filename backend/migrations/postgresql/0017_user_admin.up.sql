@@ -0,0 +1,7 @@
+-- Enum for instance-wide user roles
+CREATE TYPE user_role AS ENUM ('user', 'admin');
+
+-- Role of an account on this instance, and when an administrator disabled it.
+-- A disabled account cannot sign in and its sessions and API keys stop working.
+ALTER TABLE users ADD COLUMN role user_role NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN disabled_at timestamptz;
@@ -0,0 +1,9 @@
+-- Settings administrators change at runtime through the admin API. The table
+-- holds a single row, which the id column enforces.
+CREATE TABLE instance_settings (
+    id boolean PRIMARY KEY DEFAULT true CHECK (id),
+    registration_enabled boolean NOT NULL DEFAULT true,
+    updated_at timestamptz
+);
+
+INSERT INTO instance_settings DEFAULT VALUES;
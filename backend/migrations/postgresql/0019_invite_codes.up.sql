@@ -0,0 +1,17 @@
+-- Invite codes admins mint for invite-only registration. Only a hash of the
+-- code is stored; the prefix is kept in clear text so admins can tell codes
+-- apart. max_uses and expires_at are unlimited when NULL.
+CREATE TABLE invite_codes (
+    id uuid PRIMARY KEY,
+    prefix text NOT NULL,
+    code_hash bytea NOT NULL,
+    note text,
+    max_uses integer,
+    uses integer NOT NULL DEFAULT 0,
+    expires_at timestamptz,
+    created_by uuid REFERENCES users (id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT invite_codes_code_hash_unique UNIQUE (code_hash),
+    CONSTRAINT invite_codes_max_uses_positive CHECK (max_uses IS NULL OR max_uses > 0),
+    CONSTRAINT invite_codes_uses_valid CHECK (uses >= 0 AND (max_uses IS NULL OR uses <= max_uses))
+);
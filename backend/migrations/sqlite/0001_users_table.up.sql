@@ -0,0 +1,19 @@
+-- Users table with check for password_hash when auth_provider is 'local'.
+-- Timestamps are stored as UTC text in a fixed layout, so they sort in time order.
+CREATE TABLE users (
+    id text PRIMARY KEY,
+    avatar_url text,
+    auth_provider text NOT NULL CHECK (auth_provider IN ('local', 'google', 'facebook')),
+    created_at timestamp NOT NULL,
+    email varchar(254) NOT NULL, -- Max length for email as per RFC 5321
+    full_name text NOT NULL,
+    password_hash text,
+    timezone text NOT NULL,
+    updated_at timestamp NOT NULL,
+    CONSTRAINT users_email_unique UNIQUE (email),
+    -- If auth_provider = 'local', password_hash must be set
+    CONSTRAINT users_local_auth_requires_password CHECK (
+        auth_provider <> 'local'
+        OR password_hash IS NOT NULL
+    )
+);
@@ -0,0 +1,15 @@
+-- Plants owned by a user
+CREATE TABLE plants (
+    id text PRIMARY KEY,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    species text,
+    location text,
+    notes text,
+    acquired_at timestamp,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    CONSTRAINT plants_name_not_empty CHECK (length(trim(name)) > 0)
+);
+
+CREATE INDEX plants_user_id_idx ON plants (user_id, created_at);
@@ -0,0 +1 @@
+DROP TABLE care_schedules;
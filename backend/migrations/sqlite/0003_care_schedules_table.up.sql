@@ -0,0 +1,17 @@
+-- Recurring care schedules. anchor_local is a wall-clock time interpreted in the
+-- owner's timezone, next_due_at is computed by the server from rrule and anchor_local.
+CREATE TABLE care_schedules (
+    id text PRIMARY KEY,
+    plant_id text NOT NULL REFERENCES plants (id) ON DELETE CASCADE,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    task_type text NOT NULL CHECK (task_type IN ('water', 'fertilize', 'mist', 'repot', 'prune', 'rotate', 'clean', 'other')),
+    rrule text NOT NULL,
+    anchor_local timestamp NOT NULL,
+    notes text,
+    next_due_at timestamp NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+CREATE INDEX care_schedules_plant_id_idx ON care_schedules (plant_id);
+CREATE INDEX care_schedules_user_due_idx ON care_schedules (user_id, next_due_at);
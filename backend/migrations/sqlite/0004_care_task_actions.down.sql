@@ -0,0 +1,3 @@
+DROP TABLE care_task_records;
+
+ALTER TABLE care_schedules DROP COLUMN occurrence_at;
@@ -0,0 +1,42 @@
+-- occurrence_at is the series occurrence a schedule is waiting on. next_due_at
+-- equals it unless the occurrence has been snoozed. SQLite cannot add a NOT NULL
+-- column without a default, so the table is rebuilt.
+CREATE TABLE care_schedules_new (
+    id text PRIMARY KEY,
+    plant_id text NOT NULL REFERENCES plants (id) ON DELETE CASCADE,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    task_type text NOT NULL CHECK (task_type IN ('water', 'fertilize', 'mist', 'repot', 'prune', 'rotate', 'clean', 'other')),
+    rrule text NOT NULL,
+    anchor_local timestamp NOT NULL,
+    notes text,
+    next_due_at timestamp NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    occurrence_at timestamp NOT NULL
+);
+
+INSERT INTO care_schedules_new (id, plant_id, user_id, task_type, rrule, anchor_local, notes, next_due_at, created_at, updated_at, occurrence_at)
+SELECT id, plant_id, user_id, task_type, rrule, anchor_local, notes, next_due_at, created_at, updated_at, next_due_at
+FROM care_schedules;
+
+DROP TABLE care_schedules;
+ALTER TABLE care_schedules_new RENAME TO care_schedules;
+
+CREATE INDEX care_schedules_plant_id_idx ON care_schedules (plant_id);
+CREATE INDEX care_schedules_user_due_idx ON care_schedules (user_id, next_due_at);
+
+-- Append-only record of actions taken on care schedules
+CREATE TABLE care_task_records (
+    id text PRIMARY KEY,
+    schedule_id text NOT NULL REFERENCES care_schedules (id) ON DELETE CASCADE,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    action text NOT NULL CHECK (action IN ('complete', 'snooze', 'skip', 'reschedule')),
+    occurrence_at timestamp NOT NULL, -- occurrence the action applied to
+    acted_at timestamp NOT NULL, -- completion time for 'complete', request time otherwise
+    previous_due_at timestamp NOT NULL,
+    next_due_at timestamp NOT NULL,
+    next_occurrence_at timestamp NOT NULL,
+    created_at timestamp NOT NULL
+);
+
+CREATE INDEX care_task_records_schedule_id_idx ON care_task_records (schedule_id, created_at);
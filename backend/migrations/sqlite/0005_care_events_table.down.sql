@@ -0,0 +1,2 @@
+-- Dropping the table also drops its append-only trigger
+DROP TABLE care_events;
@@ -0,0 +1,21 @@
+-- Append-only log of everything that happened to a plant
+CREATE TABLE care_events (
+    id text PRIMARY KEY,
+    plant_id text NOT NULL REFERENCES plants (id) ON DELETE CASCADE,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE, -- actor
+    type text NOT NULL CHECK (type IN ('water', 'fertilize', 'mist', 'repot', 'prune', 'rotate', 'clean', 'note', 'photo', 'other')),
+    occurred_at timestamp NOT NULL,
+    payload text NOT NULL DEFAULT '{}',
+    created_at timestamp NOT NULL,
+    CONSTRAINT care_events_payload_is_object CHECK (json_type(payload) = 'object')
+);
+
+CREATE INDEX care_events_timeline_idx ON care_events (plant_id, occurred_at DESC, id DESC);
+
+-- Events are never edited. Deletes are still allowed so plant and account
+-- removal can cascade.
+CREATE TRIGGER care_events_append_only
+    BEFORE UPDATE ON care_events
+BEGIN
+    SELECT RAISE(ABORT, 'care_events is append-only');
+END;
@@ -0,0 +1 @@
+DROP TABLE plant_photos;
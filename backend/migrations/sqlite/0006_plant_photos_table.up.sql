@@ -0,0 +1,17 @@
+-- Photos attached to plants. The image data lives in the blob store under
+-- blob_key and thumbnail_key.
+CREATE TABLE plant_photos (
+    id text PRIMARY KEY,
+    plant_id text NOT NULL REFERENCES plants (id) ON DELETE CASCADE,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    blob_key text NOT NULL,
+    thumbnail_key text NOT NULL,
+    content_type text NOT NULL,
+    width integer NOT NULL,
+    height integer NOT NULL,
+    size_bytes integer NOT NULL,
+    caption text,
+    created_at timestamp NOT NULL
+);
+
+CREATE INDEX plant_photos_plant_id_idx ON plant_photos (plant_id, created_at DESC);
@@ -0,0 +1 @@
+DROP TABLE reminder_deliveries;
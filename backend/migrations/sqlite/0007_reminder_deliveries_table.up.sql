@@ -0,0 +1,21 @@
+-- One row per care task occurrence and notification channel. The unique key
+-- deduplicates reminders (a snoozed occurrence gets one more reminder when the
+-- snooze ends), and pending rows are retried until they are sent, which gives
+-- at-least-once delivery across restarts.
+CREATE TABLE reminder_deliveries (
+    id text PRIMARY KEY,
+    schedule_id text NOT NULL REFERENCES care_schedules (id) ON DELETE CASCADE,
+    channel text NOT NULL,
+    occurrence_at timestamp NOT NULL,
+    due_at timestamp NOT NULL,
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'cancelled')),
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamp NOT NULL,
+    last_error text,
+    sent_at timestamp,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    CONSTRAINT reminder_deliveries_dedup UNIQUE (schedule_id, occurrence_at, due_at, channel)
+);
+
+CREATE INDEX reminder_deliveries_pending_idx ON reminder_deliveries (next_attempt_at) WHERE status = 'pending';
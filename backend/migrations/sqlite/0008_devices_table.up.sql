@@ -0,0 +1,14 @@
+-- Push notification tokens registered by the mobile app. A token identifies an
+-- app install, so it belongs to at most one user at a time.
+CREATE TABLE devices (
+    id text PRIMARY KEY,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token text NOT NULL,
+    platform text NOT NULL CHECK (platform IN ('android', 'ios', 'web')),
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    CONSTRAINT devices_token_unique UNIQUE (token),
+    CONSTRAINT devices_token_not_empty CHECK (length(token) > 0)
+);
+
+CREATE INDEX devices_user_id_idx ON devices (user_id);
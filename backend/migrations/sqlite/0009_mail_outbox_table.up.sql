@@ -0,0 +1,18 @@
+-- Rendered messages waiting to be sent. Pending rows are retried with backoff,
+-- so mail queued before a restart or an SMTP outage is still delivered.
+CREATE TABLE mail_outbox (
+    id text PRIMARY KEY,
+    to_address text NOT NULL,
+    subject text NOT NULL,
+    text_body text NOT NULL,
+    html_body text NOT NULL,
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamp NOT NULL,
+    last_error text,
+    sent_at timestamp,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+CREATE INDEX mail_outbox_pending_idx ON mail_outbox (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,15 @@
+-- Tokens issued before this time are rejected, e.g. after a password reset
+ALTER TABLE users ADD COLUMN sessions_revoked_at timestamp;
+
+-- Single-use password reset tokens. Only a SHA-256 hash of the token is stored.
+CREATE TABLE password_reset_tokens (
+    id text PRIMARY KEY,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash blob NOT NULL,
+    expires_at timestamp NOT NULL,
+    used_at timestamp,
+    created_at timestamp NOT NULL,
+    CONSTRAINT password_reset_tokens_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
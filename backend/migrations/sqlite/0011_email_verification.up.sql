@@ -0,0 +1,16 @@
+-- Set once the user proved access to their email address
+ALTER TABLE users ADD COLUMN email_verified_at timestamp;
+
+-- Pending email verifications. The address being verified is stored with the
+-- token so a token only confirms the address it was sent to.
+CREATE TABLE email_verification_tokens (
+    id text PRIMARY KEY,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    email varchar(254) NOT NULL,
+    token_hash blob NOT NULL,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL,
+    CONSTRAINT email_verification_tokens_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX email_verification_tokens_user_id_idx ON email_verification_tokens (user_id);
@@ -0,0 +1,19 @@
+-- Personal API keys for scripts and integrations. Only a hash of the key is
+-- stored; the prefix is kept in clear text so users can tell their keys apart.
+-- An empty scope list grants the same access as the user's session. Scopes are
+-- a JSON array of strings, validated by the server before they are stored.
+CREATE TABLE api_keys (
+    id text PRIMARY KEY,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    prefix text NOT NULL,
+    key_hash blob NOT NULL,
+    scopes text NOT NULL DEFAULT '[]',
+    last_used_at timestamp,
+    created_at timestamp NOT NULL,
+    CONSTRAINT api_keys_key_hash_unique UNIQUE (key_hash),
+    CONSTRAINT api_keys_name_not_empty CHECK (length(name) > 0),
+    CONSTRAINT api_keys_scopes_is_array CHECK (json_type(scopes) = 'array')
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
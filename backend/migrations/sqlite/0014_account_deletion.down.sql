@@ -0,0 +1,4 @@
+DROP TABLE file_deletions;
+
+DROP INDEX users_deletion_scheduled_idx;
+ALTER TABLE users DROP COLUMN deletion_scheduled_at;
@@ -0,0 +1,13 @@
+-- Failed logins per client address and per account email. A row is kept in
+-- the database rather than in memory so every API instance sees the same
+-- count. blocked_until is set once the failures call for a backoff or lockout.
+CREATE TABLE login_throttles (
+    scope text NOT NULL CHECK (scope IN ('ip', 'account')),
+    key text NOT NULL,
+    failures integer NOT NULL DEFAULT 0,
+    last_failure_at timestamp NOT NULL,
+    blocked_until timestamp,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX login_throttles_last_failure_idx ON login_throttles (last_failure_at);
@@ -0,0 +1,4 @@
+-- Role of an account on this instance, and when an administrator disabled it.
+-- A disabled account cannot sign in and its sessions and API keys stop working.
+ALTER TABLE users ADD COLUMN role text NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+ALTER TABLE users ADD COLUMN disabled_at timestamp;
@@ -0,0 +1,9 @@
+-- Settings administrators change at runtime through the admin API. The table
+-- holds a single row, which the id column enforces.
+CREATE TABLE instance_settings (
+    id integer PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    registration_enabled integer NOT NULL DEFAULT 1 CHECK (registration_enabled IN (0, 1)),
+    updated_at timestamp
+);
+
+INSERT INTO instance_settings DEFAULT VALUES;
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// FileStore represents the file_store enum from the SQL schema
+type FileStore string
+
+const (
+	FileStoreBlobs   FileStore = "blobs"   // Photos in the blob store
+	FileStoreAvatars FileStore = "avatars" // Avatars kept by the auth service
+)
+
+// FileDeletion is a file of an erased account that still has to be removed
+type FileDeletion struct {
+	ID       uuid.UUID
+	Store    FileStore
+	Key      string
+	Attempts int
+}
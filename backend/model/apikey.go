@@ -0,0 +1,60 @@
+package model
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyScope limits what an API key can be used for
+type APIKeyScope string
+
+const (
+	APIKeyScopeRead   APIKeyScope = "read"   // Only safe methods, combined with the other scopes
+	APIKeyScopePlants APIKeyScope = "plants" // Plants, photos and care events
+	APIKeyScopeTasks  APIKeyScope = "tasks"  // Care schedules and due tasks
+)
+
+// Valid reports whether s is one of the known scopes
+func (s APIKeyScope) Valid() bool {
+	switch s {
+	case APIKeyScopeRead, APIKeyScopePlants, APIKeyScopeTasks:
+		return true
+	}
+	return false
+}
+
+// APIKey is a personal access key used instead of a session by scripts and integrations
+type APIKey struct {
+	ID         uuid.UUID     `json:"id"`
+	UserID     uuid.UUID     `json:"user_id"`
+	Name       string        `json:"name"`
+	Prefix     string        `json:"prefix"`
+	KeyHash    []byte        `json:"-"`
+	Scopes     []APIKeyScope `json:"scopes"`
+	LastUsedAt *time.Time    `json:"last_used_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// Allows reports whether the key may call an endpoint in scope with the given
+// HTTP method. Without resource scopes the key can reach every scoped endpoint,
+// and the read scope restricts it to safe methods.
+func (k *APIKey) Allows(scope APIKeyScope, method string) bool {
+	readOnly, resources, granted := false, 0, false
+	for _, s := range k.Scopes {
+		if s == APIKeyScopeRead {
+			readOnly = true
+			continue
+		}
+		resources++
+		if s == scope {
+			granted = true
+		}
+	}
+
+	if readOnly && method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	return resources == 0 || granted
+}
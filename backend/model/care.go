@@ -0,0 +1,129 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CareTaskType represents the care_task_type enum from the SQL schema
+type CareTaskType string
+
+const (
+	CareTaskWater     CareTaskType = "water"
+	CareTaskFertilize CareTaskType = "fertilize"
+	CareTaskMist      CareTaskType = "mist"
+	CareTaskRepot     CareTaskType = "repot"
+	CareTaskPrune     CareTaskType = "prune"
+	CareTaskRotate    CareTaskType = "rotate"
+	CareTaskClean     CareTaskType = "clean"
+	CareTaskOther     CareTaskType = "other"
+)
+
+// Valid reports whether t is one of the known care task types
+func (t CareTaskType) Valid() bool {
+	switch t {
+	case CareTaskWater, CareTaskFertilize, CareTaskMist, CareTaskRepot,
+		CareTaskPrune, CareTaskRotate, CareTaskClean, CareTaskOther:
+		return true
+	}
+	return false
+}
+
+// LocalTimeLayout is the wire format of LocalTime
+const LocalTimeLayout = "2006-01-02T15:04:05"
+
+// LocalTime is a wall-clock date and time without a zone. It is interpreted in
+// the owning user's timezone.
+type LocalTime time.Time
+
+// ParseLocalTime parses "2006-01-02T15:04:05" or "2006-01-02T15:04"
+func ParseLocalTime(s string) (LocalTime, error) {
+	for _, layout := range []string{LocalTimeLayout, "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return LocalTime(t), nil
+		}
+	}
+	return LocalTime{}, fmt.Errorf("invalid local time %q, expected format %s", s, LocalTimeLayout)
+}
+
+// Time returns the wall clock as a time.Time in UTC
+func (t LocalTime) Time() time.Time {
+	return time.Time(t)
+}
+
+// String formats the local time using LocalTimeLayout
+func (t LocalTime) String() string {
+	return time.Time(t).Format(LocalTimeLayout)
+}
+
+// MarshalJSON implements json.Marshaler
+func (t LocalTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *LocalTime) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseLocalTime(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// CareSchedule represents a recurring care task for a plant, matching the care_schedules SQL table.
+// OccurrenceAt is the series occurrence being waited on; NextDueAt is later than it when snoozed.
+type CareSchedule struct {
+	ID           uuid.UUID    `json:"id" db:"id"`
+	PlantID      uuid.UUID    `json:"plant_id" db:"plant_id"`
+	UserID       uuid.UUID    `json:"user_id" db:"user_id"`
+	TaskType     CareTaskType `json:"task_type" db:"task_type"`
+	RRule        string       `json:"rrule" db:"rrule"`
+	Anchor       LocalTime    `json:"anchor" db:"anchor_local"`
+	Notes        *string      `json:"notes" db:"notes"`
+	NextDueAt    time.Time    `json:"next_due_at" db:"next_due_at"`
+	OccurrenceAt time.Time    `json:"occurrence_at" db:"occurrence_at"`
+	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// CareTaskAction represents the care_task_action enum from the SQL schema
+type CareTaskAction string
+
+const (
+	CareTaskActionComplete   CareTaskAction = "complete"
+	CareTaskActionSnooze     CareTaskAction = "snooze"
+	CareTaskActionSkip       CareTaskAction = "skip"
+	CareTaskActionReschedule CareTaskAction = "reschedule"
+)
+
+// CareTaskRecord is an entry of the append-only log of actions taken on care schedules
+type CareTaskRecord struct {
+	ID               uuid.UUID      `json:"id" db:"id"`
+	ScheduleID       uuid.UUID      `json:"schedule_id" db:"schedule_id"`
+	UserID           uuid.UUID      `json:"user_id" db:"user_id"`
+	Action           CareTaskAction `json:"action" db:"action"`
+	OccurrenceAt     time.Time      `json:"occurrence_at" db:"occurrence_at"`
+	ActedAt          time.Time      `json:"acted_at" db:"acted_at"`
+	PreviousDueAt    time.Time      `json:"previous_due_at" db:"previous_due_at"`
+	NextDueAt        time.Time      `json:"next_due_at" db:"next_due_at"`
+	NextOccurrenceAt time.Time      `json:"next_occurrence_at" db:"next_occurrence_at"`
+	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
+}
+
+// CareTask is a single due occurrence of a care schedule
+type CareTask struct {
+	ScheduleID uuid.UUID    `json:"schedule_id"`
+	PlantID    uuid.UUID    `json:"plant_id"`
+	PlantName  string       `json:"plant_name"`
+	TaskType   CareTaskType `json:"task_type"`
+	DueAt      time.Time    `json:"due_at"`
+	Overdue    bool         `json:"overdue"`
+}
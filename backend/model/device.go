@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DevicePlatform represents the device_platform enum from the SQL schema
+type DevicePlatform string
+
+const (
+	DeviceAndroid DevicePlatform = "android"
+	DeviceIOS     DevicePlatform = "ios"
+	DeviceWeb     DevicePlatform = "web"
+)
+
+// Valid reports whether p is one of the known device platforms
+func (p DevicePlatform) Valid() bool {
+	switch p {
+	case DeviceAndroid, DeviceIOS, DeviceWeb:
+		return true
+	}
+	return false
+}
+
+// Device is a push notification token registered by an app install
+type Device struct {
+	ID        uuid.UUID      `json:"id"`
+	UserID    uuid.UUID      `json:"user_id"`
+	Token     string         `json:"token"`
+	Platform  DevicePlatform `json:"platform"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CareEventType represents the care_event_type enum from the SQL schema
+type CareEventType string
+
+const (
+	CareEventWater     CareEventType = "water"
+	CareEventFertilize CareEventType = "fertilize"
+	CareEventMist      CareEventType = "mist"
+	CareEventRepot     CareEventType = "repot"
+	CareEventPrune     CareEventType = "prune"
+	CareEventRotate    CareEventType = "rotate"
+	CareEventClean     CareEventType = "clean"
+	CareEventNote      CareEventType = "note"
+	CareEventPhoto     CareEventType = "photo"
+	CareEventOther     CareEventType = "other"
+)
+
+// Valid reports whether t is one of the known care event types
+func (t CareEventType) Valid() bool {
+	switch t {
+	case CareEventWater, CareEventFertilize, CareEventMist, CareEventRepot, CareEventPrune,
+		CareEventRotate, CareEventClean, CareEventNote, CareEventPhoto, CareEventOther:
+		return true
+	}
+	return false
+}
+
+// EventType returns the care event type recorded when a task of this type is completed
+func (t CareTaskType) EventType() CareEventType {
+	return CareEventType(t)
+}
+
+// CareEvent is an entry of a plant's append-only history, matching the care_events SQL table
+type CareEvent struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	PlantID    uuid.UUID       `json:"plant_id" db:"plant_id"`
+	UserID     uuid.UUID       `json:"user_id" db:"user_id"`
+	Type       CareEventType   `json:"type" db:"type"`
+	OccurredAt time.Time       `json:"occurred_at" db:"occurred_at"`
+	Payload    json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// CareEventFilter selects a page of a plant's timeline, newest first
+type CareEventFilter struct {
+	Types []CareEventType
+	// Events strictly older than (BeforeOccurredAt, BeforeID) are returned when BeforeID is set
+	BeforeOccurredAt time.Time
+	BeforeID         uuid.UUID
+	Limit            int
+}
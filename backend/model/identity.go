@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links an OAuth provider account to a user
+type UserIdentity struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	Provider  AuthProvider `json:"provider"`
+	Subject   string       `json:"-"`
+	Email     *string      `json:"email"`
+	CreatedAt time.Time    `json:"created_at"`
+}
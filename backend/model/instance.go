@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// InstanceSettings holds the settings administrators change at runtime
+type InstanceSettings struct {
+	// RegistrationEnabled allows new accounts to sign up
+	RegistrationEnabled bool `json:"registration_enabled" db:"registration_enabled"`
+	// UpdatedAt is nil until an administrator changed a setting
+	UpdatedAt *time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// InstanceStats summarizes the accounts and data stored on this instance
+type InstanceStats struct {
+	Users            int64 `json:"users"`
+	Admins           int64 `json:"admins"`
+	DisabledUsers    int64 `json:"disabled_users"`
+	UnverifiedUsers  int64 `json:"unverified_users"`
+	PendingDeletions int64 `json:"pending_deletions"`
+	Plants           int64 `json:"plants"`
+	CareSchedules    int64 `json:"care_schedules"`
+	CareEvents       int64 `json:"care_events"`
+	Photos           int64 `json:"photos"`
+	PhotoBytes       int64 `json:"photo_bytes"`
+	APIKeys          int64 `json:"api_keys"`
+	Devices          int64 `json:"devices"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InviteCode admits new accounts while registration is invite-only
+type InviteCode struct {
+	ID       uuid.UUID `json:"id"`
+	Prefix   string    `json:"prefix"`
+	CodeHash []byte    `json:"-"`
+	Note     *string   `json:"note"`
+	// MaxUses is how many accounts the code can create, nil for no limit
+	MaxUses *int `json:"max_uses"`
+	Uses    int  `json:"uses"`
+	// ExpiresAt is nil for codes that do not expire
+	ExpiresAt *time.Time `json:"expires_at"`
+	// CreatedBy is the admin who minted the code, nil once their account is gone
+	CreatedBy *uuid.UUID `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
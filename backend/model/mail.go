@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboundMail is a rendered email stored in the outbox until it is sent
+type OutboundMail struct {
+	ID        uuid.UUID `json:"id"`
+	To        string    `json:"to"`
+	Subject   string    `json:"subject"`
+	TextBody  string    `json:"text_body"`
+	HTMLBody  string    `json:"html_body"`
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+}
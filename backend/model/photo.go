@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Photo represents a photo attached to a plant, matching the plant_photos SQL table
+type Photo struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	PlantID      uuid.UUID `json:"plant_id" db:"plant_id"`
+	UserID       uuid.UUID `json:"user_id" db:"user_id"`
+	BlobKey      string    `json:"-" db:"blob_key"`
+	ThumbnailKey string    `json:"-" db:"thumbnail_key"`
+	ContentType  string    `json:"content_type" db:"content_type"`
+	Width        int       `json:"width" db:"width"`
+	Height       int       `json:"height" db:"height"`
+	SizeBytes    int64     `json:"size_bytes" db:"size_bytes"`
+	Caption      *string   `json:"caption" db:"caption"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
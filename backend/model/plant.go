@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Plant represents a plant owned by a user, matching the plants SQL table
+type Plant struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Species    *string    `json:"species" db:"species"`
+	Location   *string    `json:"location" db:"location"`
+	Notes      *string    `json:"notes" db:"notes"`
+	AcquiredAt *time.Time `json:"acquired_at" db:"acquired_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
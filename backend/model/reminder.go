@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReminderDeliveryStatus represents the reminder_delivery_status enum from the SQL schema
+type ReminderDeliveryStatus string
+
+const (
+	ReminderPending   ReminderDeliveryStatus = "pending"
+	ReminderSent      ReminderDeliveryStatus = "sent"
+	ReminderFailed    ReminderDeliveryStatus = "failed"
+	ReminderCancelled ReminderDeliveryStatus = "cancelled"
+)
+
+// Reminder is a claimed reminder delivery together with what is needed to notify the user
+type Reminder struct {
+	DeliveryID   uuid.UUID    `json:"delivery_id"`
+	Channel      string       `json:"channel"`
+	Attempts     int          `json:"attempts"`
+	ScheduleID   uuid.UUID    `json:"schedule_id"`
+	OccurrenceAt time.Time    `json:"occurrence_at"`
+	DueAt        time.Time    `json:"due_at"`
+	UserID       uuid.UUID    `json:"user_id"`
+	PlantID      uuid.UUID    `json:"plant_id"`
+	PlantName    string       `json:"plant_name"`
+	TaskType     CareTaskType `json:"task_type"`
+	// Current is false when the schedule has moved past DueAt since the reminder was queued
+	Current bool `json:"current"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// ThrottleScope represents the throttle_scope enum from the SQL schema
+type ThrottleScope string
+
+const (
+	ThrottleScopeIP      ThrottleScope = "ip"      // Failures from one client address
+	ThrottleScopeAccount ThrottleScope = "account" // Failures against one account email
+)
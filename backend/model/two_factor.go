@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TOTP is the authenticator app enrollment of a user
+type TOTP struct {
+	UserID uuid.UUID
+	Secret string
+	// EnabledAt is nil until the enrollment was confirmed with a code
+	EnabledAt *time.Time
+	// LastUsedStep is the time step of the last accepted code
+	LastUsedStep *int64
+	CreatedAt    time.Time
+}
+
+// Enabled reports whether the enrollment was confirmed
+func (t *TOTP) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// RecoveryCode is a hashed single-use code that replaces a TOTP code
+type RecoveryCode struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	CodeHash string
+}
@@ -15,6 +15,19 @@ const (
 	AuthProviderFacebook AuthProvider = "facebook"
 )
 
+// UserRole is the role of a user on this instance
+type UserRole string
+
+const (
+	UserRoleUser  UserRole = "user"
+	UserRoleAdmin UserRole = "admin"
+)
+
+// Valid reports whether r is a known role
+func (r UserRole) Valid() bool {
+	return r == UserRoleUser || r == UserRoleAdmin
+}
+
 // User represents the user model matching the SQL table schema
 type User struct {
 	ID           uuid.UUID    `json:"id" db:"id"`
@@ -26,4 +39,18 @@ type User struct {
 	PasswordHash *string      `json:"password_hash,omitempty" db:"password_hash"`
 	Timezone     string       `json:"timezone" db:"timezone"`
 	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
-}
\ No newline at end of file
+	// EmailVerifiedAt is nil until the user confirmed their email address
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+	// SessionsRevokedAt invalidates every token issued before it
+	SessionsRevokedAt *time.Time `json:"-" db:"sessions_revoked_at"`
+	// DeletionScheduledAt is when the account will be erased, nil unless deletion was requested
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at" db:"deletion_scheduled_at"`
+	Role                UserRole   `json:"role" db:"role"`
+	// DisabledAt is when an administrator disabled the account, nil while it is active
+	DisabledAt *time.Time `json:"disabled_at" db:"disabled_at"`
+}
+
+// HasRole reports whether the user holds role. Admins hold every role.
+func (u *User) HasRole(role UserRole) bool {
+	return u.Role == role || u.Role == UserRoleAdmin
+}